@@ -0,0 +1,120 @@
+package db
+
+import (
+	"errors"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var archiveSuffixes = []string{".7z", ".rar"}
+
+func isArchiveFileName(fileName string) bool {
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(strings.ToLower(fileName), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readArchiveMetadata extracts the archive into a temp folder using the user provided extractor command,
+// and reads the metadata of the supported files found inside it. the extracted files are removed afterwards.
+func readArchiveMetadata(archivePath string, options ScanOptions) ([]*switchfs.ContentMetaAttributes, error) {
+	args, err := splitCommandLine(options.ArchiveExtractorCommand)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no archive extractor command defined")
+	}
+
+	tempFolder, err := ioutil.TempDir("", "slm-archive")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempFolder)
+
+	replacer := strings.NewReplacer("{"+settings.TEMPLATE_ARCHIVE+"}", archivePath, "{"+settings.TEMPLATE_OUTPUT+"}", tempFolder)
+	for i, arg := range args {
+		args[i] = replacer.Replace(arg)
+	}
+	zap.S().Infof("--> [Extract] running %v", args)
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		zap.S().Errorf("failed to extract archive [%v] - %v", string(output), err)
+		return nil, err
+	}
+
+	var result []*switchfs.ContentMetaAttributes
+	err = filepath.Walk(tempFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isSupportedFileName(info.Name()) {
+			return nil
+		}
+		metadata, err := GetGameMetadata(info, path, options)
+		if err != nil {
+			zap.S().Warnf("[file:%v] unable to determine titileId / version inside archive [%v]", info.Name(), archivePath)
+			return nil
+		}
+		result = append(result, metadata)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no supported files found in archive")
+	}
+	return result, nil
+}
+
+// splitCommandLine splits a command into its arguments on white space, keeping quoted ("..." or '...') text together,
+// so that paths with spaces can be quoted. backslashes are kept as is (windows paths) except for \" inside double quotes.
+func splitCommandLine(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) && runes[i+1] == '"' {
+				current.WriteRune('"')
+				i++
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in the command [" + command + "]")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"7z x {ARCHIVE} -o{OUTPUT} -y", []string{"7z", "x", "{ARCHIVE}", "-o{OUTPUT}", "-y"}, false},
+		{`"C:\Program Files\7-Zip\7z.exe" x {ARCHIVE}`, []string{`C:\Program Files\7-Zip\7z.exe`, "x", "{ARCHIVE}"}, false},
+		{`unrar x '/mnt/my games/{ARCHIVE}'`, []string{"unrar", "x", "/mnt/my games/{ARCHIVE}"}, false},
+		{`sh -c "echo \"quoted\" it's"`, []string{"sh", "-c", `echo "quoted" it's`}, false},
+		{`7z  x   ""  -y`, []string{"7z", "x", "", "-y"}, false},
+		{`-o"{OUTPUT}"/sub`, []string{"-o{OUTPUT}/sub"}, false},
+		{"", nil, false},
+		{`7z "x`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			got, err := splitCommandLine(test.command)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestArchiveFilesAreMarkedReadOnly(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("the fake extractor requires sh")
+	}
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp": {},
+		"updates.7z":                      {},
+	})
+	//the fake extractor creates a tagged update file in the output folder, the quoted argument keeps the spaces
+	command := `sh -c "touch '{OUTPUT}/Game [0100000000010800][v65536].nsp'"`
+	localDB := scanTestFolder(t, folder, ScanOptions{ArchiveExtractorCommand: command})
+
+	switchFile, ok := localDB.TitlesMap["010000000001"]
+	if !ok {
+		t.Fatal("title was not registered")
+	}
+	if switchFile.File.InArchive {
+		t.Error("the base file is not inside an archive")
+	}
+	update, ok := switchFile.Updates[65536]
+	if !ok {
+		t.Fatalf("the update inside the archive was not registered, skipped: %v", localDB.Skipped)
+	}
+	if !update.InArchive || update.Info.Name() != "updates.7z" {
+		t.Errorf("update = %v (in archive: %v), want the read only updates.7z", update.Info.Name(), update.InArchive)
+	}
+}
@@ -1,32 +1,83 @@
 package db
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/giwty/switch-library-manager/settings"
 	"github.com/giwty/switch-library-manager/switchfs"
 	"go.uber.org/zap"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	versionRegex = regexp.MustCompile(`\[[vV]?(?P<version>[0-9]{1,10})]`)
 	titleIdRegex = regexp.MustCompile(`\[(?P<titleId>[A-Z,a-z0-9]{16})]`)
+	fileTagRegex = regexp.MustCompile(`[\[(]([^\])]+)[\])]`)
 )
 
 var (
-	total     = 0
-	globalInd = 0
+	regionTags = map[string]bool{"US": true, "USA": true, "EU": true, "EUR": true, "EUROPE": true, "JP": true, "JPN": true,
+		"JAPAN": true, "KR": true, "KOR": true, "KOREA": true, "HK": true, "CN": true, "CHN": true, "CHINA": true, "ASIA": true,
+		"UK": true, "GB": true, "AU": true, "AUS": true, "WORLD": true, "NTSC-U": true, "NTSC-J": true, "PAL": true}
+	languageTags = map[string]bool{"EN": true, "FR": true, "DE": true, "ES": true, "IT": true, "NL": true, "PT": true,
+		"RU": true, "JA": true, "KO": true, "ZH": true, "ZHCN": true, "ZHTW": true, "PL": true, "SV": true, "DA": true,
+		"NO": true, "FI": true}
+	//region tag variants, mapped to the canonical region code
+	regionAliases = map[string]string{"USA": "US", "NTSC-U": "US", "EUR": "EU", "EUROPE": "EU", "PAL": "EU",
+		"JPN": "JP", "JAPAN": "JP", "NTSC-J": "JP", "KOR": "KR", "KOREA": "KR", "CHN": "CN", "CHINA": "CN",
+		"UK": "GB", "AUS": "AU"}
 )
 
+// SetRegionAliases adds region tag variants (such as {"NA": "US"}), on top of the built in ones
+func SetRegionAliases(aliases map[string]string) {
+	for variant, canonical := range aliases {
+		variant, canonical = strings.ToUpper(strings.TrimSpace(variant)), strings.ToUpper(strings.TrimSpace(canonical))
+		if variant == "" || canonical == "" {
+			continue
+		}
+		regionAliases[variant] = canonical
+		regionTags[variant] = true
+		regionTags[canonical] = true
+	}
+}
+
+// NormalizeRegion maps a region tag to its canonical code (USA, NTSC-U -> US), unknown tags are returned upper cased
+func NormalizeRegion(region string) string {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if canonical, ok := regionAliases[region]; ok {
+		return canonical
+	}
+	return region
+}
+
+// delay before the first retry of a failed read, doubled on each further attempt
+var retryDelay = 500 * time.Millisecond
+
+// SetRetryDelay sets the delay before retrying a failed read (doubled on each further attempt), the default is 500ms
+func SetRetryDelay(delay time.Duration) {
+	if delay > 0 {
+		retryDelay = delay
+	}
+}
+
 type ExtendedFileInfo struct {
 	Info       os.FileInfo
 	BaseFolder string
 	Metadata   *switchfs.ContentMetaAttributes
+	//parsed from the file name tags, empty when not tagged
+	Region    string
+	Languages []string
+	//the file was read from inside an archive, Info is the archive itself - such files are read only, and are
+	//never deleted, moved or renamed
+	InArchive bool
 }
 
 type SwitchFile struct {
@@ -34,141 +85,527 @@ type SwitchFile struct {
 	BaseExist bool
 	Updates   map[int]ExtendedFileInfo
 	Dlc       map[string]ExtendedFileInfo
+	//modification time of the newest file (base, update or DLC) of the title
+	LastModified time.Time
+}
+
+// Files returns the base, update (by version) and DLC (by titleId) files of the title
+func (s *SwitchFile) Files() []ExtendedFileInfo {
+	var result []ExtendedFileInfo
+	if s.BaseExist {
+		result = append(result, s.File)
+	}
+	versions := make([]int, 0, len(s.Updates))
+	for version := range s.Updates {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	for _, version := range versions {
+		f := s.Updates[version]
+		//XCI files are registered both as base and as update
+		if s.BaseExist && f.Info == s.File.Info {
+			continue
+		}
+		result = append(result, f)
+	}
+	dlcIds := make([]string, 0, len(s.Dlc))
+	for id := range s.Dlc {
+		dlcIds = append(dlcIds, id)
+	}
+	sort.Strings(dlcIds)
+	for _, id := range dlcIds {
+		result = append(result, s.Dlc[id])
+	}
+	return result
+}
+
+type ScanOptions struct {
+	Recursive bool
+	//when set, files are identified by their name tags only, even if the keys are available
+	DisableDeepScan bool
+	//external command used to extract 7z/rar archives, archives are skipped when empty
+	ArchiveExtractorCommand string
+	//number of times to retry reading a file's metadata before falling back to the name tags
+	RetryCount int
+	//scan the files and folders whose name starts with a dot (.DS_Store, .Trashes etc.), which are skipped by default.
+	//macOS resource forks (._ files) are always skipped
+	IncludeHidden bool
+	//format (FORMAT_NSP / FORMAT_XCI) kept when the same base game and version is found in both formats
+	PreferredFormat string
+	//register XCI files as base games only, without the update bundled in the cartridge dump, so that it is not
+	//counted against the separate update files of the title
+	XciBaseOnly bool
+	//with XciBaseOnly, the update bundled in an XCI (its version read by the deep scan) still counts as the title's
+	//update, as long as no separate update file of the title is at least as new
+	XciBundledUpdate bool
+	//ignore the update / DLC files, for base games only libraries
+	SkipUpdates bool
+	SkipDLC     bool
+	//when set, files whose titleId is in the titles DB are typed (base/update/DLC) by the DB entries
+	TitlesDB *SwitchTitlesDB
+	//files larger than this (in bytes) are skipped instead of parsed, unlimited when 0
+	MaxFileSize int64
+	//when set, the scan stops once the context is done (such as after a deadline), and the DB is marked as partial
+	Context context.Context
 }
 
+const (
+	FORMAT_NSP = "nsp"
+	FORMAT_XCI = "xci"
+)
+
+// LocalSwitchFilesDB is fully built before CreateLocalSwitchFilesDB returns, and a rescan always returns a new DB,
+// so embedders running background rescans should swap the DB pointer rather than wait on the old one.
+// Operations changing an existing DB (such as removing old updates) go through the methods holding the write lock,
+// readers running concurrently with them should use the accessor methods (Titles, GetTitle), which return copies,
+// rather than TitlesMap.
 type LocalSwitchFilesDB struct {
+	mutex     sync.RWMutex
 	TitlesMap map[string]*SwitchFile
 	Skipped   map[os.FileInfo]string
+	//files that are still being downloaded (accompanied by a .part/.aria2 marker)
+	InProgress []ExtendedFileInfo
+	//update files which have the same title and version as an already registered update (duplicates, re-dumps)
+	DuplicateUpdates []DuplicateFile
+	//base files of an already registered base game
+	DuplicateBases []DuplicateFile
+	//sub folders which could not be read, their files are missing from the DB
+	UnreachableFolders []string
+	//the scan was stopped by the ScanOptions context before all the files were scanned
+	Partial bool
+	//the region and language tags shared by the files of the scan
+	tags *stringPool
+}
+
+type DuplicateFile struct {
+	File        ExtendedFileInfo
+	DuplicateOf ExtendedFileInfo
+}
+
+func CreateLocalSwitchFilesDB(files []os.FileInfo, parentFolder string, progress ProgressUpdater, options ScanOptions) (*LocalSwitchFilesDB, error) {
+	return CreateLocalSwitchFilesDBFromFolders([]ScanFolder{{Path: parentFolder, Files: files}}, progress, options)
+}
+
+// ScanFolder is a folder to scan, along with its (already listed) content
+type ScanFolder struct {
+	Path  string
+	Files []os.FileInfo
+}
+
+// CreateLocalSwitchFilesDBFromFolders scans several folders into a single local DB
+func CreateLocalSwitchFilesDBFromFolders(folders []ScanFolder, progress ProgressUpdater, options ScanOptions) (*LocalSwitchFilesDB, error) {
+	localDB := &LocalSwitchFilesDB{TitlesMap: map[string]*SwitchFile{}, Skipped: map[os.FileInfo]string{}, tags: newStringPool()}
+	scanProgress := &scanProgress{updater: progress}
+	for _, folder := range folders {
+		scanLocalFiles(folder.Path, folder.Files, scanProgress, options, localDB)
+		if localDB.Partial {
+			break
+		}
+	}
+
+	return localDB, nil
 }
 
-func CreateLocalSwitchFilesDB(files []os.FileInfo, parentFolder string, progress ProgressUpdater, recursive bool) (*LocalSwitchFilesDB, error) {
-	titles := map[string]*SwitchFile{}
-	skipped := map[os.FileInfo]string{}
-	globalInd = 0
-	total = 0
-	scanLocalFiles(parentFolder, files, progress, recursive, titles, skipped)
+// GetTitle returns a copy of the local title for the given titleId prefix (the titleId without its last 4 chars)
+func (l *LocalSwitchFilesDB) GetTitle(idPrefix string) (*SwitchFile, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	switchFile, ok := l.TitlesMap[idPrefix]
+	if !ok {
+		return nil, false
+	}
+	return switchFile.copy(), true
+}
 
-	return &LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped}, nil
+// Titles returns a copy of TitlesMap (and of its titles), safe to use while the DB is being changed
+func (l *LocalSwitchFilesDB) Titles() map[string]*SwitchFile {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	result := make(map[string]*SwitchFile, len(l.TitlesMap))
+	for k, v := range l.TitlesMap {
+		result[k] = v.copy()
+	}
+	return result
+}
+
+// SetUpdates replaces the update files of a local title, holding the write lock. the map is owned by the DB afterwards
+func (l *LocalSwitchFilesDB) SetUpdates(idPrefix string, updates map[int]ExtendedFileInfo) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if switchFile, ok := l.TitlesMap[idPrefix]; ok {
+		switchFile.Updates = updates
+	}
+}
+
+func (s *SwitchFile) copy() *SwitchFile {
+	result := *s
+	result.Updates = make(map[int]ExtendedFileInfo, len(s.Updates))
+	for version, update := range s.Updates {
+		result.Updates[version] = update
+	}
+	result.Dlc = make(map[string]ExtendedFileInfo, len(s.Dlc))
+	for id, dlc := range s.Dlc {
+		result.Dlc[id] = dlc
+	}
+	return &result
+}
+
+// the progress of a single scan, the total grows as the sub folders are listed
+type scanProgress struct {
+	updater ProgressUpdater
+	curr    int
+	total   int
 }
 
 func scanLocalFiles(parentFolder string, files []os.FileInfo,
-	progress ProgressUpdater,
-	recurse bool, titles map[string]*SwitchFile,
-	skipped map[os.FileInfo]string) {
-	total += len(files)
+	progress *scanProgress,
+	options ScanOptions, localDB *LocalSwitchFilesDB) {
+	skipped := localDB.Skipped
+	progress.total += len(files)
+	fileNames := map[string]bool{}
+	for _, file := range files {
+		fileNames[file.Name()] = true
+	}
 	for _, file := range files {
-		globalInd += 1
-		if progress != nil {
-			progress.UpdateProgress(globalInd, total, file.Name())
+		if options.Context != nil && options.Context.Err() != nil {
+			localDB.Partial = true
+			return
 		}
-		//skip mac hidden files
-		if file.Name()[0:1] == "." {
+		progress.curr += 1
+		if progress.updater != nil {
+			progress.updater.UpdateProgress(progress.curr, progress.total, file.Name())
+		}
+		if strings.HasPrefix(file.Name(), "._") || (!options.IncludeHidden && strings.HasPrefix(file.Name(), ".")) {
+			continue
+		}
+
+		//skip downloads which are not complete yet, each download is reported once (by its main file)
+		if isDownloadMarker(file.Name(), fileNames) {
+			continue
+		}
+		if isDownloadInProgress(file.Name(), fileNames) {
+			localDB.InProgress = append(localDB.InProgress, ExtendedFileInfo{Info: file, BaseFolder: parentFolder})
 			continue
 		}
 
 		//scan sub-folders if flag is present
 		filePath := filepath.Join(parentFolder, file.Name())
 		if file.IsDir() {
-			if !recurse {
+			if !options.Recursive {
 				continue
 			}
 			folder := filePath
-			innerFiles, err := ioutil.ReadDir(folder)
+			innerFiles, err := ReadFolder(folder, options.RetryCount)
 			if err != nil {
 				zap.S().Errorf("failed scanning NSP folder [%v]", err)
+				//the rest of the library is still scanned
+				localDB.UnreachableFolders = append(localDB.UnreachableFolders, folder)
 				continue
 			}
-			scanLocalFiles(folder, innerFiles, progress, recurse, titles, skipped)
+			scanLocalFiles(folder, innerFiles, progress, options, localDB)
+			if localDB.Partial {
+				return
+			}
+		}
+
+		if options.MaxFileSize > 0 && file.Size() > options.MaxFileSize {
+			zap.S().Warnf("[file:%v] skipped, its size (%v bytes) exceeds the max file size", file.Name(), file.Size())
+			skipped[file] = "file exceeds the max file size"
+			continue
+		}
+
+		if options.ArchiveExtractorCommand != "" && isArchiveFileName(file.Name()) {
+			archiveMetadata, err := readArchiveMetadata(filePath, options)
+			if err != nil {
+				skipped[file] = "unable to read archive"
+				continue
+			}
+			for _, metadata := range archiveMetadata {
+				registerFile(localDB, file, parentFolder, metadata, true, options)
+			}
+			continue
 		}
 
 		//only handle NSZ and NSP files
-		if !strings.HasSuffix(file.Name(), "xci") && !strings.HasSuffix(file.Name(), "nsp") && !strings.HasSuffix(file.Name(), "nsz") {
+		if !isSupportedFileName(file.Name()) {
 			skipped[file] = "non supported File"
 			continue
 		}
 
-		metadata, err := GetGameMetadata(file, filePath)
+		metadata, err := GetGameMetadata(file, filePath, options)
 
 		if err != nil {
-			skipped[file] = "unable to determine titileId / version"
+			skipped[file] = err.Error()
 			continue
 		}
 
-		idPrefix := metadata.TitleId[0 : len(metadata.TitleId)-4]
-		switchTitle := &SwitchFile{Updates: map[int]ExtendedFileInfo{}, Dlc: map[string]ExtendedFileInfo{}, BaseExist: false}
-		if t, ok := titles[idPrefix]; ok {
-			switchTitle = t
-		}
-		titles[idPrefix] = switchTitle
+		registerFile(localDB, file, parentFolder, metadata, false, options)
+	}
 
-		//process Updates
-		if strings.HasSuffix(metadata.TitleId, "800") {
-			metadata.Type = "Update"
-			if update, ok := switchTitle.Updates[metadata.Version]; ok {
-				zap.S().Warnf("-->Duplicate update file found [%v] and [%v]", update.Info.Name(), file.Name())
+}
+
+const (
+	contentTypeBase   = "Base"
+	contentTypeUpdate = "Update"
+	contentTypeDLC    = "DLC"
+)
+
+// the content type derived from the titleId - updates end with 800, base games with 000, anything else is a DLC
+func titleIdContentType(titleId string) string {
+	if strings.HasSuffix(titleId, "800") {
+		return contentTypeUpdate
+	}
+	if strings.HasSuffix(titleId, "000") {
+		return contentTypeBase
+	}
+	return contentTypeDLC
+}
+
+// the content type of the titles DB entry for the titleId, false when the DB has no entry for it
+func titlesDBContentType(titleId string, titlesDB *SwitchTitlesDB) (string, bool) {
+	titleId = strings.ToLower(titleId)
+	switchTitle, ok := titlesDB.TitlesMap[titleId[0:len(titleId)-4]]
+	if !ok {
+		return "", false
+	}
+	if _, ok := switchTitle.Dlc[titleId]; ok {
+		return contentTypeDLC, true
+	}
+	baseId := strings.ToLower(switchTitle.Attributes.Id)
+	if baseId == "" {
+		return "", false
+	}
+	if titleId == baseId {
+		return contentTypeBase, true
+	}
+	if titleId == baseId[0:len(baseId)-3]+"800" {
+		return contentTypeUpdate, true
+	}
+	return "", false
+}
+
+// add the file to its title, according to the content type derived from the titleId
+func registerFile(localDB *LocalSwitchFilesDB, file os.FileInfo, parentFolder string, metadata *switchfs.ContentMetaAttributes,
+	inArchive bool, options ScanOptions) {
+	contentType := titleIdContentType(metadata.TitleId)
+	if options.TitlesDB != nil {
+		if dbContentType, ok := titlesDBContentType(metadata.TitleId, options.TitlesDB); ok {
+			if dbContentType != contentType {
+				zap.S().Infof("[file:%v] typed as %v by the titles DB, instead of %v", file.Name(), dbContentType, contentType)
 			}
-			switchTitle.Updates[metadata.Version] = ExtendedFileInfo{Info: file, BaseFolder: parentFolder, Metadata: metadata}
-			continue
+			contentType = dbContentType
+		}
+	}
+	isUpdate := contentType == contentTypeUpdate
+	isDLC := contentType == contentTypeDLC
+	if (isUpdate && options.SkipUpdates) || (isDLC && options.SkipDLC) {
+		return
+	}
+	titles := localDB.TitlesMap
+	extendedInfo := func() ExtendedFileInfo {
+		extendedFileInfo := newExtendedFileInfo(file, parentFolder, metadata, localDB.tags)
+		extendedFileInfo.InArchive = inArchive
+		return extendedFileInfo
+	}
+	idPrefix := metadata.TitleId[0 : len(metadata.TitleId)-4]
+	switchTitle := &SwitchFile{Updates: map[int]ExtendedFileInfo{}, Dlc: map[string]ExtendedFileInfo{}, BaseExist: false}
+	if t, ok := titles[idPrefix]; ok {
+		switchTitle = t
+	}
+	titles[idPrefix] = switchTitle
+	if file.ModTime().After(switchTitle.LastModified) {
+		switchTitle.LastModified = file.ModTime()
+	}
+
+	//process Updates
+	if isUpdate {
+		metadata.Type = contentTypeUpdate
+		if update, ok := switchTitle.Updates[metadata.Version]; ok && !(reconcilesBundledUpdate(options) && isBundledUpdate(switchTitle, update)) {
+			zap.S().Warnf("-->Duplicate update file found [%v] and [%v]", update.Info.Name(), file.Name())
+			localDB.DuplicateUpdates = append(localDB.DuplicateUpdates,
+				DuplicateFile{File: update, DuplicateOf: extendedInfo()})
 		}
+		switchTitle.Updates[metadata.Version] = extendedInfo()
+		if reconcilesBundledUpdate(options) {
+			reconcileBundledUpdate(switchTitle)
+		}
+		return
+	}
 
-		//process base
-		if strings.HasSuffix(metadata.TitleId, "000") {
-			metadata.Type = "Base"
-			if switchTitle.BaseExist {
-				zap.S().Warnf("-->Duplicate base file found [%v] and [%v]", file.Name(), switchTitle.File.Info.Name())
+	//process base
+	if !isDLC {
+		metadata.Type = contentTypeBase
+		newFile := extendedInfo()
+		if switchTitle.BaseExist {
+			zap.S().Warnf("-->Duplicate base file found [%v] and [%v]", file.Name(), switchTitle.File.Info.Name())
+			existing := switchTitle.File
+			if keepExistingBase(existing, newFile, options.PreferredFormat) {
+				localDB.DuplicateBases = append(localDB.DuplicateBases, DuplicateFile{File: newFile, DuplicateOf: existing})
+				return
 			}
-			switchTitle.File = ExtendedFileInfo{Info: file, BaseFolder: parentFolder, Metadata: metadata}
-			switchTitle.BaseExist = true
-
-			//handle XCI
-			if metadata.Version != 0 {
-				metadata.Type = "Update"
-				switchTitle.Updates[metadata.Version] = ExtendedFileInfo{Info: file, BaseFolder: parentFolder, Metadata: metadata}
+			localDB.DuplicateBases = append(localDB.DuplicateBases, DuplicateFile{File: existing, DuplicateOf: newFile})
+			//drop the update part of a replaced XCI
+			if update, ok := switchTitle.Updates[existing.Metadata.Version]; ok && update.Info == existing.Info {
+				delete(switchTitle.Updates, existing.Metadata.Version)
 			}
-			continue
 		}
+		switchTitle.File = newFile
+		switchTitle.BaseExist = true
 
-		if dlc, ok := switchTitle.Dlc[metadata.TitleId]; ok {
-			zap.S().Warnf("-->Duplicate DLC file found [%v] and [%v]", file.Name(), dlc.Info.Name())
-			if dlc.Metadata.Version > metadata.Version {
-				continue
-			}
+		//handle XCI
+		if metadata.Version != 0 && !options.SkipUpdates && !options.XciBaseOnly {
+			metadata.Type = contentTypeUpdate
+			switchTitle.Updates[metadata.Version] = extendedInfo()
+		}
+		if reconcilesBundledUpdate(options) {
+			reconcileBundledUpdate(switchTitle)
+		}
+		return
+	}
+
+	if dlc, ok := switchTitle.Dlc[metadata.TitleId]; ok {
+		zap.S().Warnf("-->Duplicate DLC file found [%v] and [%v]", file.Name(), dlc.Info.Name())
+		if dlc.Metadata.Version > metadata.Version {
+			return
+		}
+	}
+	//not an update, and not main TitleAttributes, so treat it as a DLC
+	metadata.Type = contentTypeDLC
+	switchTitle.Dlc[metadata.TitleId] = extendedInfo()
+}
+
+func reconcilesBundledUpdate(options ScanOptions) bool {
+	return options.XciBaseOnly && options.XciBundledUpdate && !options.SkipUpdates
+}
+
+// the update entry registered for the update bundled in the base file of the title (XCI)
+func isBundledUpdate(switchTitle *SwitchFile, update ExtendedFileInfo) bool {
+	return switchTitle.BaseExist && update.Info == switchTitle.File.Info
+}
+
+// registers the update bundled in the base XCI of the title, unless a separate update file is at least as new,
+// in which case the XCI only counts as the base. files are registered in any order, so this runs after each of them.
+func reconcileBundledUpdate(switchTitle *SwitchFile) {
+	latestSeparate := 0
+	for version, update := range switchTitle.Updates {
+		if isBundledUpdate(switchTitle, update) {
+			delete(switchTitle.Updates, version)
+		} else if version > latestSeparate {
+			latestSeparate = version
+		}
+	}
+	base := switchTitle.File
+	if !switchTitle.BaseExist || base.Metadata == nil || fileFormat(base.Info.Name()) != FORMAT_XCI ||
+		base.Metadata.Version <= latestSeparate {
+		return
+	}
+	metadata := *base.Metadata
+	metadata.Type = contentTypeUpdate
+	bundled := base
+	bundled.Metadata = &metadata
+	switchTitle.Updates[metadata.Version] = bundled
+}
+
+var downloadMarkerSuffixes = []string{".part", ".aria2"}
+
+// download control files are not part of the library, nor are partial downloads of a file which is present too
+// (game.nsp.part next to game.nsp), the download is reported by the file itself
+func isDownloadMarker(fileName string, folderFileNames map[string]bool) bool {
+	if strings.HasSuffix(fileName, ".aria2") {
+		return true
+	}
+	return strings.HasSuffix(fileName, ".part") && folderFileNames[strings.TrimSuffix(fileName, ".part")]
+}
+
+// a file is considered in progress when it is a partial download (game.nsp.part), or has a download control file next to it (game.nsp.aria2)
+func isDownloadInProgress(fileName string, folderFileNames map[string]bool) bool {
+	if strings.HasSuffix(fileName, ".part") && isSupportedFileName(strings.TrimSuffix(fileName, ".part")) {
+		return true
+	}
+	for _, suffix := range downloadMarkerSuffixes {
+		if folderFileNames[fileName+suffix] {
+			return true
 		}
-		//not an update, and not main TitleAttributes, so treat it as a DLC
-		metadata.Type = "DLC"
-		switchTitle.Dlc[metadata.TitleId] = ExtendedFileInfo{Info: file, BaseFolder: parentFolder, Metadata: metadata}
 	}
+	return false
+}
+
+// the last base file found is kept, unless both files have the same version and only the existing one has the preferred format
+func keepExistingBase(existing ExtendedFileInfo, newFile ExtendedFileInfo, preferredFormat string) bool {
+	if preferredFormat == "" || existing.Metadata == nil || existing.Metadata.Version != newFile.Metadata.Version {
+		return false
+	}
+	return fileFormat(existing.Info.Name()) == preferredFormat && fileFormat(newFile.Info.Name()) != preferredFormat
+}
+
+func fileFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".nsp", ".nsz":
+		return FORMAT_NSP
+	case ".xci", ".xcz":
+		return FORMAT_XCI
+	}
+	return ""
+}
+
+func isSupportedFileName(fileName string) bool {
+	return strings.HasSuffix(fileName, "xci") || strings.HasSuffix(fileName, "nsp") || strings.HasSuffix(fileName, "nsz")
+}
 
+func newExtendedFileInfo(file os.FileInfo, parentFolder string, metadata *switchfs.ContentMetaAttributes, tags *stringPool) ExtendedFileInfo {
+	region, languages := ParseRegionAndLanguagesFromFileName(file.Name())
+	return ExtendedFileInfo{Info: file, BaseFolder: parentFolder, Metadata: metadata,
+		Region: tags.intern(region), Languages: tags.internList(languages)}
 }
 
-func GetGameMetadata(file os.FileInfo, filePath string) (*switchfs.ContentMetaAttributes, error) {
+func readFileMetadata(file os.FileInfo, filePath string) (*switchfs.ContentMetaAttributes, error) {
 	var metadata *switchfs.ContentMetaAttributes = nil
-	keys, _ := settings.SwitchKeys()
 	var err error
+	if strings.HasSuffix(file.Name(), "nsp") || strings.HasSuffix(file.Name(), "nsz") {
+		metadata, err = switchfs.ReadNspMetadata(filePath)
+		if err != nil {
+			zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.Name(), err)
+		}
+	} else if strings.HasSuffix(file.Name(), "xci") {
+		metadata, err = switchfs.ReadXciMetadata(filePath)
+		if err != nil {
+			zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.Name(), err)
+		}
+	}
+	return metadata, err
+}
 
-	if keys != nil && keys.GetKey("header_key") != "" {
-		if strings.HasSuffix(file.Name(), "nsp") || strings.HasSuffix(file.Name(), "nsz") {
-			metadata, err = switchfs.ReadNspMetadata(filePath)
-			if err != nil {
-				zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.Name(), err)
+// GetGameMetadata reads the metadata of the file, or parses it from the file name tags when deep scan is disabled
+// or the file cannot be parsed. when retries are enabled, a file still failing to be read after all of them is
+// returned as an error instead, as the failure is likely the share and not the file.
+func GetGameMetadata(file os.FileInfo, filePath string, options ScanOptions) (*switchfs.ContentMetaAttributes, error) {
+	var metadata *switchfs.ContentMetaAttributes = nil
+	keys, _ := settings.SwitchKeys()
+
+	if !options.DisableDeepScan && keys != nil && keys.GetKey("header_key") != "" {
+		//reads on network shares can fail intermittently, so retry with an increasing delay before giving up
+		var err error
+		delay := retryDelay
+		for attempt := 0; attempt <= options.RetryCount; attempt++ {
+			if attempt > 0 {
+				zap.S().Infof("[file:%v] retrying to read metadata in %v [attempt %v/%v]", file.Name(), delay, attempt, options.RetryCount)
+				time.Sleep(delay)
+				delay *= 2
 			}
-		} else if strings.HasSuffix(file.Name(), "xci") {
-			metadata, err = switchfs.ReadXciMetadata(filePath)
-			if err != nil {
-				zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.Name(), err)
+			metadata, err = readFileMetadata(file, filePath)
+			if metadata != nil {
+				return metadata, nil
 			}
 		}
-
-	}
-
-	if metadata != nil {
-		return metadata, nil
+		if options.RetryCount > 0 && err != nil {
+			return nil, fmt.Errorf("unable to read the file after %v retries - %v", options.RetryCount, err)
+		}
 	}
 
 	//fallback to parse data from filename
 
 	//parse title id
-	titleId, _ := parseTitleIdFromFileName(file.Name())
+	titleId, _ := ParseTitleIdFromFileName(file.Name())
 	version, _ := parseVersionFromFileName(file.Name())
 
 	if titleId == nil || version == nil {
@@ -190,7 +627,7 @@ func parseVersionFromFileName(fileName string) (*int, error) {
 	return &ver, nil
 }
 
-func parseTitleIdFromFileName(fileName string) (*string, error) {
+func ParseTitleIdFromFileName(fileName string) (*string, error) {
 	res := titleIdRegex.FindStringSubmatch(fileName)
 
 	if len(res) != 2 {
@@ -200,6 +637,40 @@ func parseTitleIdFromFileName(fileName string) (*string, error) {
 	return &titleId, nil
 }
 
+// ParseRegionAndLanguagesFromFileName reads the region (e.g. [US], (EUR)) and languages (e.g. [En,Fr,De]) tags of a file name
+func ParseRegionAndLanguagesFromFileName(fileName string) (string, []string) {
+	region := ""
+	var languages []string
+	for _, match := range fileTagRegex.FindAllStringSubmatch(fileName, -1) {
+		tag := strings.ToUpper(strings.TrimSpace(match[1]))
+		if regionTags[tag] {
+			if region == "" {
+				region = NormalizeRegion(tag)
+			}
+			continue
+		}
+		if languages != nil {
+			continue
+		}
+		tokens := strings.FieldsFunc(tag, func(r rune) bool {
+			return r == ',' || r == '+' || r == ' '
+		})
+		allLanguages := len(tokens) != 0
+		for _, token := range tokens {
+			if !languageTags[token] {
+				allLanguages = false
+				break
+			}
+		}
+		if allLanguages {
+			for _, token := range tokens {
+				languages = append(languages, strings.ToLower(token))
+			}
+		}
+	}
+	return region, languages
+}
+
 func ParseTitleNameFromFileName(fileName string) string {
 	ind := strings.Index(fileName, "[")
 	if ind != -1 {
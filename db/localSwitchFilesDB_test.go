@@ -0,0 +1,712 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDisableDeepScan(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	//any header key enables the deep scan, the empty test file then fails to be parsed
+	if err := ioutil.WriteFile(filepath.Join(folder, "prod.keys"), []byte("header_key = 00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", folder)
+	t.Setenv("SLM_KEYS", "")
+	if _, err := settings.InitSwitchKeys(folder); err != nil {
+		t.Fatal(err)
+	}
+	//leave the keys without a header key, which turns the deep scan off for the other tests
+	defer func() {
+		_ = ioutil.WriteFile(filepath.Join(folder, "prod.keys"), []byte{}, 0644)
+		_, _ = settings.InitSwitchKeys(folder)
+	}()
+	if err := ioutil.WriteFile(filepath.Join(folder, "Game [0100000000010000][v0].nsp"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		disableDeepScan bool
+		wantParsed      bool
+	}{
+		{"keys present", false, true},
+		{"deep scan disabled", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			core, logs := observer.New(zap.ErrorLevel)
+			defer zap.ReplaceGlobals(zap.New(core))()
+			files, err := ioutil.ReadDir(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			localDB, err := CreateLocalSwitchFilesDB(files, folder, nil, ScanOptions{DisableDeepScan: test.disableDeepScan})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed := logs.FilterMessageSnippet("failed to read NSP").Len() != 0; parsed != test.wantParsed {
+				t.Errorf("parsed the file with the keys = %v, want %v", parsed, test.wantParsed)
+			}
+			//either way the file is identified by its name tags
+			if switchFile, ok := localDB.TitlesMap["010000000001"]; !ok || !switchFile.BaseExist {
+				t.Errorf("got titles %v, want the game identified by its name tags", localDB.TitlesMap)
+			}
+		})
+	}
+}
+
+// createTestFiles creates empty files named after their tags (such as "Game [0100000000010000][v0].nsp") in a
+// new temp folder, with the given modification times, and returns the folder
+func createTestFiles(t *testing.T, files map[string]time.Time) string {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "slm-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(folder) })
+	for name, modTime := range files {
+		filePath := filepath.Join(folder, name)
+		if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if !modTime.IsZero() {
+			if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return folder
+}
+
+// scanTestFolder scans the folder by the file name tags
+func scanTestFolder(t *testing.T, folder string, options ScanOptions) *LocalSwitchFilesDB {
+	t.Helper()
+	options.DisableDeepScan = true
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localDB, err := CreateLocalSwitchFilesDB(files, folder, nil, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return localDB
+}
+
+func TestDownloadsInProgress(t *testing.T) {
+	const game = "Game [0100000000010000][v0].nsp"
+	tests := []struct {
+		name           string
+		files          []string
+		wantInProgress []string
+		wantOwned      bool
+	}{
+		{"complete download", []string{game}, nil, true},
+		{"aria2 control file", []string{game, game + ".aria2"}, []string{game}, false},
+		{"partial download", []string{game + ".part"}, []string{game + ".part"}, false},
+		{"partial download next to the file", []string{game, game + ".part"}, []string{game}, false},
+		{"aria2 downloading to a partial file", []string{game + ".part", game + ".part.aria2"}, []string{game + ".part"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string]time.Time{}
+			for _, name := range test.files {
+				files[name] = time.Time{}
+			}
+			localDB := scanTestFolder(t, createTestFiles(t, files), ScanOptions{})
+			var inProgress []string
+			for _, f := range localDB.InProgress {
+				inProgress = append(inProgress, f.Info.Name())
+			}
+			if !reflect.DeepEqual(inProgress, test.wantInProgress) {
+				t.Errorf("in progress %v, want %v", inProgress, test.wantInProgress)
+			}
+			if _, owned := localDB.TitlesMap["010000000001"]; owned != test.wantOwned {
+				t.Errorf("owned %v, want %v", owned, test.wantOwned)
+			}
+			if len(localDB.Skipped) != 0 {
+				t.Errorf("skipped %v, want the download files left out", localDB.Skipped)
+			}
+		})
+	}
+}
+
+func TestParseRegionAndLanguagesFromFileName(t *testing.T) {
+	tests := []struct {
+		fileName      string
+		wantRegion    string
+		wantLanguages []string
+	}{
+		{"Game [0100000000010000][v0].nsp", "", nil},
+		{"Game [0100000000010000][v0][US].nsp", "US", nil},
+		{"Game (EU) [0100000000010000][v0].nsp", "EU", nil},
+		{"Game [0100000000010000][v0] (jp).nsp", "JP", nil},
+		{"Game [0100000000010000][v0][En,Fr,De].nsp", "", []string{"en", "fr", "de"}},
+		{"Game [0100000000010000][v0](En+Ja).nsp", "", []string{"en", "ja"}},
+		{"Game [0100000000010000][v0][EU][En Fr].nsp", "EU", []string{"en", "fr"}},
+		{"Game [0100000000010000][v0][En][US][JP][Fr].nsp", "US", []string{"en"}},
+		{"Game [0100000000010000][v0][En,Klingon].nsp", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			region, languages := ParseRegionAndLanguagesFromFileName(test.fileName)
+			if region != test.wantRegion || !reflect.DeepEqual(languages, test.wantLanguages) {
+				t.Errorf("got region %q and languages %v, want %q and %v", region, languages, test.wantRegion, test.wantLanguages)
+			}
+		})
+	}
+}
+
+func TestScannedFilesHaveTheirRegionAndLanguages(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0][US][En,Fr].nsp": {},
+		"Game [0100000000010800][v65536].nsp":        {},
+	})
+	localDB := scanTestFolder(t, folder, ScanOptions{})
+	switchFile, ok := localDB.TitlesMap["010000000001"]
+	if !ok {
+		t.Fatalf("got titles %v, want the game", localDB.TitlesMap)
+	}
+	if switchFile.File.Region != "US" || !reflect.DeepEqual(switchFile.File.Languages, []string{"en", "fr"}) {
+		t.Errorf("got base region %q and languages %v, want US and [en fr]", switchFile.File.Region, switchFile.File.Languages)
+	}
+	//the update is not tagged
+	if update := switchFile.Updates[65536]; update.Region != "" || update.Languages != nil {
+		t.Errorf("got update region %q and languages %v, want none", update.Region, update.Languages)
+	}
+}
+
+func TestConcurrentReadsDuringRescanAndUpdates(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp":      {},
+		"Game [0100000000010800][v65536].nsp":  {},
+		"Game [0100000000010800][v131072].nsp": {},
+		"Game DLC [0100000000011001][v0].nsp":  {},
+	})
+	localDB := scanTestFolder(t, folder, ScanOptions{})
+
+	done := make(chan bool)
+	readers := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					readers <- true
+					return
+				default:
+				}
+				for _, switchFile := range localDB.Titles() {
+					for range switchFile.Updates {
+					}
+					_ = switchFile.Files()
+				}
+				if switchFile, ok := localDB.GetTitle("010000000001"); ok {
+					_ = len(switchFile.Updates)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		//a background rescan, while the current DB is being changed in place
+		rescan := scanTestFolder(t, folder, ScanOptions{})
+		switchFile, _ := rescan.GetTitle("010000000001")
+		localDB.SetUpdates("010000000001", map[int]ExtendedFileInfo{131072: switchFile.Updates[131072]})
+		_ = RecordTitleDates(filepath.Join(folder, "title_dates.json"), localDB)
+	}
+	close(done)
+	for i := 0; i < 4; i++ {
+		<-readers
+	}
+
+	switchFile, _ := localDB.GetTitle("010000000001")
+	if len(switchFile.Updates) != 1 {
+		t.Errorf("updates = %v, want only the latest update", len(switchFile.Updates))
+	}
+}
+
+func TestTitlesReturnsCopies(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp":     {},
+		"Game [0100000000010800][v65536].nsp": {},
+	})
+	localDB := scanTestFolder(t, folder, ScanOptions{})
+	tests := []struct {
+		name   string
+		change func(switchFile *SwitchFile)
+	}{
+		{"update removed", func(switchFile *SwitchFile) { delete(switchFile.Updates, 65536) }},
+		{"updates replaced", func(switchFile *SwitchFile) { switchFile.Updates = nil }},
+		{"DLC added", func(switchFile *SwitchFile) { switchFile.Dlc["0100000000011001"] = ExtendedFileInfo{} }},
+		{"base removed", func(switchFile *SwitchFile) { switchFile.BaseExist = false }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.change(localDB.Titles()["010000000001"])
+			copied, _ := localDB.GetTitle("010000000001")
+			test.change(copied)
+			switchFile := localDB.TitlesMap["010000000001"]
+			if len(switchFile.Updates) != 1 || len(switchFile.Dlc) != 0 || !switchFile.BaseExist {
+				t.Errorf("the DB title was changed through a copy: %+v", switchFile)
+			}
+		})
+	}
+}
+
+func TestConcurrentScansReportTheirOwnProgress(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"First [0100000000010000][v0].nsp":  {},
+		"Second [0100000000020000][v0].nsp": {},
+		"Third [0100000000030000][v0].nsp":  {},
+	})
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progresses := make([]*recordingProgress, 4)
+	wg := sync.WaitGroup{}
+	for i := range progresses {
+		progresses[i] = &recordingProgress{}
+		wg.Add(1)
+		go func(progress *recordingProgress) {
+			defer wg.Done()
+			if _, err := CreateLocalSwitchFilesDB(files, folder, progress, ScanOptions{DisableDeepScan: true}); err != nil {
+				t.Error(err)
+			}
+		}(progresses[i])
+	}
+	wg.Wait()
+	for i, progress := range progresses {
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(progress.updates, want) {
+			t.Errorf("scan %v reported the progress %v, want %v", i, progress.updates, want)
+		}
+	}
+}
+
+func TestHiddenFiles(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp":    {},
+		"._Game [0100000000010000][v0].nsp":  {},
+		".Hidden [0100000000020000][v0].nsp": {},
+		"._Other [0100000000030000][v0].nsp": {},
+	})
+	tests := []struct {
+		name       string
+		options    ScanOptions
+		wantTitles []string
+	}{
+		{"hidden files are skipped by default", ScanOptions{}, []string{"010000000001"}},
+		{"hidden files included, but not the resource forks", ScanOptions{IncludeHidden: true}, []string{"010000000001", "010000000002"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB := scanTestFolder(t, folder, test.options)
+			var titles []string
+			for idPrefix := range localDB.TitlesMap {
+				titles = append(titles, idPrefix)
+			}
+			sort.Strings(titles)
+			if !reflect.DeepEqual(titles, test.wantTitles) {
+				t.Errorf("titles %v, want %v", titles, test.wantTitles)
+			}
+			if len(localDB.Skipped) != 0 || len(localDB.DuplicateBases) != 0 {
+				t.Errorf("skipped %v, duplicates %v, want the hidden files left out", localDB.Skipped, localDB.DuplicateBases)
+			}
+		})
+	}
+}
+
+func TestDuplicateUpdates(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          []string
+		wantDuplicates [][]string
+	}{
+		{"same version", []string{"Game [0100000000010800][v131072].nsp", "Game redump [0100000000010800][v131072].nsp"},
+			[][]string{{"Game [0100000000010800][v131072].nsp", "Game redump [0100000000010800][v131072].nsp"}}},
+		{"different versions", []string{"Game [0100000000010800][v65536].nsp", "Game [0100000000010800][v131072].nsp"}, nil},
+		{"same version of different titles", []string{"Game [0100000000010800][v65536].nsp", "Other [0100000000020800][v65536].nsp"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string]time.Time{}
+			for _, name := range test.files {
+				files[name] = time.Time{}
+			}
+			localDB := scanTestFolder(t, createTestFiles(t, files), ScanOptions{})
+
+			var duplicates [][]string
+			for _, duplicate := range localDB.DuplicateUpdates {
+				pair := []string{duplicate.File.Info.Name(), duplicate.DuplicateOf.Info.Name()}
+				sort.Strings(pair)
+				duplicates = append(duplicates, pair)
+			}
+			if !reflect.DeepEqual(duplicates, test.wantDuplicates) {
+				t.Errorf("duplicate updates %v, want %v", duplicates, test.wantDuplicates)
+			}
+		})
+	}
+}
+
+func TestPreferredFormatOfDuplicateBases(t *testing.T) {
+	tests := []struct {
+		name            string
+		preferredFormat string
+		wantKept        string
+		wantDuplicate   string
+	}{
+		{"prefer nsp", FORMAT_NSP, "Game [0100000000010000][v0].nsp", "Game [0100000000010000][v0].xci"},
+		{"prefer xci", FORMAT_XCI, "Game [0100000000010000][v0].xci", "Game [0100000000010000][v0].nsp"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := createTestFiles(t, map[string]time.Time{
+				"Game [0100000000010000][v0].nsp": {},
+				"Game [0100000000010000][v0].xci": {},
+			})
+			localDB := scanTestFolder(t, folder, ScanOptions{PreferredFormat: test.preferredFormat})
+
+			title, ok := localDB.TitlesMap["010000000001"]
+			if !ok || !title.BaseExist {
+				t.Fatal("the base was not found")
+			}
+			if title.File.Info.Name() != test.wantKept {
+				t.Errorf("kept %v, want %v", title.File.Info.Name(), test.wantKept)
+			}
+			if len(localDB.DuplicateBases) != 1 || localDB.DuplicateBases[0].File.Info.Name() != test.wantDuplicate {
+				t.Fatalf("duplicates %+v, want %v", localDB.DuplicateBases, test.wantDuplicate)
+			}
+		})
+	}
+}
+
+func TestSkippedContentTypes(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp":     {},
+		"Game [0100000000010800][v65536].nsp": {},
+		"Game DLC [0100000000011001][v0].nsp": {},
+		//a title with only an update and a DLC
+		"Other [0100000000020800][v65536].nsp": {},
+		"Other DLC [0100000000021001][v0].nsp": {},
+	})
+	tests := []struct {
+		name        string
+		options     ScanOptions
+		wantTitles  []string
+		wantUpdates int
+		wantDlc     int
+	}{
+		{"nothing skipped", ScanOptions{}, []string{"010000000001", "010000000002"}, 2, 2},
+		{"updates skipped", ScanOptions{SkipUpdates: true}, []string{"010000000001", "010000000002"}, 0, 2},
+		{"DLC skipped", ScanOptions{SkipDLC: true}, []string{"010000000001", "010000000002"}, 2, 0},
+		{"base games only", ScanOptions{SkipUpdates: true, SkipDLC: true}, []string{"010000000001"}, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB := scanTestFolder(t, folder, test.options)
+			var titles []string
+			updates, dlc := 0, 0
+			for idPrefix, switchFile := range localDB.TitlesMap {
+				titles = append(titles, idPrefix)
+				updates += len(switchFile.Updates)
+				dlc += len(switchFile.Dlc)
+			}
+			sort.Strings(titles)
+			if !reflect.DeepEqual(titles, test.wantTitles) {
+				t.Errorf("titles %v, want %v", titles, test.wantTitles)
+			}
+			if updates != test.wantUpdates || dlc != test.wantDlc {
+				t.Errorf("got %v updates and %v DLC, want %v and %v", updates, dlc, test.wantUpdates, test.wantDlc)
+			}
+			if len(localDB.Skipped) != 0 {
+				t.Errorf("skipped %v, want the skipped content types left out of the skipped files", localDB.Skipped)
+			}
+		})
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	const oversized = "Oversized [0100000000020000][v0].nsp"
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp": {},
+		oversized:                         {},
+	})
+	//a sparse file, taking no actual disk space
+	if err := os.Truncate(filepath.Join(folder, oversized), 10<<30); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		maxFileSize int64
+		wantSkipped bool
+	}{
+		{"unlimited", 0, false},
+		{"below the max size", 20 << 30, false},
+		{"above the max size", 1 << 30, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB := scanTestFolder(t, folder, ScanOptions{MaxFileSize: test.maxFileSize})
+			if _, ok := localDB.TitlesMap["010000000001"]; !ok {
+				t.Error("the small file was not scanned")
+			}
+			if _, registered := localDB.TitlesMap["010000000002"]; registered == test.wantSkipped {
+				t.Errorf("registered = %v, want %v", registered, !test.wantSkipped)
+			}
+			var skipped []string
+			for file, reason := range localDB.Skipped {
+				skipped = append(skipped, file.Name()+": "+reason)
+			}
+			var wantSkipped []string
+			if test.wantSkipped {
+				wantSkipped = []string{oversized + ": file exceeds the max file size"}
+			}
+			if !reflect.DeepEqual(skipped, wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, wantSkipped)
+			}
+		})
+	}
+}
+
+func TestTypeByTitlesDB(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"Game [0100000000010000][v0].nsp":     {},
+		"Game [0100000000010800][v65536].nsp": {},
+		//DLC whose titleId looks like a base and an update
+		"Game DLC [0100000000012000][v0].nsp": {},
+		"Game DLC [0100000000011800][v0].nsp": {},
+		//a title missing from the titles DB is typed by its tag
+		"Other [0100000000020000][v0].nsp": {},
+	})
+	titlesDB := &SwitchTitlesDB{TitlesMap: map[string]*SwitchTitle{
+		"010000000001": {Attributes: TitleAttributes{Id: "0100000000010000", Name: "Game"}, Dlc: map[string]TitleAttributes{
+			"0100000000012000": {Id: "0100000000012000", Name: "DLC 1"},
+			"0100000000011800": {Id: "0100000000011800", Name: "DLC 2"},
+		}},
+	}}
+	tests := []struct {
+		name        string
+		titlesDB    *SwitchTitlesDB
+		wantBase    string
+		wantUpdates []int
+		wantDlc     []string
+	}{
+		//by the tags, the DLC are a duplicate base and an update
+		{"typed by the tags", nil, "Game [0100000000010000][v0].nsp", []int{0, 65536}, nil},
+		{"typed by the titles DB", titlesDB, "Game [0100000000010000][v0].nsp", []int{65536}, []string{"0100000000011800", "0100000000012000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB := scanTestFolder(t, folder, ScanOptions{TitlesDB: test.titlesDB})
+
+			title := localDB.TitlesMap["010000000001"]
+			if !title.BaseExist || title.File.Info.Name() != test.wantBase {
+				t.Errorf("got base %v, want %v", title.File.Info.Name(), test.wantBase)
+			}
+			var updates []int
+			for version := range title.Updates {
+				updates = append(updates, version)
+			}
+			sort.Ints(updates)
+			var dlc []string
+			for id, f := range title.Dlc {
+				dlc = append(dlc, id)
+				if f.Metadata.Type != contentTypeDLC {
+					t.Errorf("DLC %v is typed %v", id, f.Metadata.Type)
+				}
+			}
+			sort.Strings(dlc)
+			if !reflect.DeepEqual(updates, test.wantUpdates) || !reflect.DeepEqual(dlc, test.wantDlc) {
+				t.Errorf("got updates %v and DLC %v, want %v and %v", updates, dlc, test.wantUpdates, test.wantDlc)
+			}
+			if other := localDB.TitlesMap["010000000002"]; other == nil || !other.BaseExist {
+				t.Errorf("got title %+v, want the base typed by its tag", other)
+			}
+		})
+	}
+}
+
+func TestNormalizeRegion(t *testing.T) {
+	defaultAliases, defaultTags := map[string]string{}, map[string]bool{}
+	for variant, canonical := range regionAliases {
+		defaultAliases[variant] = canonical
+	}
+	for tag := range regionTags {
+		defaultTags[tag] = true
+	}
+	defer func() { regionAliases, regionTags = defaultAliases, defaultTags }()
+	SetRegionAliases(map[string]string{" na ": "us", "Nordic": "EU", "": "US"})
+
+	tests := []struct {
+		fileName   string
+		wantRegion string
+	}{
+		{"Game [0100000000010000][v0][US].nsp", "US"},
+		{"Game [0100000000010000][v0][USA].nsp", "US"},
+		{"Game (NTSC-U) [0100000000010000][v0].nsp", "US"},
+		{"Game [0100000000010000][v0] (usa).nsp", "US"},
+		{"Game [0100000000010000][v0][Europe].nsp", "EU"},
+		{"Game [0100000000010000][v0][PAL].nsp", "EU"},
+		{"Game [0100000000010000][v0][JPN].nsp", "JP"},
+		{"Game [0100000000010000][v0][UK].nsp", "GB"},
+		//without an alias, region tags are kept as is
+		{"Game [0100000000010000][v0][Asia].nsp", "ASIA"},
+		//variants added by the region_aliases setting
+		{"Game [0100000000010000][v0][NA].nsp", "US"},
+		{"Game [0100000000010000][v0][Nordic].nsp", "EU"},
+	}
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			if region, _ := ParseRegionAndLanguagesFromFileName(test.fileName); region != test.wantRegion {
+				t.Errorf("got region %q, want %q", region, test.wantRegion)
+			}
+		})
+	}
+}
+
+// cancellingProgress cancels the scan context once the given number of files were reached
+type cancellingProgress struct {
+	after  int
+	cancel context.CancelFunc
+}
+
+func (c *cancellingProgress) UpdateProgress(curr int, total int, message string) {
+	if curr == c.after {
+		c.cancel()
+	}
+}
+
+func TestScanDeadline(t *testing.T) {
+	folder := createTestFiles(t, map[string]time.Time{
+		"First [0100000000010000][v0].nsp":  {},
+		"Second [0100000000020000][v0].nsp": {},
+		"Third [0100000000030000][v0].nsp":  {},
+		"Fourth [0100000000040000][v0].nsp": {},
+	})
+	files, err := ReadFolder(folder, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		context     func() (context.Context, context.CancelFunc)
+		cancelAfter int
+		wantTitles  int
+		wantPartial bool
+	}{
+		{"no deadline", nil, 0, 4, false},
+		{"deadline not reached", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Hour)
+		}, 0, 4, false},
+		{"deadline already passed", func() (context.Context, context.CancelFunc) {
+			return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		}, 0, 0, true},
+		{"deadline reached during the scan", func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		}, 2, 2, true},
+		{"deadline reached on the last file", func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		}, 4, 4, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := ScanOptions{DisableDeepScan: true}
+			var progress ProgressUpdater
+			if test.context != nil {
+				ctx, cancel := test.context()
+				defer cancel()
+				options.Context = ctx
+				if test.cancelAfter != 0 {
+					progress = &cancellingProgress{after: test.cancelAfter, cancel: cancel}
+				}
+			}
+			localDB, err := CreateLocalSwitchFilesDB(files, folder, progress, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(localDB.TitlesMap) != test.wantTitles || localDB.Partial != test.wantPartial {
+				t.Errorf("got %v titles (partial %v), want %v titles (partial %v)",
+					len(localDB.TitlesMap), localDB.Partial, test.wantTitles, test.wantPartial)
+			}
+		})
+	}
+}
+
+func TestXciBundledUpdate(t *testing.T) {
+	xci := "Game [0100000000010000][v131072].xci"
+	tests := []struct {
+		name        string
+		options     ScanOptions
+		updateFiles []string
+		wantUpdates map[int]string
+	}{
+		{"xci counted as base and update", ScanOptions{},
+			[]string{"Game [0100000000010800][v65536].nsp"},
+			map[int]string{65536: "Game [0100000000010800][v65536].nsp", 131072: xci}},
+		{"xci as base only", ScanOptions{XciBaseOnly: true},
+			[]string{"Game [0100000000010800][v65536].nsp"},
+			map[int]string{65536: "Game [0100000000010800][v65536].nsp"}},
+		{"bundled update without update files", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true},
+			nil,
+			map[int]string{131072: xci}},
+		{"bundled update newer than the update files", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true},
+			[]string{"Game [0100000000010800][v65536].nsp"},
+			map[int]string{65536: "Game [0100000000010800][v65536].nsp", 131072: xci}},
+		{"update file as new as the bundled update", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true},
+			[]string{"Game [0100000000010800][v131072].nsp"},
+			map[int]string{131072: "Game [0100000000010800][v131072].nsp"}},
+		{"update file newer than the bundled update", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true},
+			[]string{"Game [0100000000010800][v65536].nsp", "Game [0100000000010800][v196608].nsp"},
+			map[int]string{65536: "Game [0100000000010800][v65536].nsp", 196608: "Game [0100000000010800][v196608].nsp"}},
+		{"update file scanned before the xci", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true},
+			[]string{"A update [0100000000010800][v196608].nsp"},
+			map[int]string{196608: "A update [0100000000010800][v196608].nsp"}},
+		{"updates skipped", ScanOptions{XciBaseOnly: true, XciBundledUpdate: true, SkipUpdates: true},
+			[]string{"Game [0100000000010800][v65536].nsp"},
+			map[int]string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string]time.Time{xci: {}}
+			for _, name := range test.updateFiles {
+				files[name] = time.Time{}
+			}
+			localDB := scanTestFolder(t, createTestFiles(t, files), test.options)
+
+			title, ok := localDB.TitlesMap["010000000001"]
+			if !ok || !title.BaseExist || title.File.Info.Name() != xci {
+				t.Fatalf("got title %+v, want the xci as the base", title)
+			}
+			updates := map[int]string{}
+			for version, update := range title.Updates {
+				updates[version] = update.Info.Name()
+			}
+			if !reflect.DeepEqual(updates, test.wantUpdates) {
+				t.Errorf("updates %v, want %v", updates, test.wantUpdates)
+			}
+			if len(localDB.DuplicateUpdates) != 0 {
+				t.Errorf("duplicate updates %+v, want none", localDB.DuplicateUpdates)
+			}
+			if title.File.Metadata.Type != contentTypeBase && test.options.XciBaseOnly {
+				t.Errorf("the base is typed %v", title.File.Metadata.Type)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// ThrottledProgressUpdater forwards at most one progress update per interval, so that a fast scan does not flood
+// a slow UI with redraws. the final update (curr == total) is always forwarded.
+type ThrottledProgressUpdater struct {
+	updater    ProgressUpdater
+	interval   time.Duration
+	lastUpdate time.Time
+	mutex      sync.Mutex
+}
+
+func NewThrottledProgressUpdater(updater ProgressUpdater, interval time.Duration) *ThrottledProgressUpdater {
+	return &ThrottledProgressUpdater{updater: updater, interval: interval}
+}
+
+func (t *ThrottledProgressUpdater) UpdateProgress(curr int, total int, message string) {
+	t.mutex.Lock()
+	now := time.Now()
+	if curr < total && now.Sub(t.lastUpdate) < t.interval {
+		t.mutex.Unlock()
+		return
+	}
+	t.lastUpdate = now
+	t.mutex.Unlock()
+	t.updater.UpdateProgress(curr, total, message)
+}
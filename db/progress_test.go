@@ -0,0 +1,64 @@
+package db
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingProgress keeps the progress updates it receives
+type recordingProgress struct {
+	mutex   sync.Mutex
+	updates []int
+}
+
+func (r *recordingProgress) UpdateProgress(curr int, total int, message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.updates = append(r.updates, curr)
+}
+
+func TestThrottledProgressUpdater(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     []int
+	}{
+		{"not throttled", 0, []int{1, 2, 3, 4, 5}},
+		{"throttled", time.Hour, []int{1, 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &recordingProgress{}
+			throttled := NewThrottledProgressUpdater(recorder, test.interval)
+			for i := 1; i <= 5; i++ {
+				throttled.UpdateProgress(i, 5, "file")
+			}
+			if !reflect.DeepEqual(recorder.updates, test.want) {
+				t.Errorf("forwarded %v, want %v", recorder.updates, test.want)
+			}
+		})
+	}
+}
+
+func TestThrottledProgressUpdaterConcurrent(t *testing.T) {
+	recorder := &recordingProgress{}
+	throttled := NewThrottledProgressUpdater(recorder, time.Hour)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				throttled.UpdateProgress(j, 100, "file")
+			}
+		}()
+	}
+	wg.Wait()
+	throttled.UpdateProgress(100, 100, "file")
+	//the first update, and the final one
+	if len(recorder.updates) != 2 || recorder.updates[1] != 100 {
+		t.Errorf("forwarded %v, want the first and the final update", recorder.updates)
+	}
+}
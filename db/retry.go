@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// UnreachableError is returned when a folder still cannot be read after all the retries,
+// typically a network share that disconnected
+type UnreachableError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("share unreachable [%v] - %v", e.Path, e.Err)
+}
+
+// ReadFolder lists the folder content, retrying with an increasing delay (reads of network shares can fail
+// intermittently). missing folders are not retried.
+func ReadFolder(folder string, retryCount int) ([]os.FileInfo, error) {
+	var files []os.FileInfo
+	err := withRetry(folder, retryCount, func() error {
+		var err error
+		files, err = ioutil.ReadDir(folder)
+		return err
+	})
+	return files, err
+}
+
+// StatPath is os.Stat, retried like ReadFolder
+func StatPath(path string, retryCount int) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := withRetry(path, retryCount, func() error {
+		var err error
+		info, err = os.Stat(path)
+		return err
+	})
+	return info, err
+}
+
+func withRetry(path string, retryCount int, operation func() error) error {
+	err := operation()
+	delay := retryDelay
+	for attempt := 1; attempt <= retryCount && err != nil && !os.IsNotExist(err); attempt++ {
+		zap.S().Infof("[path:%v] retrying in %v [attempt %v/%v, reason: %v]", path, delay, attempt, retryCount, err)
+		time.Sleep(delay)
+		delay *= 2
+		err = operation()
+	}
+	if err != nil && retryCount > 0 && !os.IsNotExist(err) {
+		return &UnreachableError{Path: path, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+// useUnreadableKeys makes the deep scan run, the empty test files then fail to be read
+func useUnreadableKeys(t *testing.T) {
+	t.Helper()
+	folder := createTestFiles(t, map[string]time.Time{})
+	if err := ioutil.WriteFile(filepath.Join(folder, "prod.keys"), []byte("header_key = 00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", folder)
+	t.Setenv("SLM_KEYS", "")
+	if _, err := settings.InitSwitchKeys(folder); err != nil {
+		t.Fatal(err)
+	}
+	//leave the keys without a header key, which turns the deep scan off for the other tests
+	t.Cleanup(func() {
+		_ = ioutil.WriteFile(filepath.Join(folder, "prod.keys"), []byte{}, 0644)
+		_, _ = settings.InitSwitchKeys(folder)
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	SetRetryDelay(time.Millisecond)
+	defer SetRetryDelay(500 * time.Millisecond)
+	failure := errors.New("read failed")
+	tests := []struct {
+		name            string
+		retryCount      int
+		failures        int
+		wantAttempts    int
+		wantUnreachable bool
+	}{
+		{"no retries, success", 0, 0, 1, false},
+		{"no retries, failure", 0, 1, 1, false},
+		{"retries, success on the first try", 3, 0, 1, false},
+		{"retries, success on a retry", 3, 2, 3, false},
+		{"retries run out", 2, 5, 3, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry("path", test.retryCount, func() error {
+				attempts++
+				if attempts <= test.failures {
+					return failure
+				}
+				return nil
+			})
+			if attempts != test.wantAttempts {
+				t.Errorf("attempts = %v, want %v", attempts, test.wantAttempts)
+			}
+			_, unreachable := err.(*UnreachableError)
+			if unreachable != test.wantUnreachable {
+				t.Errorf("err = %v, want unreachable %v", err, test.wantUnreachable)
+			}
+		})
+	}
+}
+
+func TestWithRetryBacksOff(t *testing.T) {
+	SetRetryDelay(10 * time.Millisecond)
+	defer SetRetryDelay(500 * time.Millisecond)
+	start := time.Now()
+	_ = withRetry("path", 3, func() error { return errors.New("read failed") })
+	//10ms, then 20ms, then 40ms
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("3 retries took %v, want at least 70ms", elapsed)
+	}
+}
+
+func TestUnreadableFilesAreSkippedAfterRetries(t *testing.T) {
+	SetRetryDelay(time.Millisecond)
+	defer SetRetryDelay(500 * time.Millisecond)
+	useUnreadableKeys(t)
+	tests := []struct {
+		name        string
+		retryCount  int
+		wantSkipped bool
+	}{
+		{"no retries falls back to the name tags", 0, false},
+		{"retries run out", 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := createTestFiles(t, map[string]time.Time{"Game [0100000000010000][v0].nsp": {}})
+			files, err := ioutil.ReadDir(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			localDB, _ := CreateLocalSwitchFilesDB(files, folder, nil, ScanOptions{RetryCount: test.retryCount})
+			_, registered := localDB.TitlesMap["010000000001"]
+			if registered == test.wantSkipped {
+				t.Errorf("registered = %v, want %v", registered, !test.wantSkipped)
+			}
+			if !test.wantSkipped {
+				return
+			}
+			if len(localDB.Skipped) != 1 {
+				t.Fatalf("skipped %v, want the unreadable file", localDB.Skipped)
+			}
+			for _, reason := range localDB.Skipped {
+				if !strings.Contains(reason, "after 2 retries") {
+					t.Errorf("skip reason = %v, want the read error", reason)
+				}
+			}
+		})
+	}
+}
+
+func TestUnreachableFoldersKeepPartialResults(t *testing.T) {
+	SetRetryDelay(time.Millisecond)
+	defer SetRetryDelay(500 * time.Millisecond)
+	tests := []struct {
+		name string
+		//breaks the listed sub folder before it is scanned
+		breakFolder func(folder string) error
+		retryCount  int
+	}{
+		{"folder removed", os.RemoveAll, 0},
+		{"folder unreadable after the retries", func(folder string) error {
+			if err := os.RemoveAll(folder); err != nil {
+				return err
+			}
+			return ioutil.WriteFile(folder, []byte{}, 0644)
+		}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := createTestFiles(t, map[string]time.Time{"Game [0100000000010000][v0].nsp": {}})
+			for _, name := range []string{"share", "local"} {
+				if err := os.Mkdir(filepath.Join(folder, name), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ioutil.WriteFile(filepath.Join(folder, "local", "Other [0100000000020000][v0].nsp"), []byte{}, 0644); err != nil {
+				t.Fatal(err)
+			}
+			files, err := ReadFolder(folder, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			share := filepath.Join(folder, "share")
+			if err := test.breakFolder(share); err != nil {
+				t.Fatal(err)
+			}
+
+			localDB, err := CreateLocalSwitchFilesDB(files, folder, nil,
+				ScanOptions{Recursive: true, DisableDeepScan: true, RetryCount: test.retryCount})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(localDB.UnreachableFolders, []string{share}) {
+				t.Errorf("unreachable folders %v, want %v", localDB.UnreachableFolders, share)
+			}
+			for _, idPrefix := range []string{"010000000001", "010000000002"} {
+				if _, ok := localDB.GetTitle(idPrefix); !ok {
+					t.Errorf("title %v is missing from the partial results", idPrefix)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFolderReportsUnreachableShares(t *testing.T) {
+	SetRetryDelay(time.Millisecond)
+	defer SetRetryDelay(500 * time.Millisecond)
+	folder := createTestFiles(t, map[string]time.Time{"file": {}})
+	//reading a file as a folder fails like a disconnected share
+	_, err := ReadFolder(filepath.Join(folder, "file"), 2)
+	if unreachable, ok := err.(*UnreachableError); !ok || !strings.Contains(err.Error(), "share unreachable") ||
+		unreachable.Path != filepath.Join(folder, "file") {
+		t.Errorf("got error %v, want the share unreachable error", err)
+	}
+	//missing folders are reported as is, without retries
+	if _, err := ReadFolder(filepath.Join(folder, "missing"), 2); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want a not exist error", err)
+	}
+}
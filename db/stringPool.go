@@ -0,0 +1,46 @@
+package db
+
+import (
+	"strings"
+	"sync"
+)
+
+// stringPool keeps a single copy of the strings repeated across the files of a library (region and language tags),
+// so that large libraries do not hold thousands of identical copies. a nil pool keeps the strings as they are
+type stringPool struct {
+	sync.Mutex
+	strings map[string]string
+	lists   map[string][]string
+}
+
+func newStringPool() *stringPool {
+	return &stringPool{strings: map[string]string{}, lists: map[string][]string{}}
+}
+
+func (p *stringPool) intern(s string) string {
+	if p == nil || s == "" {
+		return s
+	}
+	p.Lock()
+	defer p.Unlock()
+	if interned, ok := p.strings[s]; ok {
+		return interned
+	}
+	p.strings[s] = s
+	return s
+}
+
+// internList returns a shared copy of the list, which must not be modified
+func (p *stringPool) internList(list []string) []string {
+	if p == nil || len(list) == 0 {
+		return list
+	}
+	key := strings.Join(list, "\x00")
+	p.Lock()
+	defer p.Unlock()
+	if interned, ok := p.lists[key]; ok {
+		return interned
+	}
+	p.lists[key] = list
+	return list
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+// syntheticFile is a file of a synthetic library, which is never read
+type syntheticFile struct {
+	name string
+}
+
+func (f syntheticFile) Name() string       { return f.name }
+func (f syntheticFile) Size() int64        { return 0 }
+func (f syntheticFile) Mode() os.FileMode  { return 0644 }
+func (f syntheticFile) ModTime() time.Time { return time.Time{} }
+func (f syntheticFile) IsDir() bool        { return false }
+func (f syntheticFile) Sys() interface{}   { return nil }
+
+func syntheticFileInfos(count int, tags *stringPool) []ExtendedFileInfo {
+	result := make([]ExtendedFileInfo, 0, count)
+	for i := 0; i < count; i++ {
+		file := syntheticFile{name: fmt.Sprintf("Game %v (EU) [En,Fr,De,It,Es][%016X][v0].nsp", i, 0x0100000000000000+i<<16)}
+		result = append(result, newExtendedFileInfo(file, "/library", &switchfs.ContentMetaAttributes{}, tags))
+	}
+	return result
+}
+
+func TestScanTagsAreShared(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       *stringPool
+		wantShared bool
+	}{
+		{"pooled", newStringPool(), true},
+		{"not pooled", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := syntheticFileInfos(2, test.tags)
+			if files[0].Region != "EU" || len(files[0].Languages) != 5 {
+				t.Fatalf("region %v, languages %v", files[0].Region, files[0].Languages)
+			}
+			if shared := &files[0].Languages[0] == &files[1].Languages[0]; shared != test.wantShared {
+				t.Errorf("languages shared: %v, want %v", shared, test.wantShared)
+			}
+		})
+	}
+}
+
+// BenchmarkScanTags reports the memory held by the file infos of a large synthetic library, with and without
+// sharing the tags across the files
+func BenchmarkScanTags(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		tags func() *stringPool
+	}{
+		{"pooled", newStringPool},
+		{"not pooled", func() *stringPool { return nil }},
+	}
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				files := syntheticFileInfos(10000, benchmark.tags())
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if after.HeapAlloc > before.HeapAlloc {
+					retained += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(files)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
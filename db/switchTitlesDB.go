@@ -2,7 +2,12 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
 	"io"
+	"io/ioutil"
+	"os"
+	"sort"
 	"strings"
 )
 
@@ -18,44 +23,84 @@ type TitleAttributes struct {
 	BannerUrl   string      `json:"bannerUrl,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Size        int         `json:"size,omitempty"`
+	//demo / pre-release entries
+	IsDemo bool `json:"isDemo,omitempty"`
 }
 
 type SwitchTitle struct {
 	Attributes TitleAttributes
 	Updates    map[int]string
 	Dlc        map[string]TitleAttributes
+	//size of the latest update, when known
+	UpdateSize int `json:",omitempty"`
 }
 
 type SwitchTitlesDB struct {
 	TitlesMap map[string]*SwitchTitle
 }
 
+const (
+	//keep the entry of the first titleId, in sorted order (upper case ids sort first)
+	NAME_CONFLICT_FIRST = "first"
+	//keep the entry with the longest name
+	NAME_CONFLICT_LONGEST = "longest"
+	//keep the entry of the preferred region, falling back to the first entry
+	NAME_CONFLICT_REGION = "region"
+)
+
+// TitlesDBOptions control how entries sharing the same titleId (such as in merged regional DBs) are resolved
+type TitlesDBOptions struct {
+	NameConflictRule string
+	PreferredRegion  string
+	//how entries repeating a titleId in the versions file are resolved
+	VersionsDuplicateRule string
+}
+
 func CreateSwitchTitleDB(titlesFile, versionsFile io.Reader) (*SwitchTitlesDB, error) {
+	return CreateSwitchTitleDBWithOptions(titlesFile, versionsFile, TitlesDBOptions{NameConflictRule: NAME_CONFLICT_FIRST})
+}
+
+func CreateSwitchTitleDBWithOptions(titlesFile, versionsFile io.Reader, options TitlesDBOptions) (*SwitchTitlesDB, error) {
 	//parge the titles objects
-	var titles = map[string]TitleAttributes{}
-	err := decodeToJsonObject(titlesFile, &titles)
+	titles, err := decodeTitles(titlesFile)
 	if err != nil {
 		return nil, err
 	}
 
 	//parse the titles objects
 	//titleID -> versionId-> release date
-	var versions = map[string]map[int]string{}
-	err = decodeToJsonObject(versionsFile, &versions)
+	versions, err := decodeVersions(versionsFile, options.VersionsDuplicateRule)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = validateTitlesShape(titles); err != nil {
+		return nil, err
+	}
+	if err = validateVersionsShape(versions); err != nil {
+		return nil, err
+	}
+
+	//sorted, so that conflicting entries are always resolved the same way. entries repeating a titleId keep their file order
+	sort.SliceStable(titles, func(i, j int) bool {
+		return titles[i].id < titles[j].id
+	})
+
 	result := SwitchTitlesDB{TitlesMap: map[string]*SwitchTitle{}}
-	for id, attr := range titles {
-		id = strings.ToLower(id)
+	for _, entry := range titles {
+		attr := entry.attr
+		id := strings.ToLower(entry.id)
+		if len(id) != 16 {
+			zap.S().Warnf("ignoring title with invalid titleId [%v]", id)
+			continue
+		}
 
 		//TitleAttributes id rules:
 		//main TitleAttributes ends with 000
 		//Updates ends with 800
 		//Dlc have a running counter (starting with 001) in the 4 last chars
 		idPrefix := id[0 : len(id)-4]
-		switchTitle := &SwitchTitle{Dlc: map[string]TitleAttributes{}}
+		switchTitle := &SwitchTitle{Dlc: map[string]TitleAttributes{}, Updates: map[int]string{}}
 		if t, ok := result.TitlesMap[idPrefix]; ok {
 			switchTitle = t
 		}
@@ -63,21 +108,104 @@ func CreateSwitchTitleDB(titlesFile, versionsFile io.Reader) (*SwitchTitlesDB, e
 
 		//process Updates
 		if strings.HasSuffix(id, "800") {
-			updates := versions[id[0:len(id)-3]+"000"]
-			switchTitle.Updates = updates
+			//titles without version data keep an empty updates map
+			if updates, ok := versions[id[0:len(id)-3]+"000"]; ok && updates != nil {
+				switchTitle.Updates = updates
+			}
+			switchTitle.UpdateSize = attr.Size
 			continue
 		}
 
 		//process main TitleAttributes
 		if strings.HasSuffix(id, "000") {
+			if switchTitle.Attributes.Id != "" {
+				attr = resolveConflict(switchTitle.Attributes, attr, options)
+			}
 			switchTitle.Attributes = attr
+			//versions are keyed by the base titleId, even when titles.json has no update entry
+			if updates, ok := versions[id]; ok && updates != nil {
+				switchTitle.Updates = updates
+			}
 			continue
 		}
 
 		//not an update, and not main TitleAttributes, so treat it as a DLC
+		if existing, ok := switchTitle.Dlc[id]; ok {
+			attr = resolveConflict(existing, attr, options)
+		}
 		switchTitle.Dlc[id] = attr
 
 	}
 
+	//the versions of titleIds missing from titles.json can't be shown without a title, so they are ignored
+	for id := range versions {
+		if len(id) != 16 {
+			zap.S().Warnf("ignoring versions of invalid titleId [%v]", id)
+			continue
+		}
+		if _, ok := result.TitlesMap[strings.ToLower(id[0:len(id)-4])]; !ok {
+			zap.S().Infof("ignoring versions of titleId [%v], which is missing from the titles file", id)
+		}
+	}
+
 	return &result, nil
 }
+
+func resolveConflict(existing TitleAttributes, other TitleAttributes, options TitlesDBOptions) TitleAttributes {
+	result := existing
+	switch options.NameConflictRule {
+	case NAME_CONFLICT_LONGEST:
+		if len(other.Name) > len(existing.Name) {
+			result = other
+		}
+	case NAME_CONFLICT_REGION:
+		if !strings.EqualFold(existing.Region, options.PreferredRegion) && strings.EqualFold(other.Region, options.PreferredRegion) {
+			result = other
+		}
+	}
+	if existing.Name != other.Name {
+		zap.S().Infof("titleId [%v] has conflicting names [%v] and [%v], using [%v] (rule: %v)", existing.Id, existing.Name, other.Name, result.Name, options.NameConflictRule)
+	}
+	return result
+}
+
+type switchTitlesDBCache struct {
+	Version      int                     `json:"version"`
+	TitlesEtag   string                  `json:"titles_etag"`
+	VersionsEtag string                  `json:"versions_etag"`
+	TitlesMap    map[string]*SwitchTitle `json:"titles"`
+}
+
+// bumped whenever the persisted fields change, so that caches written by older versions are rebuilt
+const titlesDBCacheVersion = 3
+
+// SaveSwitchTitlesDB persists the constructed DB, keyed on the etags of the json files it was built from.
+// map keys are serialized in sorted order, so the output is stable.
+func SaveSwitchTitlesDB(filePath string, titlesDB *SwitchTitlesDB, titlesEtag string, versionsEtag string) error {
+	bytes, err := json.Marshal(switchTitlesDBCache{Version: titlesDBCacheVersion, TitlesEtag: titlesEtag, VersionsEtag: versionsEtag, TitlesMap: titlesDB.TitlesMap})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, bytes, 0644)
+}
+
+// LoadSwitchTitlesDB loads a DB persisted by SaveSwitchTitlesDB, failing when it was built from different json files
+func LoadSwitchTitlesDB(filePath string, titlesEtag string, versionsEtag string) (*SwitchTitlesDB, error) {
+	if titlesEtag == "" || versionsEtag == "" {
+		return nil, errors.New("no etag to validate the cache against")
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cache := switchTitlesDBCache{}
+	err = decodeToJsonObject(file, &cache)
+	if err != nil {
+		return nil, err
+	}
+	if cache.Version != titlesDBCacheVersion || cache.TitlesEtag != titlesEtag || cache.VersionsEtag != versionsEtag {
+		return nil, errors.New("cache is outdated")
+	}
+	return &SwitchTitlesDB{TitlesMap: cache.TitlesMap}, nil
+}
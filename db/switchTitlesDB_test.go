@@ -0,0 +1,193 @@
+package db
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestTitlesDBCache(t *testing.T) (*SwitchTitlesDB, string) {
+	t.Helper()
+	titles := `{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game", "region": "US", "releaseDate": 20200101, "screenshots": ["a.jpg", "b.jpg"]},
+		"0100000000011001": {"id": "0100000000011001", "name": "Game DLC"},
+		"0100000000020000": {"id": "0100000000020000", "name": "Other", "version": 0}
+	}`
+	versions := `{"0100000000010000": {"65536": "2020-02-01", "131072": "2020-03-01"}}`
+	titlesDB, err := CreateSwitchTitleDB(strings.NewReader(titles), strings.NewReader(versions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := ioutil.TempDir("", "slm-titles-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(folder) })
+	return titlesDB, filepath.Join(folder, "titles.db.cache")
+}
+
+func TestTitlesDBCacheRoundTrip(t *testing.T) {
+	titlesDB, filePath := newTestTitlesDBCache(t)
+	if err := SaveSwitchTitlesDB(filePath, titlesDB, "titles-etag", "versions-etag"); err != nil {
+		t.Fatal(err)
+	}
+	firstSave, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSwitchTitlesDB(filePath, "titles-etag", "versions-etag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, titlesDB) {
+		t.Errorf("got %+v, want %+v", loaded.TitlesMap, titlesDB.TitlesMap)
+	}
+
+	//saving the loaded DB again produces the very same file
+	if err := SaveSwitchTitlesDB(filePath, loaded, "titles-etag", "versions-etag"); err != nil {
+		t.Fatal(err)
+	}
+	secondSave, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(firstSave, secondSave) {
+		t.Errorf("got a different serialization after a round trip:\n%s\n%s", firstSave, secondSave)
+	}
+}
+
+func TestTitlesDBCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		titlesEtag   string
+		versionsEtag string
+		wantErr      bool
+	}{
+		{"same etags", "titles-etag", "versions-etag", false},
+		{"titles changed", "new-titles-etag", "versions-etag", true},
+		{"versions changed", "titles-etag", "new-versions-etag", true},
+		{"no titles etag", "", "versions-etag", true},
+		{"no versions etag", "titles-etag", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titlesDB, filePath := newTestTitlesDBCache(t)
+			if err := SaveSwitchTitlesDB(filePath, titlesDB, "titles-etag", "versions-etag"); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadSwitchTitlesDB(filePath, test.titlesEtag, test.versionsEtag)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr && loaded != nil {
+				t.Errorf("got %v, want no DB from an outdated cache", loaded.TitlesMap)
+			}
+		})
+	}
+
+	t.Run("older cache version", func(t *testing.T) {
+		_, filePath := newTestTitlesDBCache(t)
+		content := `{"version": 1, "titles_etag": "titles-etag", "versions_etag": "versions-etag", "titles": {}}`
+		if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSwitchTitlesDB(filePath, "titles-etag", "versions-etag"); err == nil {
+			t.Errorf("got no error, want a cache written by an older version to be rebuilt")
+		}
+	})
+}
+
+func TestInconsistentTitlesAndVersions(t *testing.T) {
+	base := `"0100000000010000": {"id": "0100000000010000", "name": "Game"}`
+	update := `"0100000000010800": {"id": "0100000000010800"}`
+	versions := `"0100000000010000": {"65536": "2020-01-01"}`
+	tests := []struct {
+		name     string
+		titles   string
+		versions string
+		//the updates of each title in the DB, titles missing from the DB are not listed
+		want map[string]map[int]string
+	}{
+		{"base and update entries with versions", `{` + base + `,` + update + `}`, `{` + versions + `}`,
+			map[string]map[int]string{"010000000001": {65536: "2020-01-01"}}},
+		{"title without versions", `{` + base + `}`, `{}`,
+			map[string]map[int]string{"010000000001": {}}},
+		{"update entry without versions", `{` + base + `,` + update + `}`, `{}`,
+			map[string]map[int]string{"010000000001": {}}},
+		{"versions of a base without an update entry", `{` + base + `}`, `{` + versions + `}`,
+			map[string]map[int]string{"010000000001": {65536: "2020-01-01"}}},
+		{"update entry without a base entry", `{` + update + `}`, `{` + versions + `}`,
+			map[string]map[int]string{"010000000001": {65536: "2020-01-01"}}},
+		{"versions without a title", `{"0100000000020000": {"id": "0100000000020000", "name": "Other"}}`, `{` + versions + `}`,
+			map[string]map[int]string{"010000000002": {}}},
+		{"invalid titleIds", `{` + base + `, "0100": {"id": "0100", "name": "Short"}}`, `{` + versions + `, "12": {"65536": "2020-01-01"}}`,
+			map[string]map[int]string{"010000000001": {65536: "2020-01-01"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titlesDB, err := CreateSwitchTitleDB(strings.NewReader(test.titles), strings.NewReader(test.versions))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]map[int]string{}
+			for idPrefix, switchTitle := range titlesDB.TitlesMap {
+				if switchTitle.Updates == nil {
+					t.Errorf("title %v has no updates map", idPrefix)
+				}
+				got[idPrefix] = switchTitle.Updates
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got updates %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNameConflictRules(t *testing.T) {
+	//the same titleId repeated in the file, and under a different case
+	titles := `{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game", "region": "JP"},
+		"0100000000010000": {"id": "0100000000010000", "name": "Game: Deluxe Edition", "region": "US"},
+		"0100000000010000": {"id": "0100000000010000", "name": "Game EU", "region": "GB"},
+		"0100000000020000": {"id": "0100000000020000", "name": "Other", "region": "US"},
+		"0100000000020000": {"id": "0100000000020000", "name": "Other", "region": "GB"},
+		"0100000000031001": {"id": "0100000000031001", "name": "First DLC", "region": "GB"},
+		"0100000000031001": {"id": "0100000000031001", "name": "First DLC (US)", "region": "US"},
+		"0100000000030000": {"id": "0100000000030000", "name": "Third", "region": "US"},
+		"01000000000a0000": {"id": "01000000000a0000", "name": "Lower case", "region": "GB"},
+		"01000000000A0000": {"id": "01000000000A0000", "name": "Upper", "region": "US"}
+	}`
+	tests := []struct {
+		name      string
+		options   TitlesDBOptions
+		wantGame  string
+		wantDlc   string
+		wantOther string
+	}{
+		{"first", TitlesDBOptions{NameConflictRule: NAME_CONFLICT_FIRST}, "Game", "First DLC", "Upper"},
+		{"longest", TitlesDBOptions{NameConflictRule: NAME_CONFLICT_LONGEST}, "Game: Deluxe Edition", "First DLC (US)", "Lower case"},
+		{"region", TitlesDBOptions{NameConflictRule: NAME_CONFLICT_REGION, PreferredRegion: "gb"}, "Game EU", "First DLC", "Lower case"},
+		{"region not found", TitlesDBOptions{NameConflictRule: NAME_CONFLICT_REGION, PreferredRegion: "KR"}, "Game", "First DLC", "Upper"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titlesDB, err := CreateSwitchTitleDBWithOptions(strings.NewReader(titles), strings.NewReader("{}"), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titlesDB.TitlesMap["010000000001"].Attributes.Name; got != test.wantGame {
+				t.Errorf("game name %q, want %q", got, test.wantGame)
+			}
+			if got := titlesDB.TitlesMap["010000000003"].Dlc["0100000000031001"].Name; got != test.wantDlc {
+				t.Errorf("dlc name %q, want %q", got, test.wantDlc)
+			}
+			if got := titlesDB.TitlesMap["01000000000a"].Attributes.Name; got != test.wantOther {
+				t.Errorf("name %q, want %q", got, test.wantOther)
+			}
+		})
+	}
+}
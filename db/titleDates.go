@@ -0,0 +1,40 @@
+package db
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// RecordTitleDates merges the newest file modification time of each local title (by titleId) with the dates
+// persisted in filePath, keeping the newest of both, and writes the result back.
+// a title whose files were replaced by older copies keeps the date it was last updated on.
+func RecordTitleDates(filePath string, localDB *LocalSwitchFilesDB) error {
+	dates := map[string]time.Time{}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(data, &dates); err != nil {
+			return err
+		}
+	}
+	localDB.mutex.Lock()
+	for idPrefix, switchFile := range localDB.TitlesMap {
+		if recorded, ok := dates[idPrefix]; ok && recorded.After(switchFile.LastModified) {
+			switchFile.LastModified = recorded
+			continue
+		}
+		if !switchFile.LastModified.IsZero() {
+			dates[idPrefix] = switchFile.LastModified
+		}
+	}
+	localDB.mutex.Unlock()
+	data, err = json.Marshal(dates)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLastModifiedIsTheNewestFile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		files map[string]time.Time
+		want  time.Time
+	}{
+		{"base only", map[string]time.Time{"Game [0100000000010000][v0].nsp": day(3)}, day(3)},
+		{"newer update", map[string]time.Time{
+			"Game [0100000000010000][v0].nsp":     day(3),
+			"Game [0100000000010800][v65536].nsp": day(9),
+		}, day(9)},
+		{"newer DLC", map[string]time.Time{
+			"Game [0100000000010000][v0].nsp":     day(3),
+			"Game [0100000000010800][v65536].nsp": day(5),
+			"Game DLC [0100000000011001][v0].nsp": day(7),
+		}, day(7)},
+		{"older update", map[string]time.Time{
+			"Game [0100000000010000][v0].nsp":     day(8),
+			"Game [0100000000010800][v65536].nsp": day(2),
+		}, day(8)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB := scanTestFolder(t, createTestFiles(t, test.files), ScanOptions{})
+			switchFile, ok := localDB.TitlesMap["010000000001"]
+			if !ok {
+				t.Fatal("title was not registered")
+			}
+			if !switchFile.LastModified.Equal(test.want) {
+				t.Errorf("LastModified = %v, want %v", switchFile.LastModified, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordTitleDates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		recorded string
+		scanned  time.Time
+		want     time.Time
+	}{
+		{"no recorded dates", "", day(5), day(5)},
+		{"older recorded date", `{"010000000001":"2020-01-02T00:00:00Z"}`, day(5), day(5)},
+		{"newer recorded date", `{"010000000001":"2020-01-09T00:00:00Z"}`, day(5), day(9)},
+		{"other title recorded", `{"010000000002":"2020-01-09T00:00:00Z"}`, day(5), day(5)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := createTestFiles(t, map[string]time.Time{"Game [0100000000010000][v0].nsp": test.scanned})
+			datesFile := filepath.Join(folder, "title_dates.json")
+			if test.recorded != "" {
+				if err := ioutil.WriteFile(datesFile, []byte(test.recorded), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files, _ := ioutil.ReadDir(folder)
+			localDB, _ := CreateLocalSwitchFilesDB(files, folder, nil, ScanOptions{DisableDeepScan: true})
+			if err := RecordTitleDates(datesFile, localDB); err != nil {
+				t.Fatal(err)
+			}
+			if got := localDB.TitlesMap["010000000001"].LastModified; !got.Equal(test.want) {
+				t.Errorf("LastModified = %v, want %v", got, test.want)
+			}
+
+			//the next run reads the persisted date back
+			os.Remove(filepath.Join(folder, "Game [0100000000010000][v0].nsp"))
+			next := &LocalSwitchFilesDB{TitlesMap: map[string]*SwitchFile{"010000000001": {}}}
+			if err := RecordTitleDates(datesFile, next); err != nil {
+				t.Fatal(err)
+			}
+			if got := next.TitlesMap["010000000001"].LastModified; !got.Equal(test.want) {
+				t.Errorf("persisted LastModified = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
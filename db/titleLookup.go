@@ -0,0 +1,63 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+// GetTitleById returns the title of a base, update or DLC titleId
+func (s *SwitchTitlesDB) GetTitleById(titleId string) (*SwitchTitle, bool) {
+	titleId = strings.ToLower(strings.TrimSpace(titleId))
+	if len(titleId) != 16 {
+		return nil, false
+	}
+	switchTitle, ok := s.TitlesMap[titleId[0:len(titleId)-4]]
+	return switchTitle, ok
+}
+
+// GetTitlesByIds resolves a batch of base, update or DLC titleIds, keyed by the given ids.
+// unknown ids are left out of the result.
+func (s *SwitchTitlesDB) GetTitlesByIds(titleIds []string) map[string]*SwitchTitle {
+	result := make(map[string]*SwitchTitle, len(titleIds))
+	for _, titleId := range titleIds {
+		if _, ok := result[titleId]; ok {
+			continue
+		}
+		if switchTitle, ok := s.GetTitleById(titleId); ok {
+			result[titleId] = switchTitle
+		}
+	}
+	return result
+}
+
+// FindTitlesById returns the titles matching a full or partial titleId - the exact match when there is one,
+// otherwise the titles whose base titleId starts with it, or else contains it. the candidates are ordered by titleId.
+func (s *SwitchTitlesDB) FindTitlesById(partialId string) []*SwitchTitle {
+	partialId = strings.ToLower(strings.TrimSpace(partialId))
+	if partialId == "" {
+		return nil
+	}
+	if switchTitle, ok := s.GetTitleById(partialId); ok {
+		return []*SwitchTitle{switchTitle}
+	}
+	var prefixMatches, partialMatches []*SwitchTitle
+	for _, switchTitle := range s.TitlesMap {
+		id := strings.ToLower(switchTitle.Attributes.Id)
+		if id == "" {
+			continue
+		}
+		if strings.HasPrefix(id, partialId) {
+			prefixMatches = append(prefixMatches, switchTitle)
+		} else if strings.Contains(id, partialId) {
+			partialMatches = append(partialMatches, switchTitle)
+		}
+	}
+	result := prefixMatches
+	if len(result) == 0 {
+		result = partialMatches
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Attributes.Id < result[j].Attributes.Id
+	})
+	return result
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testTitlesDB() *SwitchTitlesDB {
+	return &SwitchTitlesDB{TitlesMap: map[string]*SwitchTitle{
+		"010000000001": {Attributes: TitleAttributes{Id: "0100000000010000", Name: "First"}},
+		"010000000002": {Attributes: TitleAttributes{Id: "0100000000020000", Name: "Second"}},
+		"0100abcd0003": {Attributes: TitleAttributes{Id: "0100ABCD00030000", Name: "Third"}},
+		//a DLC without the base entry of its game
+		"010000000004": {Dlc: map[string]TitleAttributes{"0100000000041001": {Id: "0100000000041001"}}},
+	}}
+}
+
+func TestFindTitlesById(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"exact base", "0100000000020000", []string{"0100000000020000"}},
+		{"exact update", " 0100000000020800 ", []string{"0100000000020000"}},
+		{"prefix matching several titles", "01000000000", []string{"0100000000010000", "0100000000020000"}},
+		{"case insensitive prefix", "0100abcd", []string{"0100ABCD00030000"}},
+		{"partial", "abcd0003", []string{"0100ABCD00030000"}},
+		{"no match", "ffff", nil},
+		{"empty", " ", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, switchTitle := range testTitlesDB().FindTitlesById(test.query) {
+				got = append(got, switchTitle.Attributes.Id)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetTitlesByIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		titleIds []string
+		want     map[string]string
+	}{
+		{"empty batch", nil, map[string]string{}},
+		{"base, update and DLC ids", []string{"0100000000010000", "0100000000020800", "0100000000041001"},
+			map[string]string{"0100000000010000": "First", "0100000000020800": "Second", "0100000000041001": ""}},
+		{"unknown ids are left out", []string{"0100000000010000", "0100000000090000", "not a titleId", ""},
+			map[string]string{"0100000000010000": "First"}},
+		{"ids are matched case insensitively and keyed as given", []string{"0100ABCD00030000", "0100abcd00030800"},
+			map[string]string{"0100ABCD00030000": "Third", "0100abcd00030800": "Third"}},
+		{"repeated ids", []string{"0100000000010000", "0100000000010000"},
+			map[string]string{"0100000000010000": "First"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := map[string]string{}
+			for titleId, switchTitle := range testTitlesDB().GetTitlesByIds(test.titleIds) {
+				got[titleId] = switchTitle.Attributes.Name
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
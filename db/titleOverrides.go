@@ -0,0 +1,105 @@
+package db
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+)
+
+// TitleOverride replaces the titles DB values of a title, empty fields are left unchanged
+type TitleOverride struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+	//base titles only - the DLC titleIds expected for the title, the other DLC of the titles DB are dropped
+	ExpectedDlc []string `json:"expected_dlc"`
+	//DLC only - the DLC is dropped from the titles DB (such as delisted DLC which can't be obtained)
+	Delisted bool `json:"delisted"`
+}
+
+// LoadTitleOverrides reads a json object of titleId -> override
+func LoadTitleOverrides(filePath string) (map[string]TitleOverride, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	overrides := map[string]TitleOverride{}
+	err = json.NewDecoder(file).Decode(&overrides)
+	if err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// ApplyTitleOverrides merges the overrides into the titles DB. base titles missing from the DB (such as homebrew)
+// are added, overrides of unknown DLC or update ids are ignored.
+func ApplyTitleOverrides(titlesDB *SwitchTitlesDB, overrides map[string]TitleOverride) {
+	for id, override := range overrides {
+		id = strings.ToLower(id)
+		if len(id) != 16 {
+			zap.S().Warnf("Ignoring title override with invalid titleId [%v]", id)
+			continue
+		}
+		idPrefix := id[0 : len(id)-4]
+		switchTitle, ok := titlesDB.TitlesMap[idPrefix]
+
+		if strings.HasSuffix(id, "000") {
+			if !ok {
+				switchTitle = &SwitchTitle{Dlc: map[string]TitleAttributes{}}
+				titlesDB.TitlesMap[idPrefix] = switchTitle
+			}
+			if switchTitle.Attributes.Id == "" {
+				switchTitle.Attributes.Id = strings.ToUpper(id)
+			}
+			switchTitle.Attributes = override.apply(switchTitle.Attributes)
+			if override.ExpectedDlc != nil {
+				restrictDlc(switchTitle, override.ExpectedDlc)
+			}
+			continue
+		}
+
+		if !ok {
+			zap.S().Warnf("Ignoring title override of unknown titleId [%v]", id)
+			continue
+		}
+		if dlc, ok := switchTitle.Dlc[id]; ok {
+			if override.Delisted {
+				delete(switchTitle.Dlc, id)
+				continue
+			}
+			switchTitle.Dlc[id] = override.apply(dlc)
+			continue
+		}
+		zap.S().Warnf("Ignoring title override of unknown titleId [%v]", id)
+	}
+}
+
+// keep only the expected DLC of a title. expected ids unknown to the titles DB are not added, since there is
+// nothing to report about them.
+func restrictDlc(switchTitle *SwitchTitle, expectedDlc []string) {
+	expected := map[string]bool{}
+	for _, id := range expectedDlc {
+		expected[strings.ToLower(id)] = true
+	}
+	for id := range switchTitle.Dlc {
+		if !expected[id] {
+			delete(switchTitle.Dlc, id)
+		}
+	}
+	for id := range expected {
+		if _, ok := switchTitle.Dlc[id]; !ok {
+			zap.S().Warnf("Ignoring expected DLC of unknown titleId [%v]", id)
+		}
+	}
+}
+
+func (o TitleOverride) apply(attributes TitleAttributes) TitleAttributes {
+	if o.Name != "" {
+		attributes.Name = o.Name
+	}
+	if o.Region != "" {
+		attributes.Region = o.Region
+	}
+	return attributes
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTitleOverrides(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-overrides")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	overridesJson := `{
+		"0100ABCD00010000": {"name": "Overridden Game", "region": "JP"},
+		"0100abcd00011001": {"name": "Overridden DLC"},
+		"0100ffff00010000": {"name": "Homebrew"},
+		"0100eeee00011001": {"name": "Unknown DLC"},
+		"0100abcd00011009": {"name": "Unknown DLC of a known title"},
+		"0100abcd": {"name": "Invalid"}
+	}`
+	overridesPath := filepath.Join(folder, "title_overrides.json")
+	if err := ioutil.WriteFile(overridesPath, []byte(overridesJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := LoadTitleOverrides(overridesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titlesDB := &SwitchTitlesDB{TitlesMap: map[string]*SwitchTitle{
+		"0100abcd0001": {Attributes: TitleAttributes{Id: "0100ABCD00010000", Name: "Game", Region: "US"},
+			Dlc: map[string]TitleAttributes{
+				"0100abcd00011001": {Id: "0100ABCD00011001", Name: "DLC 1", Region: "US"},
+				"0100abcd00011002": {Id: "0100ABCD00011002", Name: "DLC 2", Region: "US"},
+			}},
+	}}
+	ApplyTitleOverrides(titlesDB, overrides)
+
+	want := map[string]SwitchTitle{
+		"0100abcd0001": {Attributes: TitleAttributes{Id: "0100ABCD00010000", Name: "Overridden Game", Region: "JP"},
+			Dlc: map[string]TitleAttributes{
+				//empty fields of the override keep the titles DB value
+				"0100abcd00011001": {Id: "0100ABCD00011001", Name: "Overridden DLC", Region: "US"},
+				"0100abcd00011002": {Id: "0100ABCD00011002", Name: "DLC 2", Region: "US"},
+			}},
+		//base titles missing from the titles DB are added
+		"0100ffff0001": {Attributes: TitleAttributes{Id: "0100FFFF00010000", Name: "Homebrew"}, Dlc: map[string]TitleAttributes{}},
+	}
+	got := map[string]SwitchTitle{}
+	for id, title := range titlesDB.TitlesMap {
+		got[id] = *title
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got titles %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadTitleOverridesRejectsInvalidJson(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-overrides")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	overridesPath := filepath.Join(folder, "title_overrides.json")
+	if err := ioutil.WriteFile(overridesPath, []byte(`{"0100abcd00010000": "name"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTitleOverrides(overridesPath); err == nil {
+		t.Error("got no error for invalid overrides, want an error")
+	}
+	if _, err := LoadTitleOverrides(filepath.Join(folder, "missing.json")); err == nil {
+		t.Error("got no error for a missing overrides file, want an error")
+	}
+}
+
+// overridesTitlesDB is a game with three DLC in the titles DB, one of them a phantom entry that can't be obtained
+func overridesTitlesDB() *SwitchTitlesDB {
+	return &SwitchTitlesDB{TitlesMap: map[string]*SwitchTitle{
+		"0100abcd0001": {Attributes: TitleAttributes{Id: "0100ABCD00010000", Name: "Game"},
+			Dlc: map[string]TitleAttributes{
+				"0100abcd00011001": {Id: "0100ABCD00011001", Name: "DLC 1"},
+				"0100abcd00011002": {Id: "0100ABCD00011002", Name: "DLC 2"},
+				"0100abcd00011003": {Id: "0100ABCD00011003", Name: "Phantom DLC"},
+			}},
+	}}
+}
+
+func TestDlcOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]TitleOverride
+		wantDlc   []string
+	}{
+		{"no override", nil, []string{"0100abcd00011001", "0100abcd00011002", "0100abcd00011003"}},
+		{"expected DLC", map[string]TitleOverride{
+			"0100ABCD00010000": {ExpectedDlc: []string{"0100ABCD00011001", "0100abcd00011002"}},
+		}, []string{"0100abcd00011001", "0100abcd00011002"}},
+		{"expected DLC unknown to the titles DB are not added", map[string]TitleOverride{
+			"0100abcd00010000": {ExpectedDlc: []string{"0100abcd00011001", "0100abcd00011009"}},
+		}, []string{"0100abcd00011001"}},
+		{"no expected DLC", map[string]TitleOverride{
+			"0100abcd00010000": {ExpectedDlc: []string{}},
+		}, nil},
+		{"delisted DLC", map[string]TitleOverride{
+			"0100ABCD00011003": {Delisted: true},
+		}, []string{"0100abcd00011001", "0100abcd00011002"}},
+		{"renaming the base title keeps its DLC", map[string]TitleOverride{
+			"0100abcd00010000": {Name: "Renamed"},
+		}, []string{"0100abcd00011001", "0100abcd00011002", "0100abcd00011003"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titlesDB := overridesTitlesDB()
+			ApplyTitleOverrides(titlesDB, test.overrides)
+			var got []string
+			for id := range titlesDB.TitlesMap["0100abcd0001"].Dlc {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.wantDlc) {
+				t.Errorf("got DLC %v, want %v", got, test.wantDlc)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// titleEntry is a titles json file entry, keyed by its titleId
+type titleEntry struct {
+	id   string
+	attr TitleAttributes
+}
+
+// decodeTitles parses the titles json file (titleID -> title attributes) in file order, keeping the entries which
+// repeat a titleId (such as in merged regional DBs), so that they can be resolved by the name conflict rule.
+func decodeTitles(reader io.Reader) ([]titleEntry, error) {
+	decoder := json.NewDecoder(reader)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected a json object, got [%v]", token)
+	}
+	var result []titleEntry
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		id, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a titleId, got [%v]", token)
+		}
+		var attr TitleAttributes
+		if err = decoder.Decode(&attr); err != nil {
+			return nil, err
+		}
+		result = append(result, titleEntry{id: id, attr: attr})
+	}
+	if _, err = decoder.Token(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
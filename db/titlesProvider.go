@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TitlesProvider constructs the switch titles DB from a metadata source
+type TitlesProvider interface {
+	LoadTitlesDB() (*SwitchTitlesDB, error)
+}
+
+// JsonTitlesProvider is the default provider, downloading the titles/versions json files
+// (when a newer version is available) into the cache folder
+type JsonTitlesProvider struct {
+	baseFolder  string
+	progress    ProgressUpdater
+	titlesURL   string
+	versionsURL string
+	client      *http.Client
+}
+
+func NewJsonTitlesProvider(baseFolder string, progress ProgressUpdater) *JsonTitlesProvider {
+	return &JsonTitlesProvider{baseFolder: baseFolder, progress: progress,
+		titlesURL: settings.TITLES_JSON_URL, versionsURL: settings.VERSIONS_JSON_URL, client: http.DefaultClient}
+}
+
+// SetSource overrides where the titles/versions json files are downloaded from (such as a mirror), and the client
+// downloading them
+func (p *JsonTitlesProvider) SetSource(titlesURL string, versionsURL string, client *http.Client) {
+	p.titlesURL, p.versionsURL, p.client = titlesURL, versionsURL, client
+}
+
+func (p *JsonTitlesProvider) LoadTitlesDB() (*SwitchTitlesDB, error) {
+	settingsObj := settings.ReadSettings(p.baseFolder)
+	cacheFolder, err := settings.CacheFolder(p.baseFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache folder - %v", err)
+	}
+
+	titlesFilePath := filepath.Join(cacheFolder, settings.TITLE_JSON_FILENAME)
+	versionsFilePath := filepath.Join(cacheFolder, settings.VERSIONS_JSON_FILENAME)
+	titlesValidators := FileValidators{Etag: settingsObj.TitlesEtag, ContentLength: settingsObj.TitlesContentLength, LastModified: settingsObj.TitlesLastModified}
+	versionsValidators := FileValidators{Etag: settingsObj.VersionsEtag, ContentLength: settingsObj.VersionsContentLength, LastModified: settingsObj.VersionsLastModified}
+	var titleFile, versionsFile *os.File
+	var titlesErr, versionsErr error
+	if settingsObj.Offline {
+		p.updateProgress(1, 3, "Loading titles.json / versions.json")
+		titleFile, titlesErr = openCachedFile(p.titlesURL, titlesFilePath)
+		versionsFile, versionsErr = openCachedFile(p.versionsURL, versionsFilePath)
+	} else {
+		//the downloads are independent so run them concurrently
+		p.updateProgress(1, 3, "Downloading titles.json / versions.json")
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			titleFile, titlesValidators, titlesErr = loadAndUpdateFile(p.client, p.titlesURL, titlesFilePath, titlesValidators, settingsObj.CheckContentChanges)
+		}()
+		go func() {
+			defer wg.Done()
+			versionsFile, versionsValidators, versionsErr = loadAndUpdateFile(p.client, p.versionsURL, versionsFilePath, versionsValidators, settingsObj.CheckContentChanges)
+		}()
+		wg.Wait()
+	}
+
+	if titleFile != nil {
+		defer titleFile.Close()
+	}
+	if versionsFile != nil {
+		defer versionsFile.Close()
+	}
+	if titlesErr != nil {
+		return nil, fmt.Errorf("title json file doesn't exist - %v", titlesErr)
+	}
+	if versionsErr != nil {
+		return nil, fmt.Errorf("version json file doesn't exist - %v", versionsErr)
+	}
+
+	//update the config file with new etag
+	settingsObj.TitlesEtag = titlesValidators.Etag
+	settingsObj.TitlesContentLength = titlesValidators.ContentLength
+	settingsObj.TitlesLastModified = titlesValidators.LastModified
+	settingsObj.VersionsEtag = versionsValidators.Etag
+	settingsObj.VersionsContentLength = versionsValidators.ContentLength
+	settingsObj.VersionsLastModified = versionsValidators.LastModified
+	settings.SaveSettings(settingsObj, p.baseFolder)
+	titlesEtag, versionsEtag := titlesValidators.CacheKey(), versionsValidators.CacheKey()
+	options := TitlesDBOptions{NameConflictRule: settingsObj.NameConflictRule, PreferredRegion: settingsObj.PreferredRegion,
+		VersionsDuplicateRule: settingsObj.VersionsDuplicateRule}
+	if options.NameConflictRule != "" && options.NameConflictRule != NAME_CONFLICT_FIRST {
+		//the cached DB depends on the conflict resolution as well
+		titlesEtag += "|" + options.NameConflictRule + "|" + options.PreferredRegion
+	}
+	if options.VersionsDuplicateRule != "" && options.VersionsDuplicateRule != VERSIONS_DUPLICATE_MERGE {
+		versionsEtag += "|" + options.VersionsDuplicateRule
+	}
+
+	p.updateProgress(2, 3, "Building titles DB ...")
+	//reuse the previously built DB, as long as the json files did not change
+	cacheFilePath := filepath.Join(cacheFolder, settings.TITLES_DB_CACHE_FILENAME)
+	switchTitleDB, err := LoadSwitchTitlesDB(cacheFilePath, titlesEtag, versionsEtag)
+	if err != nil {
+		zap.S().Infof("titles DB cache is not used, reason - [%v]", err)
+		switchTitleDB, err = CreateSwitchTitleDBWithOptions(titleFile, versionsFile, options)
+		if err == nil {
+			if cacheErr := SaveSwitchTitlesDB(cacheFilePath, switchTitleDB, titlesEtag, versionsEtag); cacheErr != nil {
+				zap.S().Warnf("failed to save titles DB cache - %v", cacheErr)
+			}
+		}
+	}
+	if err == nil {
+		//overrides are applied after the cache, so that editing them does not require rebuilding the DB
+		p.applyTitleOverrides(switchTitleDB)
+	}
+	p.updateProgress(3, 3, "Done")
+	return switchTitleDB, err
+}
+
+// TitlesDBRefreshTime returns when the titles DB was last changed - the Last-Modified time reported by the server,
+// or the time the titles json file was downloaded when the server does not report it
+func TitlesDBRefreshTime(baseFolder string, settingsObj *settings.AppSettings) (time.Time, error) {
+	if lastModified, err := http.ParseTime(settingsObj.TitlesLastModified); err == nil {
+		return lastModified, nil
+	}
+	cacheFolder, err := settings.CacheFolder(baseFolder)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(filepath.Join(cacheFolder, settings.TITLE_JSON_FILENAME))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func (p *JsonTitlesProvider) applyTitleOverrides(switchTitleDB *SwitchTitlesDB) {
+	overridesFilePath := filepath.Join(p.baseFolder, settings.TITLE_OVERRIDES_FILENAME)
+	if _, err := os.Stat(overridesFilePath); err != nil {
+		return
+	}
+	overrides, err := LoadTitleOverrides(overridesFilePath)
+	if err != nil {
+		zap.S().Errorf("failed to read title overrides file %v - %v", overridesFilePath, err)
+		return
+	}
+	ApplyTitleOverrides(switchTitleDB, overrides)
+}
+
+func (p *JsonTitlesProvider) updateProgress(curr int, total int, message string) {
+	if p.progress != nil {
+		p.progress.UpdateProgress(curr, total, message)
+	}
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+const (
+	testTitlesJson   = `{"0100000000010000": {"id": "0100000000010000", "name": "Game"}}`
+	testVersionsJson = `{"0100000000010000": {"65536": "2020-01-01"}}`
+)
+
+// useTestCacheFolder points the settings at a temp folder holding the given settings json, which is also the
+// cache folder of the titles/versions json files
+func useTestCacheFolder(t *testing.T, settingsJson string) string {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "slm-titles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(folder)
+		settings.SetSettingsFilePath("")
+	})
+	if err := ioutil.WriteFile(filepath.Join(folder, settings.SETTINGS_FILENAME), []byte(settingsJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings.SetSettingsFilePath(filepath.Join(folder, settings.SETTINGS_FILENAME))
+	return folder
+}
+
+// failingTransport fails (and counts) every request
+type failingTransport struct {
+	requests int32
+}
+
+func (f *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&f.requests, 1)
+	return nil, os.ErrPermission
+}
+
+func TestOfflineTitlesDB(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     string
+		cached       bool
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"offline with cached files", `{"offline": true}`, true, 0, false},
+		{"offline without cached files", `{"offline": true}`, false, 0, true},
+		{"online falls back to the cached files", `{"offline": false}`, true, 2, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestCacheFolder(t, test.settings)
+			if test.cached {
+				for name, content := range map[string]string{settings.TITLE_JSON_FILENAME: testTitlesJson, settings.VERSIONS_JSON_FILENAME: testVersionsJson} {
+					if err := ioutil.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			transport := &failingTransport{}
+			defaultTransport := http.DefaultTransport
+			http.DefaultTransport = transport
+			defer func() { http.DefaultTransport = defaultTransport }()
+
+			titlesDB, err := NewJsonTitlesProvider(folder, nil).LoadTitlesDB()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if transport.requests != test.wantRequests {
+				t.Errorf("got %v requests, want %v", transport.requests, test.wantRequests)
+			}
+			if !test.wantErr && titlesDB.TitlesMap["010000000001"].Attributes.Name != "Game" {
+				t.Errorf("got titles %v, want the cached title", titlesDB.TitlesMap)
+			}
+		})
+	}
+}
+
+func TestTitlesAndVersionsAreDownloadedConcurrently(t *testing.T) {
+	folder := useTestCacheFolder(t, `{}`)
+	//every request waits for the other one, so they only both succeed when they are in flight at the same time
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	bothArrived := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(bothArrived)
+	}()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/titles.json" || r.URL.Path == "/versions.json" {
+			arrived.Done()
+		}
+		select {
+		case <-bothArrived:
+		case <-time.After(5 * time.Second):
+			http.Error(w, "the other download never started", http.StatusGatewayTimeout)
+			return
+		}
+		if r.URL.Path == "/titles.json" {
+			w.Write([]byte(testTitlesJson))
+		} else {
+			w.Write([]byte(testVersionsJson))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewJsonTitlesProvider(folder, nil)
+	provider.SetSource(server.URL+"/titles.json", server.URL+"/versions.json", server.Client())
+	titlesDB, err := provider.LoadTitlesDB()
+	if err != nil {
+		t.Fatalf("got error %v, want both downloads to succeed", err)
+	}
+	if titlesDB.TitlesMap["010000000001"].Attributes.Name != "Game" {
+		t.Errorf("got titles %v, want the downloaded title", titlesDB.TitlesMap)
+	}
+}
+
+func TestTitlesDBRefreshTime(t *testing.T) {
+	downloaded := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		lastModified string
+		cached       bool
+		want         time.Time
+		wantErr      bool
+	}{
+		{"last modified by the server", "Sun, 01 Mar 2020 08:00:00 GMT", true, time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), false},
+		{"download time without a last modified", "", true, downloaded, false},
+		{"invalid last modified", "yesterday", true, downloaded, false},
+		{"never downloaded", "", false, time.Time{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-titles")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			t.Setenv(settings.CACHE_FOLDER_ENV, "")
+			if err := ioutil.WriteFile(filepath.Join(folder, settings.SETTINGS_FILENAME), []byte("{}"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			settings.SetSettingsFilePath(filepath.Join(folder, settings.SETTINGS_FILENAME))
+			defer settings.SetSettingsFilePath("")
+			if test.cached {
+				titlesPath := filepath.Join(folder, settings.TITLE_JSON_FILENAME)
+				if err := ioutil.WriteFile(titlesPath, []byte(testTitlesJson), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(titlesPath, downloaded, downloaded); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := TitlesDBRefreshTime(folder, &settings.AppSettings{TitlesLastModified: test.lastModified})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the share of entries allowed to miss a required field, before the file format is considered changed
+const maxInvalidEntriesRatio = 0.5
+
+// validateTitlesShape checks that the decoded titles.json entries hold the fields the DB relies on, so that an
+// upstream format change is reported instead of silently producing a DB with no names or ids
+func validateTitlesShape(titles []titleEntry) error {
+	if len(titles) == 0 {
+		return fmt.Errorf("titles json file format looks wrong - no titles found, the app may need to be updated")
+	}
+	missingId, bases, missingName := 0, 0, 0
+	for _, entry := range titles {
+		if entry.attr.Id == "" {
+			missingId++
+		}
+		if strings.HasSuffix(strings.ToLower(entry.id), "000") {
+			bases++
+			if entry.attr.Name == "" {
+				missingName++
+			}
+		}
+	}
+	if float64(missingId) > float64(len(titles))*maxInvalidEntriesRatio {
+		return fmt.Errorf("titles json file format looks wrong - %v of %v entries have no id, the app may need to be updated", missingId, len(titles))
+	}
+	if float64(missingName) > float64(bases)*maxInvalidEntriesRatio {
+		return fmt.Errorf("titles json file format looks wrong - %v of %v games have no name, the app may need to be updated", missingName, bases)
+	}
+	return nil
+}
+
+// validateVersionsShape checks that the versions.json release dates are in the expected yyyy-mm-dd format
+func validateVersionsShape(versions map[string]map[int]string) error {
+	total, invalid := 0, 0
+	for _, titleVersions := range versions {
+		for _, date := range titleVersions {
+			total++
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				invalid++
+			}
+		}
+	}
+	if float64(invalid) > float64(total)*maxInvalidEntriesRatio {
+		return fmt.Errorf("versions json file format looks wrong - %v of %v release dates are not yyyy-mm-dd dates, the app may need to be updated", invalid, total)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitlesJsonShapeValidation(t *testing.T) {
+	validTitles := `{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game"},
+		"0100000000020000": {"id": "0100000000020000", "name": "Other"},
+		"0100000000011001": {"id": "0100000000011001", "name": "Game DLC"}
+	}`
+	validVersions := `{"0100000000010000": {"65536": "2020-01-01", "131072": "2020-02-01"}}`
+	tests := []struct {
+		name     string
+		titles   string
+		versions string
+		wantErr  string
+	}{
+		{"valid", validTitles, validVersions, ""},
+		{"no titles", `{}`, validVersions, "no titles found"},
+		{"most ids missing", `{
+			"0100000000010000": {"titleId": "0100000000010000", "name": "Game"},
+			"0100000000020000": {"titleId": "0100000000020000", "name": "Other"},
+			"0100000000030000": {"id": "0100000000030000", "name": "Third"}
+		}`, validVersions, "2 of 3 entries have no id"},
+		{"few ids missing", `{
+			"0100000000010000": {"titleId": "0100000000010000", "name": "Game"},
+			"0100000000020000": {"id": "0100000000020000", "name": "Other"},
+			"0100000000030000": {"id": "0100000000030000", "name": "Third"}
+		}`, validVersions, ""},
+		{"most names missing", `{
+			"0100000000010000": {"id": "0100000000010000", "title": "Game"},
+			"0100000000020000": {"id": "0100000000020000", "title": "Other"},
+			"0100000000011001": {"id": "0100000000011001", "name": "Game DLC"}
+		}`, validVersions, "2 of 2 games have no name"},
+		{"dates not in yyyy-mm-dd", validTitles, `{"0100000000010000": {"65536": "01/01/2020", "131072": "2020-02-01T00:00:00Z"}}`, "2 of 2 release dates"},
+		{"few dates not in yyyy-mm-dd", validTitles, `{"0100000000010000": {"65536": "01/01/2020", "131072": "2020-02-01", "196608": "2020-03-01"}}`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CreateSwitchTitleDB(strings.NewReader(test.titles), strings.NewReader(test.versions))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("got error %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	bytes2 "bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"io"
 	"io/ioutil"
@@ -15,14 +16,41 @@ type ProgressUpdater interface {
 	UpdateProgress(curr int, total int, message string)
 }
 
+// FileValidators identify the downloaded version of a file. The content length and last modified date are only
+// used (as a secondary check, in case the server changes the content without changing the etag) when set.
+type FileValidators struct {
+	Etag          string
+	ContentLength int64
+	LastModified  string
+}
+
+// CacheKey identifies the downloaded content, for caches derived from it
+func (v FileValidators) CacheKey() string {
+	if v.ContentLength == 0 && v.LastModified == "" {
+		return v.Etag
+	}
+	return fmt.Sprintf("%v|%v|%v", v.Etag, v.ContentLength, v.LastModified)
+}
+
 func LoadAndUpdateFile(url string, filePath string, etag string) (*os.File, string, error) {
+	file, validators, err := LoadAndUpdateFileWithValidators(url, filePath, FileValidators{Etag: etag}, false)
+	return file, validators.Etag, err
+}
+
+// LoadAndUpdateFileWithValidators downloads the file when its etag changed, or when checkContent is set and its
+// content length / last modified date differ from the given validators
+func LoadAndUpdateFileWithValidators(url string, filePath string, validators FileValidators, checkContent bool) (*os.File, FileValidators, error) {
+	return loadAndUpdateFile(http.DefaultClient, url, filePath, validators, checkContent)
+}
+
+func loadAndUpdateFile(client *http.Client, url string, filePath string, validators FileValidators, checkContent bool) (*os.File, FileValidators, error) {
 
 	//create file if not exist
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		_, err = os.Create(filePath)
 		if err != nil {
 			zap.S().Errorf("Failed to create file %v - %v\n", filePath, err)
-			return nil, "", err
+			return nil, validators, err
 		}
 	}
 
@@ -30,14 +58,19 @@ func LoadAndUpdateFile(url string, filePath string, etag string) (*os.File, stri
 
 	//try to check if there is a new version
 	//if so, save the file
-	bytes, newEtag, err := downloadBytesFromUrl(url, etag)
+	etag := validators.Etag
+	if checkContent && contentChanged(client, url, validators) {
+		zap.S().Infof("content of [%v] changed without an etag change, downloading it again", url)
+		etag = ""
+	}
+	bytes, newValidators, err := downloadBytesFromUrl(client, url, etag)
 	if err == nil {
 		//validate json structure
 		var test map[string]interface{}
 		err = decodeToJsonObject(bytes2.NewReader(bytes), &test)
 		if err == nil {
 			file, err = saveFile(bytes, filePath)
-			etag = newEtag
+			validators = newValidators
 		} else {
 			zap.S().Infof("ignoring new update [%v], reason - [mailformed json file]", url)
 		}
@@ -46,21 +79,51 @@ func LoadAndUpdateFile(url string, filePath string, etag string) (*os.File, stri
 	}
 
 	if file == nil {
-		//load file
-		file, err = os.Open(filePath)
-		if err != nil {
-			zap.S().Infof("ignoring new update [%v], reason - [mailformed json file]", url)
-			return nil, "", err
-		}
+		file, err = openCachedFile(url, filePath)
+	}
+
+	return file, validators, err
+}
+
+// openCachedFile opens the previously downloaded file
+func openCachedFile(url string, filePath string) (*os.File, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		zap.S().Infof("ignoring new update [%v], reason - [mailformed json file]", url)
+		return nil, err
+	}
 
-		fileInfo, err := os.Stat(filePath)
-		if err != nil || fileInfo.Size() == 0 {
-			zap.S().Infof("Local file is empty, or corrupted")
-			return nil, "", err
+	fileInfo, err := os.Stat(filePath)
+	if err != nil || fileInfo.Size() == 0 {
+		zap.S().Infof("Local file is empty, or corrupted")
+		file.Close()
+		if err == nil {
+			err = fmt.Errorf("file %v is empty", filePath)
 		}
+		return nil, err
 	}
+	return file, nil
+}
 
-	return file, etag, err
+// compares the content length and last modified date reported by the server with the stored ones
+func contentChanged(client *http.Client, url string, validators FileValidators) bool {
+	if validators.ContentLength == 0 && validators.LastModified == "" {
+		return false
+	}
+	resp, err := client.Head(url)
+	if err != nil {
+		zap.S().Infof("failed to check [%v] content length, reason - [%v]", url, err)
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return false
+	}
+	if validators.ContentLength != 0 && resp.ContentLength > 0 && resp.ContentLength != validators.ContentLength {
+		return true
+	}
+	lastModified := resp.Header.Get("Last-Modified")
+	return validators.LastModified != "" && lastModified != "" && lastModified != validators.LastModified
 }
 
 func decodeToJsonObject(reader io.Reader, target interface{}) error {
@@ -68,43 +131,44 @@ func decodeToJsonObject(reader io.Reader, target interface{}) error {
 	return err
 }
 
-func downloadBytesFromUrl(url string, etag string) ([]byte, string, error) {
+func downloadBytesFromUrl(client *http.Client, url string, etag string) ([]byte, FileValidators, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, FileValidators{}, err
 	}
 	req.Header.Set("If-None-Match", etag)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, "", err
+		return nil, FileValidators{}, err
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, "", errors.New("got a non 200 response - " + resp.Status)
+		return nil, FileValidators{}, errors.New("got a non 200 response - " + resp.Status)
 	}
 	defer resp.Body.Close()
 	//getting the new etag
-	etag = resp.Header.Get("Etag")
+	validators := FileValidators{Etag: resp.Header.Get("Etag"), LastModified: resp.Header.Get("Last-Modified")}
 
 	if resp.StatusCode == http.StatusOK {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, "", err
+			return nil, FileValidators{}, err
 		}
-		return body, etag, nil
+		validators.ContentLength = int64(len(body))
+		return body, validators, nil
 	}
 
-	return nil, "", errors.New("no new updates")
+	return nil, FileValidators{}, errors.New("no new updates")
 }
 
 func saveFile(bytes []byte, fileName string) (*os.File, error) {
 
-	err := ioutil.WriteFile("./"+fileName, bytes, 0644)
+	err := ioutil.WriteFile(fileName, bytes, 0644)
 	if err != nil {
 		return nil, err
 	}
 
-	file, err := os.Open("./" + fileName)
+	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+func TestLoadAndUpdateFileWritesToTheCacheFolder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Etag", "v1")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	folder, err := ioutil.TempDir("", "slm-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	t.Setenv(settings.CACHE_FOLDER_ENV, filepath.Join(folder, "cache"))
+
+	cacheFolder, err := settings.CacheFolder(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, _, err := LoadAndUpdateFile(server.URL, filepath.Join(cacheFolder, settings.TITLE_JSON_FILENAME), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if _, err := os.Stat(filepath.Join(folder, "cache", settings.TITLE_JSON_FILENAME)); err != nil {
+		t.Errorf("the titles json is not in the cache folder - %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(folder, settings.TITLE_JSON_FILENAME)); err == nil {
+		t.Error("the titles json was written to the base folder")
+	}
+}
+
+func TestLoadAndUpdateFileContentChanges(t *testing.T) {
+	cached := `{"cached": true}`
+	downloaded := `{"downloaded": true}`
+	tests := []struct {
+		name             string
+		headStatus       int
+		headLength       int
+		headLastModified string
+		checkContent     bool
+		want             string
+	}{
+		{"unchanged etag and content", http.StatusOK, len(cached), "", true, cached},
+		{"unchanged etag and changed content length", http.StatusOK, len(cached) + 1, "", true, downloaded},
+		{"unchanged etag and changed last modified date", http.StatusOK, len(cached), "Thu, 01 Oct 2020 00:00:00 GMT", true, downloaded},
+		{"changed content length without checking the content", http.StatusOK, len(cached) + 1, "", false, cached},
+		{"head not allowed", http.StatusMethodNotAllowed, len(cached) + 1, "", true, cached},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Etag", "v1")
+				if r.Method == http.MethodHead {
+					if test.headLastModified != "" {
+						w.Header().Set("Last-Modified", test.headLastModified)
+					}
+					w.Header().Set("Content-Length", strconv.Itoa(test.headLength))
+					w.WriteHeader(test.headStatus)
+					return
+				}
+				if r.Header.Get("If-None-Match") == "v1" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Write([]byte(downloaded))
+			}))
+			defer server.Close()
+			folder, err := ioutil.TempDir("", "slm-download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			filePath := filepath.Join(folder, "titles.json")
+			if err := ioutil.WriteFile(filePath, []byte(cached), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			validators := FileValidators{Etag: "v1", ContentLength: int64(len(cached)), LastModified: "Wed, 01 Jan 2020 00:00:00 GMT"}
+			file, _, err := loadAndUpdateFile(server.Client(), server.URL, filePath, validators, test.checkContent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			content, err := ioutil.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != test.want {
+				t.Errorf("got %v, want %v", string(content), test.want)
+			}
+		})
+	}
+}
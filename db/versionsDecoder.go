@@ -0,0 +1,94 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	//merge the version lists of the duplicate entries
+	VERSIONS_DUPLICATE_MERGE = "merge"
+	//keep the entry with the highest latest version
+	VERSIONS_DUPLICATE_HIGHEST = "highest"
+)
+
+// decodeVersions parses the versions json file (titleID -> versionId -> release date). titleIds are lower cased,
+// and entries repeating a titleId are resolved with the given rule, rather than keeping whichever entry comes last.
+func decodeVersions(reader io.Reader, rule string) (map[string]map[int]string, error) {
+	decoder := json.NewDecoder(reader)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected a json object, got [%v]", token)
+	}
+	result := map[string]map[int]string{}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		id, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a titleId, got [%v]", token)
+		}
+		var titleVersions map[int]string
+		if err = decoder.Decode(&titleVersions); err != nil {
+			return nil, err
+		}
+		id = strings.ToLower(id)
+		existing, ok := result[id]
+		if !ok {
+			result[id] = titleVersions
+			continue
+		}
+		result[id] = resolveVersionsDuplicate(existing, titleVersions, rule)
+		zap.S().Infof("titleId [%v] has duplicate versions entries, using the versions %v (rule: %v)", id, versionsList(result[id]), rule)
+	}
+	if _, err = decoder.Token(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func resolveVersionsDuplicate(existing map[int]string, other map[int]string, rule string) map[int]string {
+	if rule == VERSIONS_DUPLICATE_HIGHEST {
+		if latestVersion(other) > latestVersion(existing) {
+			return other
+		}
+		return existing
+	}
+	merged := make(map[int]string, len(existing)+len(other))
+	for version, date := range other {
+		merged[version] = date
+	}
+	//on conflicting release dates, the first entry wins
+	for version, date := range existing {
+		merged[version] = date
+	}
+	return merged
+}
+
+func latestVersion(versions map[int]string) int {
+	latest := -1
+	for version := range versions {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+func versionsList(versions map[int]string) []int {
+	result := make([]int, 0, len(versions))
+	for version := range versions {
+		result = append(result, version)
+	}
+	sort.Ints(result)
+	return result
+}
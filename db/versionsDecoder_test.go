@@ -0,0 +1,51 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeVersionsDuplicates(t *testing.T) {
+	const duplicates = `{
+		"0100000000010000": {"65536": "2020-01-01", "131072": "2020-02-01"},
+		"0100000000020000": {"65536": "2020-03-01"},
+		"0100000000010000": {"65536": "2020-09-09", "196608": "2020-04-01"},
+		"0100000000020000": {}
+	}`
+	tests := []struct {
+		name    string
+		json    string
+		rule    string
+		want    map[string]map[int]string
+		wantErr bool
+	}{
+		{"merge keeps the first release dates", duplicates, VERSIONS_DUPLICATE_MERGE, map[string]map[int]string{
+			"0100000000010000": {65536: "2020-01-01", 131072: "2020-02-01", 196608: "2020-04-01"},
+			"0100000000020000": {65536: "2020-03-01"},
+		}, false},
+		{"highest keeps the entry with the latest version", duplicates, VERSIONS_DUPLICATE_HIGHEST, map[string]map[int]string{
+			"0100000000010000": {65536: "2020-09-09", 196608: "2020-04-01"},
+			"0100000000020000": {65536: "2020-03-01"},
+		}, false},
+		{"titleIds differing by case are duplicates",
+			`{"01000000000A0000": {"65536": "2020-01-01"}, "01000000000a0000": {"131072": "2020-02-01"}}`, VERSIONS_DUPLICATE_MERGE,
+			map[string]map[int]string{"01000000000a0000": {65536: "2020-01-01", 131072: "2020-02-01"}}, false},
+		{"on equal latest versions the first entry wins",
+			`{"0100000000010000": {"65536": "first"}, "0100000000010000": {"65536": "second"}}`, VERSIONS_DUPLICATE_HIGHEST,
+			map[string]map[int]string{"0100000000010000": {65536: "first"}}, false},
+		{"not an object", `[]`, VERSIONS_DUPLICATE_MERGE, nil, true},
+		{"invalid version", `{"0100000000010000": {"abc": "2020-01-01"}}`, VERSIONS_DUPLICATE_MERGE, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeVersions(strings.NewReader(test.json), test.rule)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
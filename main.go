@@ -1,16 +1,25 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/process"
 	"github.com/giwty/switch-library-manager/settings"
 	"github.com/giwty/switch-library-manager/ui"
 	"go.uber.org/zap"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 )
 
+var (
+	configFile = flag.String("config", "", "path to the settings file (defaults to settings.json in the app folder)")
+	keysStdin  = flag.Bool("keys-stdin", false, "read the keys (in the prod.keys format) from stdin")
+)
+
 func main() {
 
 	exePath, err := os.Executable()
@@ -34,6 +43,22 @@ func main() {
 		}
 	}
 
+	flag.Parse()
+	if configFile != nil && *configFile != "" {
+		settings.SetSettingsFilePath(*configFile)
+	}
+	if *keysStdin {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Println("failed to read the keys from stdin. aborting", err)
+			return
+		}
+		settings.SetKeysContent(string(content))
+	}
+
+	if flag.Arg(0) != "doctor" {
+		ui.RunFirstRunSetup(workingFolder)
+	}
 	appSettings := settings.ReadSettings(workingFolder)
 
 	logger := createLogger(workingFolder, appSettings.Debug)
@@ -45,6 +70,18 @@ func main() {
 	sugar.Infof("[Executable: %v]", exePath)
 	sugar.Infof("[Working directory: %v]", workingFolder)
 
+	process.SetAuditLogFolder(workingFolder)
+	db.SetRegionAliases(appSettings.RegionAliases)
+
+	if flag.Arg(0) == "doctor" {
+		healthy := ui.CreateConsole(workingFolder, sugar).RunHealthCheck()
+		if !healthy {
+			logger.Sync()
+			os.Exit(1)
+		}
+		return
+	}
+
 	if appSettings.GUI {
 		ui.CreateGUI(workingFolder, sugar).Start()
 	} else {
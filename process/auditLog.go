@@ -0,0 +1,53 @@
+package process
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	AUDIT_LOG_FILENAME = "slm_audit.log"
+
+	AUDIT_DELETE = "delete"
+	AUDIT_MOVE   = "move"
+	AUDIT_MKDIR  = "mkdir"
+	AUDIT_RMDIR  = "rmdir"
+)
+
+var (
+	auditLogPath  = ""
+	auditLogMutex = sync.Mutex{}
+)
+
+// SetAuditLogFolder enables the audit log, an append-only record of every change done to the library files.
+// unlike slm.log, the audit log is never truncated.
+func SetAuditLogFolder(folder string) {
+	auditLogPath = filepath.Join(folder, AUDIT_LOG_FILENAME)
+}
+
+// appends a tab separated entry: timestamp, operation, source, destination, result
+func auditOperation(operation string, source string, destination string, opErr error) {
+	if auditLogPath == "" {
+		return
+	}
+	result := "ok"
+	if opErr != nil {
+		result = "failed: " + opErr.Error()
+	}
+	auditLogMutex.Lock()
+	defer auditLogMutex.Unlock()
+	file, err := os.OpenFile(auditLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		zap.S().Errorf("Failed to open the audit log %v [%v]\n", auditLogPath, err)
+		return
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "%v\t%v\t%v\t%v\t%v\n", time.Now().Format(time.RFC3339), operation, source, destination, result)
+	if err != nil {
+		zap.S().Errorf("Failed to write to the audit log %v [%v]\n", auditLogPath, err)
+	}
+}
@@ -0,0 +1,113 @@
+package process
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+// useTestAuditLog enables the audit log in a temp folder, returning the audit log path
+func useTestAuditLog(t *testing.T) string {
+	t.Helper()
+	folder := newTestFolder(t)
+	SetAuditLogFolder(folder)
+	t.Cleanup(func() { auditLogPath = "" })
+	return filepath.Join(folder, AUDIT_LOG_FILENAME)
+}
+
+// readAuditLog returns the audit entries, without their timestamp
+func readAuditLog(t *testing.T, path string) [][]string {
+	t.Helper()
+	if !fileExists(path) {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			t.Fatalf("audit entry %q has %v fields, want 5", line, len(fields))
+		}
+		if _, err := time.Parse(time.RFC3339, fields[0]); err != nil {
+			t.Errorf("audit entry %q has no timestamp [%v]", line, err)
+		}
+		result = append(result, fields[1:])
+	}
+	return result
+}
+
+func TestAuditLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{"dry run", true},
+		{"apply", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auditLog := useTestAuditLog(t)
+			folder := newTestFolder(t)
+			settingsObj := useTestSettings(t, newTestFolder(t))
+			settingsObj.OrganizeOptions.CreateFolderPerGame = true
+			settingsObj.OrganizeOptions.DeleteOldUpdateFiles = true
+			settingsObj.OrganizeOptions.DryRun = test.dryRun
+			settingsObj.Apply = true
+
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+				File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+				BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{
+					65536:  newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536),
+					131072: newTestFile(t, folder, "v2.nsp", "0100000000010800", 131072),
+				},
+			}}}
+			titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+				"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}},
+			}}
+			DeleteOldUpdates(settingsObj, localDB, NewBlacklist(nil))
+			OrganizeByFolders(folder, localDB, titlesDB, nil)
+
+			var want [][]string
+			if !test.dryRun {
+				gameFolder := filepath.Join(folder, "Game")
+				want = [][]string{
+					{AUDIT_DELETE, filepath.Join(folder, "v1.nsp"), "", "ok"},
+					{AUDIT_MKDIR, gameFolder, "", "ok"},
+					{AUDIT_MOVE, filepath.Join(folder, "base.nsp"), filepath.Join(gameFolder, "base.nsp"), "ok"},
+					{AUDIT_MOVE, filepath.Join(folder, "v2.nsp"), filepath.Join(gameFolder, "v2.nsp"), "ok"},
+				}
+			}
+			if got := readAuditLog(t, auditLog); !reflect.DeepEqual(got, want) {
+				t.Errorf("audit entries %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAuditLogRecordsFailures(t *testing.T) {
+	auditLog := useTestAuditLog(t)
+	folder := newTestFolder(t)
+	from, to := filepath.Join(folder, "missing.nsp"), filepath.Join(folder, "moved.nsp")
+	if err := moveFile(from, to, false, nil); err == nil {
+		t.Fatal("moving a missing file succeeded")
+	}
+	//the log is append-only, a later run adds to it
+	newTestFile(t, folder, "base.nsp", "0100000000010000", 0)
+	if err := moveFile(filepath.Join(folder, "base.nsp"), to, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readAuditLog(t, auditLog)
+	if len(entries) != 2 || entries[0][1] != from || !strings.HasPrefix(entries[0][3], "failed: ") || entries[1][3] != "ok" {
+		t.Errorf("audit entries %v, want a failed move followed by a successful one", entries)
+	}
+}
@@ -0,0 +1,79 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"go.uber.org/zap"
+	"sort"
+)
+
+// Blacklist holds the update versions known to be bad (e.g. broken dumps), keyed by the titleId prefix
+type Blacklist map[string]map[int]bool
+
+// NewBlacklist builds the blacklist from the blacklisted_versions setting, mapping a titleId (of the base or the
+// update) to its bad versions
+func NewBlacklist(versions map[string][]int) Blacklist {
+	result := Blacklist{}
+	for titleId, titleVersions := range versions {
+		idPrefix, ok := titleIdPrefix(titleId)
+		if !ok {
+			zap.S().Warnf("Ignoring blacklisted versions of invalid titleId [%v]", titleId)
+			continue
+		}
+		if result[idPrefix] == nil {
+			result[idPrefix] = map[int]bool{}
+		}
+		for _, version := range titleVersions {
+			result[idPrefix][version] = true
+		}
+	}
+	return result
+}
+
+func (b Blacklist) Contains(idPrefix string, version int) bool {
+	return b[idPrefix][version]
+}
+
+type BlacklistedFile struct {
+	File    db.ExtendedFileInfo
+	Version int
+}
+
+// FindBlacklistedUpdates lists the local update files of a blacklisted version
+func FindBlacklistedUpdates(localDB map[string]*db.SwitchFile, blacklist Blacklist) []BlacklistedFile {
+	var result []BlacklistedFile
+	for idPrefix, switchFile := range localDB {
+		for version, f := range switchFile.Updates {
+			if blacklist.Contains(idPrefix, version) {
+				result = append(result, BlacklistedFile{File: f, Version: version})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File.Info.Name() < result[j].File.Info.Name()
+	})
+	return result
+}
+
+// ExcludeBlacklisted returns a copy of the local DB map without the blacklisted updates, so that they are never
+// considered the latest owned update
+func ExcludeBlacklisted(localDB map[string]*db.SwitchFile, blacklist Blacklist) map[string]*db.SwitchFile {
+	if len(blacklist) == 0 {
+		return localDB
+	}
+	result := make(map[string]*db.SwitchFile, len(localDB))
+	for idPrefix, switchFile := range localDB {
+		result[idPrefix] = switchFile
+		if len(blacklist[idPrefix]) == 0 {
+			continue
+		}
+		filtered := *switchFile
+		filtered.Updates = map[int]db.ExtendedFileInfo{}
+		for version, f := range switchFile.Updates {
+			if !blacklist.Contains(idPrefix, version) {
+				filtered.Updates[version] = f
+			}
+		}
+		result[idPrefix] = &filtered
+	}
+	return result
+}
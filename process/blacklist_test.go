@@ -0,0 +1,73 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestNewBlacklist(t *testing.T) {
+	blacklist := NewBlacklist(map[string][]int{
+		//the base and the update titleIds both blacklist the updates of the title
+		"0100000000010000": {65536},
+		"0100000000010800": {131072},
+		"0100ABCD00020800": {65536},
+		"0100":             {65536},
+	})
+	want := Blacklist{
+		"010000000001": {65536: true, 131072: true},
+		"0100abcd0002": {65536: true},
+	}
+	if !reflect.DeepEqual(blacklist, want) {
+		t.Errorf("got %v, want %v", blacklist, want)
+	}
+	if blacklist.Contains("010000000001", 196608) || !blacklist.Contains("010000000001", 131072) {
+		t.Error("got the wrong blacklisted versions")
+	}
+}
+
+func TestBlacklistedUpdates(t *testing.T) {
+	folder := newTestFolder(t)
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {BaseExist: true, File: newTestFile(t, folder, "a base.nsp", "0100000000010000", 0),
+			Updates: map[int]db.ExtendedFileInfo{
+				65536:  newTestFile(t, folder, "a v1.nsp", "0100000000010800", 65536),
+				131072: newTestFile(t, folder, "a v2.nsp", "0100000000010800", 131072),
+			}},
+		"010000000002": {BaseExist: true, File: newTestFile(t, folder, "b base.nsp", "0100000000020000", 0),
+			Updates: map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "b v1.nsp", "0100000000020800", 65536)}},
+	}
+	blacklist := NewBlacklist(map[string][]int{"0100000000010800": {131072}})
+
+	blacklisted := FindBlacklistedUpdates(localDB, blacklist)
+	if len(blacklisted) != 1 || blacklisted[0].File.Info.Name() != "a v2.nsp" || blacklisted[0].Version != 131072 {
+		t.Errorf("got blacklisted updates %+v, want a v2.nsp", blacklisted)
+	}
+
+	//the blacklisted version is not the latest owned update, so the update is still missing
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "A"}, Updates: map[int]string{65536: "", 131072: ""}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "B"}, Updates: map[int]string{65536: ""}},
+	}
+	filtered := ExcludeBlacklisted(localDB, blacklist)
+	missing := ScanForMissingUpdates(filtered, switchDB)
+	if len(missing) != 1 || missing["0100000000010000"].LocalUpdate != 65536 {
+		t.Errorf("got missing updates %+v, want A at the local update 65536", missing)
+	}
+	if filtered["010000000002"] != localDB["010000000002"] {
+		t.Error("a title without blacklisted versions was copied")
+	}
+	if len(localDB["010000000001"].Updates) != 2 {
+		t.Error("the blacklisted update was removed from the local DB")
+	}
+	if got := ScanForMissingUpdates(localDB, switchDB); len(got) != 0 {
+		t.Errorf("got missing updates %+v without the blacklist, want none", got)
+	}
+
+	//the blacklisted update is the one to delete, not the older one
+	oldUpdates := ConsolidateUpdates(&db.LocalSwitchFilesDB{TitlesMap: localDB}, true, blacklist)
+	if len(oldUpdates) != 1 || oldUpdates[0].File.Info.Name() != "a v2.nsp" || oldUpdates[0].LatestLocalVersion != 65536 {
+		t.Errorf("got old updates %+v, want the blacklisted a v2.nsp", oldUpdates)
+	}
+}
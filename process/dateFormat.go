@@ -0,0 +1,39 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"strings"
+	"time"
+)
+
+var (
+	//date formats found in the titles/versions DB
+	knownDateLayouts = []string{"2006-01-02", "20060102", "2006/01/02", time.RFC3339}
+	datePresets      = map[string]string{
+		settings.DATE_FORMAT_ISO8601: "2006-01-02",
+		settings.DATE_FORMAT_US:      "01/02/2006",
+		settings.DATE_FORMAT_EU:      "02/01/2006",
+	}
+)
+
+// FormatDate normalizes a date from the DB to the given format, which is either a preset name or a Go time layout.
+// empty dates are returned as is, and dates that cannot be parsed are returned unchanged.
+func FormatDate(date string, format string) string {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return ""
+	}
+	layout := format
+	if preset, ok := datePresets[strings.ToLower(format)]; ok {
+		layout = preset
+	}
+	if layout == "" {
+		layout = datePresets[settings.DATE_FORMAT_ISO8601]
+	}
+	for _, knownLayout := range knownDateLayouts {
+		if t, err := time.Parse(knownLayout, date); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return date
+}
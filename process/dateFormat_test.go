@@ -0,0 +1,37 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		name   string
+		date   string
+		format string
+		want   string
+	}{
+		{"iso date to iso", "2020-07-31", settings.DATE_FORMAT_ISO8601, "2020-07-31"},
+		{"compact date to iso", "20200731", settings.DATE_FORMAT_ISO8601, "2020-07-31"},
+		{"slashed date to iso", "2020/07/31", settings.DATE_FORMAT_ISO8601, "2020-07-31"},
+		{"rfc3339 date to iso", "2020-07-31T10:00:00Z", settings.DATE_FORMAT_ISO8601, "2020-07-31"},
+		{"us preset", "20200731", settings.DATE_FORMAT_US, "07/31/2020"},
+		{"eu preset", "2020-07-31", settings.DATE_FORMAT_EU, "31/07/2020"},
+		{"preset names are case insensitive", "2020-07-31", "EU", "31/07/2020"},
+		{"go layout", "2020-07-31", "Jan 2, 2006", "Jul 31, 2020"},
+		{"no format defaults to iso", "20200731", "", "2020-07-31"},
+		{"surrounding spaces", " 2020-07-31 ", settings.DATE_FORMAT_US, "07/31/2020"},
+		{"empty date", "", settings.DATE_FORMAT_US, ""},
+		{"blank date", "  ", settings.DATE_FORMAT_US, ""},
+		{"unparseable date", "soon", settings.DATE_FORMAT_US, "soon"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FormatDate(test.date, test.format); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
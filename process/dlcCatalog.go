@@ -0,0 +1,52 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+type DLCEntry struct {
+	//the titles DB entry, with its id and name always set
+	Attributes db.TitleAttributes
+	Owned      bool
+	//the local file, when owned
+	Local db.ExtendedFileInfo
+}
+
+// DLCCatalog lists all the DLC of a title in the titles DB, ordered by titleId, flagging the ones owned locally.
+// switchFile is nil when no file of the title is owned.
+func DLCCatalog(switchFile *db.SwitchFile, switchTitle *db.SwitchTitle) []DLCEntry {
+	result := make([]DLCEntry, 0, len(switchTitle.Dlc))
+	for id, attributes := range switchTitle.Dlc {
+		entry := DLCEntry{Attributes: attributes}
+		if switchFile != nil {
+			entry.Local, entry.Owned = switchFile.Dlc[id]
+		}
+		if entry.Attributes.Id == "" {
+			entry.Attributes.Id = strings.ToUpper(id)
+		}
+		entry.Attributes.Name = dlcName(entry, switchTitle)
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Attributes.Id) < strings.ToLower(result[j].Attributes.Id)
+	})
+	return result
+}
+
+// the name of the DLC in the titles DB, else the name tag of the local file, else a name derived from the base game
+func dlcName(entry DLCEntry, switchTitle *db.SwitchTitle) string {
+	if name := strings.TrimSpace(entry.Attributes.Name); name != "" {
+		return name
+	}
+	if entry.Owned && entry.Local.Info != nil {
+		if name := strings.TrimSpace(db.ParseTitleNameFromFileName(entry.Local.Info.Name())); name != "" {
+			return name
+		}
+	}
+	if switchTitle.Attributes.Name != "" {
+		return switchTitle.Attributes.Name + " DLC"
+	}
+	return "DLC"
+}
@@ -0,0 +1,80 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestDLCCatalog(t *testing.T) {
+	folder := newTestFolder(t)
+	switchTitle := &db.SwitchTitle{Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"},
+		Dlc: map[string]db.TitleAttributes{
+			"0100000000011002": {Id: "0100000000011002", Name: "Second Pack"},
+			"0100000000011001": {Id: "0100000000011001", Name: " "},
+			//entries without an id or a name
+			"0100000000011003": {},
+		}}
+	switchFile := &db.SwitchFile{BaseExist: true, Dlc: map[string]db.ExtendedFileInfo{
+		"0100000000011001": newTestFile(t, folder, "First Pack [0100000000011001][v0].nsp", "0100000000011001", 0),
+		"0100000000011002": newTestFile(t, folder, "dlc2.nsp", "0100000000011002", 0),
+	}}
+
+	type entry struct {
+		id, name string
+		owned    bool
+	}
+	tests := []struct {
+		name       string
+		switchFile *db.SwitchFile
+		want       []entry
+	}{
+		{"owned DLC", switchFile, []entry{
+			//named after the local file name tag
+			{"0100000000011001", "First Pack", true},
+			{"0100000000011002", "Second Pack", true},
+			//named after the base game
+			{"0100000000011003", "Game DLC", false},
+		}},
+		{"title not owned", nil, []entry{
+			{"0100000000011001", "Game DLC", false},
+			{"0100000000011002", "Second Pack", false},
+			{"0100000000011003", "Game DLC", false},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []entry
+			for _, dlc := range DLCCatalog(test.switchFile, switchTitle) {
+				got = append(got, entry{dlc.Attributes.Id, dlc.Attributes.Name, dlc.Owned})
+				if dlc.Owned != (dlc.Local.Info != nil) {
+					t.Errorf("got owned %v with the local file %+v", dlc.Owned, dlc.Local)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+	if switchTitle.Dlc["0100000000011003"].Name != "" {
+		t.Error("the titles DB entry was changed")
+	}
+}
+
+func TestMissingDLCAreNamed(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"},
+		Dlc: map[string]db.TitleAttributes{
+			"0100000000011001": {Id: "0100000000011001", Name: "First Pack"},
+			"0100000000011002": {},
+		}}}
+	localDB := map[string]*db.SwitchFile{"010000000001": localTitle("0100000000010000", true, nil)}
+
+	missing := ScanForMissingDLC(localDB, switchDB)["0100000000010000"]
+	if want := []string{"First Pack [0100000000011001]", "Game DLC [0100000000011002]"}; !reflect.DeepEqual(missing.MissingDLC, want) {
+		t.Errorf("got missing DLC %v, want %v", missing.MissingDLC, want)
+	}
+	if want := []string{"0100000000011001", "0100000000011002"}; !reflect.DeepEqual(missing.MissingDLCIds, want) {
+		t.Errorf("got missing DLC ids %v, want %v", missing.MissingDLCIds, want)
+	}
+}
@@ -0,0 +1,80 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type DLCRelease struct {
+	Title       db.TitleAttributes
+	Dlc         db.TitleAttributes
+	ReleaseDate time.Time
+	Owned       bool
+}
+
+// DLCReportSnapshot records when the recent DLC were last reported, to mark the DLC released since then
+type DLCReportSnapshot struct {
+	Time time.Time `json:"time"`
+}
+
+// LoadDLCReportSnapshot reads the snapshot saved by the previous report, false when there is none
+func LoadDLCReportSnapshot(filePath string) (DLCReportSnapshot, bool, error) {
+	snapshot := DLCReportSnapshot{}
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return snapshot, false, err
+	}
+	return snapshot, true, nil
+}
+
+func SaveDLCReportSnapshot(filePath string, snapshot DLCReportSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+// FindDLCReleases lists the DLC of the locally owned base games released after since, newest first.
+// DLC without a (valid) release date in the titles DB are skipped.
+func FindDLCReleases(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, since time.Time) []DLCRelease {
+	var result []DLCRelease
+	for idPrefix, switchFile := range localDB {
+		switchTitle, ok := switchDB[idPrefix]
+		if !ok || !switchFile.BaseExist {
+			continue
+		}
+		for id, dlc := range switchTitle.Dlc {
+			releaseDate, err := parseReleaseDate(dlc.ReleaseDate)
+			if err != nil || !releaseDate.After(since) {
+				continue
+			}
+			_, owned := switchFile.Dlc[id]
+			result = append(result, DLCRelease{Title: switchTitle.Attributes, Dlc: dlc, ReleaseDate: releaseDate, Owned: owned})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ReleaseDate.Equal(result[j].ReleaseDate) {
+			return result[i].Dlc.Id < result[j].Dlc.Id
+		}
+		return result[i].ReleaseDate.After(result[j].ReleaseDate)
+	})
+	return result
+}
+
+// release dates are stored as YYYYMMDD numbers
+func parseReleaseDate(releaseDate int) (time.Time, error) {
+	return time.Parse("20060102", strconv.Itoa(releaseDate))
+}
@@ -0,0 +1,39 @@
+package process
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDLCReportSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		save      bool
+		wantFound bool
+	}{
+		{"first report", false, false},
+		{"previous report", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshotPath := filepath.Join(newTestFolder(t), "last_dlc_report.json")
+			reported := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+			if test.save {
+				if err := SaveDLCReportSnapshot(snapshotPath, DLCReportSnapshot{Time: reported}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			snapshot, found, err := LoadDLCReportSnapshot(snapshotPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != test.wantFound {
+				t.Errorf("found %v, want %v", found, test.wantFound)
+			}
+			if found && !snapshot.Time.Equal(reported) {
+				t.Errorf("time %v, want %v", snapshot.Time, reported)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+const (
+	STRATEGY_SMALLEST_FIRST = "smallest-first"
+	STRATEGY_LARGEST_FIRST  = "largest-first"
+)
+
+type DownloadCandidate struct {
+	TitleId string `json:"title_id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Size    int64  `json:"size"`
+	//content of a favorite title, planned before the other content
+	Favorite bool `json:"favorite"`
+}
+
+// DownloadCandidates resolves the name and size (from the titles DB) of every missing update and DLC.
+// the content of the favorite titles (by the titleId of any of their base, update or DLC) is marked as favorite.
+func DownloadCandidates(missingUpdates map[string]IncompleteTitle, missingDLC map[string]IncompleteTitle, switchDB map[string]*db.SwitchTitle,
+	favorites []string) []DownloadCandidate {
+	favoritePrefixes := map[string]bool{}
+	for _, titleId := range favorites {
+		titleId = strings.ToLower(strings.TrimSpace(titleId))
+		if len(titleId) == 16 {
+			favoritePrefixes[titleId[0:len(titleId)-4]] = true
+		}
+	}
+	var result []DownloadCandidate
+	for _, item := range DownloadRequests(missingUpdates, missingDLC, switchDB) {
+		idPrefix := item.TitleId[0 : len(item.TitleId)-4]
+		candidate := DownloadCandidate{TitleId: item.TitleId, Version: item.Version, Favorite: favoritePrefixes[idPrefix]}
+		if switchTitle, ok := switchDB[idPrefix]; ok {
+			candidate.Name = switchTitle.Attributes.Name
+			if strings.HasSuffix(item.TitleId, "800") {
+				//the size of the latest update
+				candidate.Size = int64(switchTitle.UpdateSize)
+			} else if dlc, ok := switchTitle.Dlc[item.TitleId]; ok {
+				candidate.Name = dlc.Name
+				candidate.Size = int64(dlc.Size)
+			}
+		}
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// PlanDownloads picks the missing content fitting in the given budget, favorites first, then ordered by the strategy
+// (smallest-first or largest-first), and returns the chosen set with the leftover budget.
+// candidates with an unknown size are not planned.
+func PlanDownloads(missing []DownloadCandidate, budgetBytes int64, strategy string) ([]DownloadCandidate, int64) {
+	var candidates []DownloadCandidate
+	for _, c := range missing {
+		if c.Size > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Favorite != candidates[j].Favorite {
+			return candidates[i].Favorite
+		}
+		if candidates[i].Size == candidates[j].Size {
+			return candidates[i].TitleId < candidates[j].TitleId
+		}
+		if strings.ToLower(strategy) == STRATEGY_LARGEST_FIRST {
+			return candidates[i].Size > candidates[j].Size
+		}
+		return candidates[i].Size < candidates[j].Size
+	})
+
+	var chosen []DownloadCandidate
+	remaining := budgetBytes
+	for _, c := range candidates {
+		if c.Size <= remaining {
+			chosen = append(chosen, c)
+			remaining -= c.Size
+		}
+	}
+	return chosen, remaining
+}
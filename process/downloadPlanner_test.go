@@ -0,0 +1,104 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func candidateIds(candidates []DownloadCandidate) []string {
+	var ids []string
+	for _, c := range candidates {
+		ids = append(ids, c.TitleId)
+	}
+	return ids
+}
+
+func TestPlanDownloads(t *testing.T) {
+	missing := []DownloadCandidate{
+		{TitleId: "a", Size: 30},
+		{TitleId: "b", Size: 10},
+		{TitleId: "c", Size: 20},
+		{TitleId: "d", Size: 0},
+		{TitleId: "e", Size: 25, Favorite: true},
+	}
+	tests := []struct {
+		name          string
+		budget        int64
+		strategy      string
+		wantIds       []string
+		wantRemaining int64
+	}{
+		{"smallest first", 60, STRATEGY_SMALLEST_FIRST, []string{"e", "b", "c"}, 5},
+		{"largest first", 60, STRATEGY_LARGEST_FIRST, []string{"e", "a"}, 5},
+		{"largest first fills the gaps", 70, STRATEGY_LARGEST_FIRST, []string{"e", "a", "b"}, 5},
+		{"unknown strategy is smallest first", 60, "", []string{"e", "b", "c"}, 5},
+		{"strategy is case insensitive", 60, "Largest-First", []string{"e", "a"}, 5},
+		{"zero budget", 0, STRATEGY_SMALLEST_FIRST, nil, 0},
+		{"budget below the smallest", 9, STRATEGY_SMALLEST_FIRST, nil, 9},
+		{"exact fit", 10, STRATEGY_SMALLEST_FIRST, []string{"b"}, 0},
+		{"budget above everything", 1000, STRATEGY_SMALLEST_FIRST, []string{"e", "b", "c", "a"}, 915},
+		{"favorite too large is passed over", 24, STRATEGY_SMALLEST_FIRST, []string{"b"}, 14},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chosen, remaining := PlanDownloads(missing, test.budget, test.strategy)
+			if ids := candidateIds(chosen); !reflect.DeepEqual(ids, test.wantIds) {
+				t.Errorf("chosen %v, want %v", ids, test.wantIds)
+			}
+			if remaining != test.wantRemaining {
+				t.Errorf("remaining %v, want %v", remaining, test.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestDownloadCandidates(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {
+			Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"},
+			Dlc:        map[string]db.TitleAttributes{"0100000000011001": {Id: "0100000000011001", Name: "Game DLC", Size: 100}},
+			UpdateSize: 500,
+		},
+		"010000000002": {
+			Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Other"},
+		},
+	}
+	missingUpdates := map[string]IncompleteTitle{
+		"010000000001": {Attributes: switchDB["010000000001"].Attributes, LatestUpdate: 65536},
+		"010000000002": {Attributes: switchDB["010000000002"].Attributes, LatestUpdate: 65536},
+	}
+	missingDLC := map[string]IncompleteTitle{
+		"0100000000010000": {Attributes: switchDB["010000000001"].Attributes, MissingDLCIds: []string{"0100000000011001"}},
+	}
+	tests := []struct {
+		name      string
+		favorites []string
+		want      []DownloadCandidate
+	}{
+		{"no favorites", nil, []DownloadCandidate{
+			{TitleId: "0100000000010800", Name: "Game", Version: 65536, Size: 500},
+			{TitleId: "0100000000011001", Name: "Game DLC", Size: 100},
+			{TitleId: "0100000000020800", Name: "Other", Version: 65536},
+		}},
+		{"favorite by base titleId", []string{"0100000000010000"}, []DownloadCandidate{
+			{TitleId: "0100000000010800", Name: "Game", Version: 65536, Size: 500, Favorite: true},
+			{TitleId: "0100000000011001", Name: "Game DLC", Size: 100, Favorite: true},
+			{TitleId: "0100000000020800", Name: "Other", Version: 65536},
+		}},
+		{"favorite by update titleId", []string{"0100000000020800"}, []DownloadCandidate{
+			{TitleId: "0100000000010800", Name: "Game", Version: 65536, Size: 500},
+			{TitleId: "0100000000011001", Name: "Game DLC", Size: 100},
+			{TitleId: "0100000000020800", Name: "Other", Version: 65536, Favorite: true},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := DownloadCandidates(missingUpdates, missingDLC, switchDB, test.favorites)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+}
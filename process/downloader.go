@@ -0,0 +1,126 @@
+package process
+
+import (
+	"errors"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type DownloadResult struct {
+	Item     InventoryItem `json:"item"`
+	ExitCode int           `json:"exit_code"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// DownloadRequests lists the titleId + version of every missing update and missing DLC
+func DownloadRequests(missingUpdates map[string]IncompleteTitle, missingDLC map[string]IncompleteTitle, switchDB map[string]*db.SwitchTitle) []InventoryItem {
+	var result []InventoryItem
+	for _, v := range missingUpdates {
+		titleId := strings.ToLower(v.Attributes.Id)
+		//game updates are listed under the base titleId
+		if strings.HasSuffix(titleId, "000") {
+			titleId = titleId[0:len(titleId)-3] + "800"
+		}
+		result = append(result, InventoryItem{TitleId: titleId, Version: v.LatestUpdate})
+	}
+	for idPrefix, v := range missingDLC {
+		switchTitle := switchDB[strings.ToLower(idPrefix)[0:len(idPrefix)-4]]
+		for _, dlcId := range v.MissingDLCIds {
+			version := 0
+			if switchTitle != nil {
+				if dlc, ok := switchTitle.Dlc[dlcId]; ok {
+					if latest, err := dlc.Version.Int64(); err == nil {
+						version = int(latest)
+					}
+				}
+			}
+			result = append(result, InventoryItem{TitleId: dlcId, Version: version})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+// RunDownloader invokes the user provided command template for each item, substituting {TITLE_ID} and {VERSION}.
+// the command is run directly (not through a shell), arguments holding spaces can be quoted in the template
+func RunDownloader(commandTemplate string, items []InventoryItem) []DownloadResult {
+	var result []DownloadResult
+	args, err := splitCommand(commandTemplate)
+	if err != nil {
+		zap.S().Errorf("Invalid downloader command [%v] - %v\n", commandTemplate, err)
+		for _, item := range items {
+			result = append(result, DownloadResult{Item: item, ExitCode: -1, Error: err.Error()})
+		}
+		return result
+	}
+	if len(args) == 0 {
+		return result
+	}
+	for _, item := range items {
+		replacer := strings.NewReplacer("{"+settings.TEMPLATE_TITLE_ID+"}", strings.ToUpper(item.TitleId),
+			"{"+settings.TEMPLATE_VERSION+"}", strconv.Itoa(item.Version))
+		itemArgs := make([]string, len(args))
+		for i, arg := range args {
+			itemArgs[i] = replacer.Replace(arg)
+		}
+		zap.S().Infof("--> [Download] running %q", itemArgs)
+		err := exec.Command(itemArgs[0], itemArgs[1:]...).Run()
+		downloadResult := DownloadResult{Item: item}
+		if err != nil {
+			downloadResult.ExitCode = -1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				downloadResult.ExitCode = exitErr.ExitCode()
+			}
+			downloadResult.Error = err.Error()
+			zap.S().Errorf("Failed to download %v [%v]\n", item.TitleId, err)
+		}
+		result = append(result, downloadResult)
+	}
+	return result
+}
+
+// splits the command template into arguments on white space, except within single or double quotes
+func splitCommand(commandTemplate string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, c := range commandTemplate {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// newFakeDownloader writes a script (in a folder with a space in its name) recording its arguments, one per line, and
+// exiting with the given code. returns the script path and the file recording the invocations
+func newFakeDownloader(t *testing.T, exitCode string) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake downloader is a shell script")
+	}
+	folder := filepath.Join(newTestFolder(t), "fake downloader")
+	if err := os.Mkdir(folder, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	invocations := filepath.Join(folder, "invocations")
+	script := "#!/bin/sh\nfor arg in \"$@\"; do printf '%s\\n' \"$arg\" >> \"" + invocations + "\"; done\n" +
+		"echo --- >> \"" + invocations + "\"\nexit " + exitCode + "\n"
+	scriptPath := filepath.Join(folder, "download.sh")
+	if err := ioutil.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return scriptPath, invocations
+}
+
+func TestRunDownloader(t *testing.T) {
+	items := []InventoryItem{{TitleId: "0100000000010800", Version: 131072}, {TitleId: "0100000000011001", Version: 0}}
+	tests := []struct {
+		name            string
+		exitCode        string
+		args            string
+		wantExitCode    int
+		wantInvocations string
+	}{
+		{"success", "0", `--id {TITLE_ID} --version {VERSION}`, 0,
+			"--id\n0100000000010800\n--version\n131072\n---\n--id\n0100000000011001\n--version\n0\n---\n"},
+		{"non zero exit code", "3", `{TITLE_ID}`, 3,
+			"0100000000010800\n---\n0100000000011001\n---\n"},
+		{"quoted arguments and shell characters", "0", `--out "My Games/{TITLE_ID} v{VERSION}" 'a;b' $(id) && * |`, 0,
+			"--out\nMy Games/0100000000010800 v131072\na;b\n$(id)\n&&\n*\n|\n---\n" +
+				"--out\nMy Games/0100000000011001 v0\na;b\n$(id)\n&&\n*\n|\n---\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scriptPath, invocations := newFakeDownloader(t, test.exitCode)
+			results := RunDownloader(`"`+scriptPath+`" `+test.args, items)
+
+			if len(results) != len(items) {
+				t.Fatalf("got %v results, want %v", len(results), len(items))
+			}
+			for i, result := range results {
+				if result.Item != items[i] || result.ExitCode != test.wantExitCode || (result.Error != "") != (test.wantExitCode != 0) {
+					t.Errorf("got result %+v, want item %v with exit code %v", result, items[i], test.wantExitCode)
+				}
+			}
+			recorded, err := ioutil.ReadFile(invocations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(recorded) != test.wantInvocations {
+				t.Errorf("got invocations\n%v\nwant\n%v", string(recorded), test.wantInvocations)
+			}
+		})
+	}
+}
+
+func TestRunDownloaderFailures(t *testing.T) {
+	items := []InventoryItem{{TitleId: "0100000000010800", Version: 65536}}
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{"missing command", filepath.Join(newTestFolder(t), "missing"), "no such file"},
+		{"unterminated quote", `download "{TITLE_ID}`, "unterminated quote"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := RunDownloader(test.template, items)
+			if len(results) != 1 || results[0].ExitCode != -1 || !strings.Contains(results[0].Error, test.wantErr) {
+				t.Errorf("got results %+v, want exit code -1 with error %q", results, test.wantErr)
+			}
+		})
+	}
+	if results := RunDownloader("", items); !reflect.DeepEqual(results, []DownloadResult(nil)) {
+		t.Errorf("got results %+v, want no invocation without a command", results)
+	}
+}
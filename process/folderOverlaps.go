@@ -0,0 +1,48 @@
+package process
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type FolderOverlap struct {
+	Folder string
+	//the scan folder holding Folder
+	Parent string
+}
+
+// FindFolderOverlaps lists the scan folders nested inside another scan folder. organizing such folders is unsafe:
+// with a recursive scan their files are found twice, and the files moved into the outer folder are
+// scanned again on the next run.
+func FindFolderOverlaps(scanFolders []string) []FolderOverlap {
+	var result []FolderOverlap
+	for i, folder := range scanFolders {
+		for j, parent := range scanFolders {
+			if i != j && isSubFolder(parent, folder) {
+				result = append(result, FolderOverlap{Folder: folder, Parent: parent})
+			}
+		}
+	}
+	return result
+}
+
+// isSubFolder checks whether folder is strictly inside parent
+func isSubFolder(parent string, folder string) bool {
+	parent, err := filepath.Abs(parent)
+	if err != nil {
+		return false
+	}
+	folder, err = filepath.Abs(folder)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		parent, folder = strings.ToLower(parent), strings.ToLower(folder)
+	}
+	rel, err := filepath.Rel(parent, folder)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
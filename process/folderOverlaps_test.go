@@ -0,0 +1,40 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindFolderOverlaps(t *testing.T) {
+	root := filepath.Join(os.TempDir(), "library")
+	games, nested := filepath.Join(root, "games"), filepath.Join(root, "games", "new")
+	workingFolder, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		folders []string
+		want    []FolderOverlap
+	}{
+		{"single folder", []string{games}, nil},
+		{"separate folders", []string{games, filepath.Join(root, "dlc")}, nil},
+		{"sibling with the same prefix", []string{games, filepath.Join(root, "games2")}, nil},
+		{"nested folder", []string{games, nested}, []FolderOverlap{{Folder: nested, Parent: games}}},
+		{"nested folder listed first", []string{nested, games}, []FolderOverlap{{Folder: nested, Parent: games}}},
+		{"deeply nested folder", []string{root, nested}, []FolderOverlap{{Folder: nested, Parent: root}}},
+		{"trailing separator", []string{games + string(filepath.Separator), nested},
+			[]FolderOverlap{{Folder: nested, Parent: games + string(filepath.Separator)}}},
+		{"relative folder", []string{"games", filepath.Join(workingFolder, "games", "new")},
+			[]FolderOverlap{{Folder: filepath.Join(workingFolder, "games", "new"), Parent: "games"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FindFolderOverlaps(test.folders); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
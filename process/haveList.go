@@ -0,0 +1,77 @@
+package process
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const haveListHashPrefix = "# sha256 "
+
+// CanonicalHaveList renders the inventory as sorted "titleId:version" lines, with lower case titleIds,
+// so that two identical libraries always produce the same text
+func CanonicalHaveList(items []InventoryItem) []string {
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, fmt.Sprintf("%v:%v", strings.ToLower(item.TitleId), item.Version))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// HaveListHash is the sha256 of the canonical have list, comparing the hashes is enough to tell if two libraries match
+func HaveListHash(lines []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// WriteHaveList writes the hash line, followed by the canonical have list lines
+func WriteHaveList(writer io.Writer, items []InventoryItem) error {
+	lines := CanonicalHaveList(items)
+	_, err := fmt.Fprintf(writer, "%v%v\n", haveListHashPrefix, HaveListHash(lines))
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		_, err = fmt.Fprintln(writer, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadHaveList parses a have list written by WriteHaveList, returning its items and recorded hash
+func ReadHaveList(reader io.Reader) ([]InventoryItem, string, error) {
+	var items []InventoryItem
+	hash := ""
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, haveListHashPrefix) {
+			hash = strings.TrimPrefix(line, haveListHashPrefix)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != 2 {
+			return nil, "", fmt.Errorf("invalid have list line [%v]", line)
+		}
+		version, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid version in have list line [%v]", line)
+		}
+		items = append(items, InventoryItem{TitleId: strings.ToLower(parts[0]), Version: version})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return items, hash, nil
+}
@@ -0,0 +1,106 @@
+package process
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalHaveList(t *testing.T) {
+	items := []InventoryItem{
+		{TitleId: "0100000000020000", Version: 0},
+		{TitleId: "0100000000010800", Version: 65536},
+		{TitleId: "0100000000010000", Version: 0},
+	}
+	//the same library, listed in another order
+	other := []InventoryItem{
+		{TitleId: "0100000000010000", Version: 0},
+		{TitleId: "0100000000020000", Version: 0},
+		{TitleId: "0100000000010800", Version: 65536},
+	}
+	want := []string{"0100000000010000:0", "0100000000010800:65536", "0100000000020000:0"}
+	got := CanonicalHaveList(items)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if HaveListHash(got) != HaveListHash(CanonicalHaveList(other)) {
+		t.Error("got different hashes for the same library")
+	}
+	changed := append([]InventoryItem{}, other...)
+	changed[2].Version = 131072
+	if HaveListHash(got) == HaveListHash(CanonicalHaveList(changed)) {
+		t.Error("got the same hash for libraries with different update versions")
+	}
+}
+
+func TestHaveListRoundTrip(t *testing.T) {
+	items := []InventoryItem{
+		{TitleId: "0100000000020000", Version: 0},
+		{TitleId: "0100000000010800", Version: 65536},
+	}
+	var written strings.Builder
+	if err := WriteHaveList(&written, items); err != nil {
+		t.Fatal(err)
+	}
+	lines := CanonicalHaveList(items)
+	if !strings.HasPrefix(written.String(), haveListHashPrefix+HaveListHash(lines)+"\n") {
+		t.Errorf("got have list %q, want it to start with its hash", written.String())
+	}
+	read, hash, err := ReadHaveList(strings.NewReader(written.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != HaveListHash(lines) || !reflect.DeepEqual(CanonicalHaveList(read), lines) {
+		t.Errorf("got items %v with hash %v, want %v with hash %v", read, hash, lines, HaveListHash(lines))
+	}
+}
+
+func TestReadHaveList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []InventoryItem
+		wantErr bool
+	}{
+		{"comments and blank lines", "# from a friend\n\n0100000000010000:0\n  0100000000010800:65536  \n",
+			[]InventoryItem{{TitleId: "0100000000010000", Version: 0}, {TitleId: "0100000000010800", Version: 65536}}, false},
+		{"upper case titleId", "0100ABCD00010000:0\n", []InventoryItem{{TitleId: "0100abcd00010000", Version: 0}}, false},
+		{"missing version", "0100000000010000\n", nil, true},
+		{"invalid version", "0100000000010000:v1\n", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, hash, err := ReadHaveList(strings.NewReader(test.content))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) || hash != "" {
+				t.Errorf("got %v with hash %q, want %v without a hash", got, hash, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareHaveLists(t *testing.T) {
+	local := []InventoryItem{
+		{TitleId: "0100000000010000", Version: 0},
+		{TitleId: "0100000000010800", Version: 65536},
+		{TitleId: "0100000000030000", Version: 0},
+	}
+	other := []InventoryItem{
+		{TitleId: "0100000000010000", Version: 0},
+		{TitleId: "0100000000010800", Version: 131072},
+		{TitleId: "0100000000020000", Version: 0},
+	}
+	want := ReconcileResult{
+		MissingHere:     []InventoryItem{{TitleId: "0100000000020000", Version: 0}},
+		ExtraHere:       []InventoryItem{{TitleId: "0100000000030000", Version: 0}},
+		VersionMismatch: []VersionMismatch{{TitleId: "0100000000010800", LocalVersion: 65536, InventoryVersion: 131072}},
+	}
+	if got := CompareInventories(local, other); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := CompareInventories(local, local); !reflect.DeepEqual(got, ReconcileResult{}) {
+		t.Errorf("got %+v comparing a have list with itself, want no differences", got)
+	}
+}
@@ -3,10 +3,12 @@ package process
 import (
 	"fmt"
 	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/settings"
 	"github.com/giwty/switch-library-manager/switchfs"
 	"go.uber.org/zap"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 type IncompleteTitle struct {
@@ -16,21 +18,37 @@ type IncompleteTitle struct {
 	LatestUpdate     int      `json:"latest_update"`
 	LatestUpdateDate string   `json:"latest_update_date"`
 	MissingDLC       []string `json:"missing_dlc"`
+	MissingDLCIds    []string `json:"missing_dlc_ids"`
+	OwnedDLC         int      `json:"owned_dlc"`
+	TotalDLC         int      `json:"total_dlc"`
+}
+
+// isBaseVersion checks whether the latest update is no newer than the local base itself - either
+// version 0, or an update already included in the base (as in some cartridge dumps)
+func isBaseVersion(latestUpdate int, switchFile *db.SwitchFile) bool {
+	if latestUpdate <= 0 {
+		return true
+	}
+	return switchFile.File.Metadata != nil && switchFile.File.Metadata.Version >= latestUpdate
 }
 
 func ScanForMissingUpdates(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) map[string]IncompleteTitle {
-	result := map[string]IncompleteTitle{}
+	return ScanForMissingUpdatesWithWorkers(localDB, switchDB, 1)
+}
 
+// ScanForMissingUpdatesWithWorkers is ScanForMissingUpdates, spread over the given number of workers
+func ScanForMissingUpdatesWithWorkers(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, workers int) map[string]IncompleteTitle {
 	//iterate over local files, and compare to remote versions
-	for idPrefix, switchFile := range localDB {
+	return scanTitles(localDB, workers, func(idPrefix string, switchFile *db.SwitchFile, result map[string]IncompleteTitle) {
 
-		if switchFile.BaseExist == false {
-			zap.S().Infof("!Missing base " + switchDB[idPrefix].Attributes.Name + " " + switchDB[idPrefix].Attributes.Id)
-			continue
+		//orphan updates (base unknown to the DB) are reported by ScanForOrphanUpdates
+		if _, ok := switchDB[idPrefix]; !ok {
+			return
 		}
 
-		if _, ok := switchDB[idPrefix]; !ok {
-			continue
+		if switchFile.BaseExist == false {
+			zap.S().Infof("!Missing base " + switchDB[idPrefix].Attributes.Name + " " + switchDB[idPrefix].Attributes.Id)
+			return
 		}
 
 		switchTitle := IncompleteTitle{Attributes: switchDB[idPrefix].Attributes, Meta: switchFile.File.Metadata}
@@ -61,13 +79,13 @@ func ScanForMissingUpdates(localDB map[string]*db.SwitchFile, switchDB map[strin
 		if len(remoteVersions) != 0 {
 			switchTitle.LatestUpdate = remoteVersions[len(remoteVersions)-1]
 			switchTitle.LatestUpdateDate = switchDB[idPrefix].Updates[remoteVersions[len(remoteVersions)-1]]
-			if switchTitle.LocalUpdate < switchTitle.LatestUpdate {
+			if switchTitle.LocalUpdate < switchTitle.LatestUpdate && !isBaseVersion(switchTitle.LatestUpdate, switchFile) {
 				result[switchDB[idPrefix].Attributes.Id] = switchTitle
 			}
 		}
 
 		if len(switchDB[idPrefix].Dlc) == 0 {
-			continue
+			return
 		}
 
 		//process dlc
@@ -82,8 +100,10 @@ func ScanForMissingUpdates(localDB map[string]*db.SwitchFile, switchDB map[strin
 					continue
 				}
 				if localDlc.Metadata.Version < int(latestDlcVersion) {
-					updateDate := strconv.Itoa(availableDlc.ReleaseDate)
-					updateDate = updateDate[0:4] + "-" + updateDate[4:6] + "-" + updateDate[6:]
+					updateDate := ""
+					if availableDlc.ReleaseDate != 0 {
+						updateDate = FormatDate(strconv.Itoa(availableDlc.ReleaseDate), settings.DATE_FORMAT_ISO8601)
+					}
 					result[availableDlc.Id] = IncompleteTitle{
 						Attributes:       availableDlc,
 						LatestUpdate:     int(latestDlcVersion),
@@ -93,38 +113,182 @@ func ScanForMissingUpdates(localDB map[string]*db.SwitchFile, switchDB map[strin
 				}
 			}
 		}
+	})
+}
 
+// SortedIncompleteTitles returns the result of ScanForMissingUpdates / ScanForMissingDLC ordered by name and titleId
+func SortedIncompleteTitles(incompleteTitles map[string]IncompleteTitle) []IncompleteTitle {
+	result := make([]IncompleteTitle, 0, len(incompleteTitles))
+	for _, v := range incompleteTitles {
+		result = append(result, v)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Attributes.Name == result[j].Attributes.Name {
+			return result[i].Attributes.Id < result[j].Attributes.Id
+		}
+		return result[i].Attributes.Name < result[j].Attributes.Name
+	})
 	return result
 }
 
+type UpdateGapBucket struct {
+	Label string `json:"label"`
+	Min   int    `json:"min"`
+	Max   int    `json:"max"` // 0 means no upper bound
+	Count int    `json:"count"`
+}
+
+// update versions are released in steps of 0x10000 (v65536, v131072, ...)
+const updateVersionStep = 0x10000
+
+// UpdatesBehind returns how many update releases the local version is behind the latest one
+func (t IncompleteTitle) UpdatesBehind() int {
+	gap := (t.LatestUpdate - t.LocalUpdate) / updateVersionStep
+	if gap < 1 && t.LatestUpdate > t.LocalUpdate {
+		gap = 1
+	}
+	return gap
+}
+
+// GroupMissingUpdatesByGap buckets the result of ScanForMissingUpdates by how far behind each title is
+func GroupMissingUpdatesByGap(missingUpdates map[string]IncompleteTitle) []UpdateGapBucket {
+	buckets := []UpdateGapBucket{
+		{Label: "1 behind", Min: 1, Max: 1},
+		{Label: "2-5 behind", Min: 2, Max: 5},
+		{Label: "6+ behind", Min: 6, Max: 0},
+	}
+	for _, title := range missingUpdates {
+		gap := title.UpdatesBehind()
+		for i := range buckets {
+			if gap >= buckets[i].Min && (buckets[i].Max == 0 || gap <= buckets[i].Max) {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+// IsAlmostComplete reports whether at least thresholdPercent of the title's DLC are owned, but not all of them
+func (i IncompleteTitle) IsAlmostComplete(thresholdPercent int) bool {
+	if thresholdPercent <= 0 || i.TotalDLC == 0 || i.OwnedDLC >= i.TotalDLC {
+		return false
+	}
+	return i.OwnedDLC*100 >= thresholdPercent*i.TotalDLC
+}
+
 func ScanForMissingDLC(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) map[string]IncompleteTitle {
-	result := map[string]IncompleteTitle{}
+	return ScanForMissingDLCWithWorkers(localDB, switchDB, 1)
+}
 
+// ScanForMissingDLCWithWorkers is ScanForMissingDLC, spread over the given number of workers
+func ScanForMissingDLCWithWorkers(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, workers int) map[string]IncompleteTitle {
 	//iterate over local files, and compare to remote versions
-	for idPrefix, switchFile := range localDB {
+	return scanTitles(localDB, workers, func(idPrefix string, switchFile *db.SwitchFile, result map[string]IncompleteTitle) {
 
 		if switchFile.BaseExist == false {
-			continue
+			return
 		}
 
 		if _, ok := switchDB[idPrefix]; !ok {
-			continue
+			return
 		}
 		switchTitle := IncompleteTitle{Attributes: switchDB[idPrefix].Attributes}
 
 		//process dlc
 		if len(switchDB[idPrefix].Dlc) != 0 {
-			for k, v := range switchDB[idPrefix].Dlc {
-				if _, ok := switchFile.Dlc[k]; !ok {
-					switchTitle.MissingDLC = append(switchTitle.MissingDLC, fmt.Sprintf("%v [%v]", v.Name, v.Id))
+			for _, dlc := range DLCCatalog(switchFile, switchDB[idPrefix]) {
+				if !dlc.Owned {
+					switchTitle.MissingDLC = append(switchTitle.MissingDLC, fmt.Sprintf("%v [%v]", dlc.Attributes.Name, dlc.Attributes.Id))
+					switchTitle.MissingDLCIds = append(switchTitle.MissingDLCIds, strings.ToLower(dlc.Attributes.Id))
 				}
 			}
+			switchTitle.TotalDLC = len(switchDB[idPrefix].Dlc)
+			switchTitle.OwnedDLC = switchTitle.TotalDLC - len(switchTitle.MissingDLC)
 			if len(switchTitle.MissingDLC) != 0 {
 				result[switchDB[idPrefix].Attributes.Id] = switchTitle
 			}
 		}
+	})
+}
+
+// ScanForOrphanUpdates lists the local update files whose base application is not found in the titles DB (e.g. delisted titles)
+func ScanForOrphanUpdates(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) []db.ExtendedFileInfo {
+	var result []db.ExtendedFileInfo
+	for idPrefix, switchFile := range localDB {
+		if _, ok := switchDB[idPrefix]; ok {
+			continue
+		}
+		for _, f := range switchFile.Updates {
+			if switchFile.BaseExist && f.Info == switchFile.File.Info {
+				continue
+			}
+			result = append(result, f)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Info.Name() < result[j].Info.Name()
+	})
+	return result
+}
+
+// ScanForOrphanDLC lists the local DLC files whose base game is not owned, split between the ones whose base game
+// is in the titles DB, and the ones whose base game is unknown to it
+func ScanForOrphanDLC(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) (baseNotOwned []db.ExtendedFileInfo, baseUnknown []db.ExtendedFileInfo) {
+	for idPrefix, switchFile := range localDB {
+		if switchFile.BaseExist {
+			continue
+		}
+		_, known := switchDB[idPrefix]
+		for _, f := range switchFile.Dlc {
+			if known {
+				baseNotOwned = append(baseNotOwned, f)
+			} else {
+				baseUnknown = append(baseUnknown, f)
+			}
+		}
+	}
+	for _, files := range [][]db.ExtendedFileInfo{baseNotOwned, baseUnknown} {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Info.Name() < files[j].Info.Name()
+		})
+	}
+	return baseNotOwned, baseUnknown
+}
+
+type DlcRequiringUpdate struct {
+	Dlc                 db.ExtendedFileInfo
+	RequiredBaseVersion int
+	LocalBaseVersion    int
+}
+
+// ScanForDLCRequiringUpdate lists owned DLC requiring a newer base game update than the one present locally.
+// the requirement is only known for deep scanned files, other DLC are skipped.
+func ScanForDLCRequiringUpdate(localDB map[string]*db.SwitchFile) []DlcRequiringUpdate {
+	var result []DlcRequiringUpdate
+	for _, switchFile := range localDB {
+		if switchFile.BaseExist == false {
+			continue
+		}
+		localVersion := 0
+		for version := range switchFile.Updates {
+			if version > localVersion {
+				localVersion = version
+			}
+		}
+		for _, dlc := range switchFile.Dlc {
+			if dlc.Metadata == nil || dlc.Metadata.RequiredApplicationVersion == 0 {
+				continue
+			}
+			if dlc.Metadata.RequiredApplicationVersion > localVersion {
+				result = append(result, DlcRequiringUpdate{Dlc: dlc,
+					RequiredBaseVersion: dlc.Metadata.RequiredApplicationVersion, LocalBaseVersion: localVersion})
+			}
+		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Dlc.Metadata.TitleId < result[j].Dlc.Metadata.TitleId
+	})
 	return result
 }
 
@@ -143,5 +307,8 @@ func ScanForBrokenFiles(localDB map[string]*db.SwitchFile) []db.ExtendedFileInfo
 			}
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Info.Name() < result[j].Info.Name()
+	})
 	return result
 }
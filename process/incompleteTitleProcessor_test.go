@@ -0,0 +1,316 @@
+package process
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestGroupMissingUpdatesByGap(t *testing.T) {
+	tests := []struct {
+		name         string
+		localUpdate  int
+		latestUpdate int
+		wantBehind   int
+		wantBucket   string
+	}{
+		{"one release behind", 65536, 131072, 1, "1 behind"},
+		{"no update installed", 0, 65536, 1, "1 behind"},
+		{"less than a release step", 65536, 65537, 1, "1 behind"},
+		{"two releases behind", 0, 131072, 2, "2-5 behind"},
+		{"five releases behind", 65536, 6 * 65536, 5, "2-5 behind"},
+		{"six releases behind", 0, 6 * 65536, 6, "6+ behind"},
+		{"far behind", 0, 40 * 65536, 40, "6+ behind"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			title := IncompleteTitle{LocalUpdate: test.localUpdate, LatestUpdate: test.latestUpdate}
+			if got := title.UpdatesBehind(); got != test.wantBehind {
+				t.Errorf("got %v updates behind, want %v", got, test.wantBehind)
+			}
+			for _, bucket := range GroupMissingUpdatesByGap(map[string]IncompleteTitle{"0100000000010000": title}) {
+				want := 0
+				if bucket.Label == test.wantBucket {
+					want = 1
+				}
+				if bucket.Count != want {
+					t.Errorf("bucket [%v] has %v titles, want %v", bucket.Label, bucket.Count, want)
+				}
+			}
+		})
+	}
+
+	buckets := GroupMissingUpdatesByGap(map[string]IncompleteTitle{
+		"0100000000010000": {LocalUpdate: 0, LatestUpdate: 65536},
+		"0100000000020000": {LocalUpdate: 65536, LatestUpdate: 131072},
+		"0100000000030000": {LocalUpdate: 0, LatestUpdate: 3 * 65536},
+		"0100000000040000": {LocalUpdate: 0, LatestUpdate: 10 * 65536},
+	})
+	var counts []int
+	for _, bucket := range buckets {
+		counts = append(counts, bucket.Count)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 1, 1}) {
+		t.Errorf("got bucket counts %v, want [2 1 1]", counts)
+	}
+}
+
+func TestScanForOrphanUpdates(t *testing.T) {
+	folder := newTestFolder(t)
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Known"},
+			Updates: map[int]string{65536: "2020-01-01", 131072: "2020-02-01"}},
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {File: newTestFile(t, folder, "known.nsp", "0100000000010000", 0), BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "known v1.nsp", "0100000000010800", 65536)}},
+		//a delisted title, only its update is owned
+		"010000000009": {Updates: map[int]db.ExtendedFileInfo{
+			65536: newTestFile(t, folder, "delisted v1.nsp", "0100000000090800", 65536),
+		}},
+		//a delisted title with its base game
+		"010000000008": {File: newTestFile(t, folder, "other.nsp", "0100000000080000", 0), BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{131072: newTestFile(t, folder, "other v2.nsp", "0100000000080800", 131072)}},
+	}
+
+	var got []string
+	for _, f := range ScanForOrphanUpdates(localDB, switchDB) {
+		got = append(got, f.Info.Name())
+	}
+	if want := []string{"delisted v1.nsp", "other v2.nsp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got orphan updates %v, want %v", got, want)
+	}
+	//the missing updates report only has the titles known to the DB
+	missing := ScanForMissingUpdates(localDB, switchDB)
+	if _, ok := missing["0100000000010000"]; len(missing) != 1 || !ok {
+		t.Errorf("got missing updates %v, want only the known title", missing)
+	}
+}
+
+func TestScanForDLCRequiringUpdate(t *testing.T) {
+	folder := newTestFolder(t)
+	dlc := func(name string, titleId string, requiredVersion int) db.ExtendedFileInfo {
+		f := newTestFile(t, folder, name, titleId, 0)
+		f.Metadata.RequiredApplicationVersion = requiredVersion
+		return f
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {File: newTestFile(t, folder, "game.nsp", "0100000000010000", 0), BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "game v1.nsp", "0100000000010800", 65536)},
+			Dlc: map[string]db.ExtendedFileInfo{
+				"0100000000011002": dlc("needs v2.nsp", "0100000000011002", 131072),
+				"0100000000011001": dlc("needs v1.nsp", "0100000000011001", 65536),
+				//tagged only files have no requirement
+				"0100000000011003": dlc("unknown.nsp", "0100000000011003", 0),
+			}},
+		"010000000002": {File: newTestFile(t, folder, "other.nsp", "0100000000020000", 0), BaseExist: true,
+			Dlc: map[string]db.ExtendedFileInfo{"0100000000021001": dlc("needs an update.nsp", "0100000000021001", 65536)}},
+		//without the base game, there is no update to compare with
+		"010000000003": {Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{
+			"0100000000031001": dlc("no base.nsp", "0100000000031001", 65536),
+		}},
+	}
+
+	var got [][]interface{}
+	for _, requiring := range ScanForDLCRequiringUpdate(localDB) {
+		got = append(got, []interface{}{requiring.Dlc.Info.Name(), requiring.RequiredBaseVersion, requiring.LocalBaseVersion})
+	}
+	want := [][]interface{}{{"needs v2.nsp", 131072, 65536}, {"needs an update.nsp", 65536, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIsAlmostComplete(t *testing.T) {
+	tests := []struct {
+		name      string
+		owned     int
+		total     int
+		threshold int
+		want      bool
+	}{
+		{"missing 1 of 10", 9, 10, 90, true},
+		{"just below the threshold", 8, 10, 90, false},
+		{"missing 1 of 3", 2, 3, 66, true},
+		{"missing 1 of 3 with a higher threshold", 2, 3, 67, false},
+		{"all owned", 10, 10, 90, false},
+		{"none owned", 0, 10, 90, false},
+		{"no DLC", 0, 0, 90, false},
+		{"highlight off", 9, 10, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			title := IncompleteTitle{OwnedDLC: test.owned, TotalDLC: test.total}
+			if got := title.IsAlmostComplete(test.threshold); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanForMissingUpdatesIgnoresBaseVersions(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseVersion   int
+		localUpdates  []int
+		remoteUpdates []int
+		wantMissing   bool
+	}{
+		{"missing update", 0, nil, []int{65536}, true},
+		{"latest update is the base version", 0, nil, []int{0}, false},
+		{"latest update included in the base", 131072, nil, []int{65536, 131072}, false},
+		{"update newer than the base", 65536, nil, []int{65536, 131072}, true},
+		{"latest update owned", 0, []int{65536}, []int{65536}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			switchFile := &db.SwitchFile{BaseExist: true, Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{},
+				File: db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000010000", Version: test.baseVersion}}}
+			for _, version := range test.localUpdates {
+				switchFile.Updates[version] = db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000010800", Version: version}}
+			}
+			switchTitle := &db.SwitchTitle{Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}, Updates: map[int]string{}}
+			for _, version := range test.remoteUpdates {
+				switchTitle.Updates[version] = "2020-01-01"
+			}
+			missing := ScanForMissingUpdates(map[string]*db.SwitchFile{"010000000001": switchFile},
+				map[string]*db.SwitchTitle{"010000000001": switchTitle})
+			if _, got := missing["0100000000010000"]; got != test.wantMissing {
+				t.Errorf("got missing update %v, want %v", got, test.wantMissing)
+			}
+		})
+	}
+}
+
+// workersTestLibrary is a library of titles missing updates, DLC, both or nothing (in equal shares), with its titles DB
+func workersTestLibrary(titles int) (map[string]*db.SwitchFile, map[string]*db.SwitchTitle) {
+	localDB := map[string]*db.SwitchFile{}
+	switchDB := map[string]*db.SwitchTitle{}
+	for i := 0; i < titles; i++ {
+		idPrefix := fmt.Sprintf("0100%08x", i)
+		titleId := idPrefix + "0000"
+		dlc := []string{idPrefix + "1001", idPrefix + "1002"}
+		switchDB[idPrefix] = dbTitle(titleId, fmt.Sprintf("Game %v", i), "US", []int{65536, 131072}, dlc...)
+		switch i % 4 {
+		case 0:
+			localDB[idPrefix] = localTitle(titleId, true, []int{131072}, dlc...)
+		case 1:
+			localDB[idPrefix] = localTitle(titleId, true, []int{65536}, dlc...)
+		case 2:
+			localDB[idPrefix] = localTitle(titleId, true, []int{131072}, dlc[0])
+		case 3:
+			localDB[idPrefix] = localTitle(titleId, true, nil)
+		}
+	}
+	return localDB, switchDB
+}
+
+func TestScanResultsDoNotDependOnTheWorkers(t *testing.T) {
+	localDB, switchDB := workersTestLibrary(64)
+	wantUpdates := ScanForMissingUpdatesWithWorkers(localDB, switchDB, 1)
+	wantDLC := ScanForMissingDLCWithWorkers(localDB, switchDB, 1)
+	if len(wantUpdates) != 32 || len(wantDLC) != 32 {
+		t.Fatalf("got %v titles missing updates and %v missing DLC, want 32 and 32", len(wantUpdates), len(wantDLC))
+	}
+
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{"one per CPU", 0},
+		{"two", 2},
+		{"eight", 8},
+		{"more workers than titles", 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ScanForMissingUpdatesWithWorkers(localDB, switchDB, test.workers); !reflect.DeepEqual(got, wantUpdates) {
+				t.Errorf("missing updates differ from a single worker: %v", sortedKeys(got))
+			}
+			if got := ScanForMissingDLCWithWorkers(localDB, switchDB, test.workers); !reflect.DeepEqual(got, wantDLC) {
+				t.Errorf("missing DLC differ from a single worker: %v", sortedKeys(got))
+			}
+		})
+	}
+}
+
+// BenchmarkScanWithWorkers compares scanning a large library for missing updates and DLC with a single worker
+// (the serial scan) and with more workers. the speedup is bounded by the available CPUs (go test -bench . -cpu 1,4)
+func BenchmarkScanWithWorkers(b *testing.B) {
+	localDB, switchDB := workersTestLibrary(20000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("updates/workers=%v", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ScanForMissingUpdatesWithWorkers(localDB, switchDB, workers)
+			}
+		})
+		b.Run(fmt.Sprintf("dlc/workers=%v", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ScanForMissingDLCWithWorkers(localDB, switchDB, workers)
+			}
+		})
+	}
+}
+
+func fileNames(files []db.ExtendedFileInfo) []string {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Info.Name())
+	}
+	return names
+}
+
+func TestScanForOrphanDLC(t *testing.T) {
+	folder := newTestFolder(t)
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Owned", "US", nil, "0100000000011001"),
+		"010000000002": dbTitle("0100000000020000", "Not owned", "US", nil, "0100000000021001", "0100000000021002"),
+	}
+	dlcOnly := func(files ...db.ExtendedFileInfo) *db.SwitchFile {
+		switchFile := &db.SwitchFile{Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}}
+		for _, f := range files {
+			switchFile.Dlc[f.Metadata.TitleId] = f
+		}
+		return switchFile
+	}
+	owned := localTitle("0100000000010000", true, nil)
+	owned.Dlc["0100000000011001"] = newTestFile(t, folder, "owned dlc.nsp", "0100000000011001", 0)
+
+	tests := []struct {
+		name             string
+		localDB          map[string]*db.SwitchFile
+		wantBaseNotOwned []string
+		wantBaseUnknown  []string
+	}{
+		{"base game owned", map[string]*db.SwitchFile{"010000000001": owned}, nil, nil},
+		{"base game in the DB but not owned", map[string]*db.SwitchFile{
+			"010000000002": dlcOnly(newTestFile(t, folder, "b dlc.nsp", "0100000000021002", 0),
+				newTestFile(t, folder, "a dlc.nsp", "0100000000021001", 0)),
+		}, []string{"a dlc.nsp", "b dlc.nsp"}, nil},
+		{"base game unknown to the DB", map[string]*db.SwitchFile{
+			"010000000009": dlcOnly(newTestFile(t, folder, "unknown dlc.nsp", "0100000000091001", 0)),
+		}, nil, []string{"unknown dlc.nsp"}},
+		{"both in one library", map[string]*db.SwitchFile{
+			"010000000001": owned,
+			"010000000002": dlcOnly(newTestFile(t, folder, "a dlc.nsp", "0100000000021001", 0)),
+			"010000000009": dlcOnly(newTestFile(t, folder, "unknown dlc.nsp", "0100000000091001", 0)),
+		}, []string{"a dlc.nsp"}, []string{"unknown dlc.nsp"}},
+		{"update without its base game", map[string]*db.SwitchFile{
+			"010000000002": localTitle("0100000000020000", false, []int{65536}),
+		}, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			baseNotOwned, baseUnknown := ScanForOrphanDLC(test.localDB, switchDB)
+			if got := fileNames(baseNotOwned); !reflect.DeepEqual(got, test.wantBaseNotOwned) {
+				t.Errorf("base not owned: got %v, want %v", got, test.wantBaseNotOwned)
+			}
+			if got := fileNames(baseUnknown); !reflect.DeepEqual(got, test.wantBaseUnknown) {
+				t.Errorf("base unknown: got %v, want %v", got, test.wantBaseUnknown)
+			}
+		})
+	}
+}
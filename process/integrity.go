@@ -0,0 +1,177 @@
+package process
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// each hashing worker streams its files through a single buffer of this size,
+// so memory use is bounded by the concurrency regardless of the file sizes
+const hashBufferSize = 1024 * 1024
+
+type FileHash struct {
+	Path string
+	Hash string
+	Err  error
+}
+
+// HashFiles computes the sha256 of the given files, hashing at most concurrency files at a time
+func HashFiles(paths []string, concurrency int) []FileHash {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	result := make([]FileHash, len(paths))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer := make([]byte, hashBufferSize)
+			for i := range jobs {
+				hash, err := hashFile(paths[i], buffer)
+				result[i] = FileHash{Path: paths[i], Hash: hash, Err: err}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return result
+}
+
+func hashFile(path string, buffer []byte) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	//hide the file's WriterTo, so the copy goes through the given buffer
+	_, err = io.CopyBuffer(hash, struct{ io.Reader }{file}, buffer)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type IntegrityRecord struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+type IntegrityMismatch struct {
+	Path         string
+	RecordedHash string
+	ActualHash   string
+}
+
+// LoadIntegrityRecords reads the hashes recorded by a previous integrity check, keyed by file path
+func LoadIntegrityRecords(filePath string) (map[string]IntegrityRecord, error) {
+	records := map[string]IntegrityRecord{}
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func SaveIntegrityRecords(filePath string, records map[string]IntegrityRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+// VerifyIntegrity hashes all the local files, and reports the files whose content changed even though their
+// size and modification time did not (a sign of corruption). the records are updated with the new hashes.
+func VerifyIntegrity(localDB map[string]*db.SwitchFile, records map[string]IntegrityRecord, concurrency int) ([]IntegrityMismatch, []FileHash) {
+	paths, infos := localFilePaths(localDB)
+	var mismatches []IntegrityMismatch
+	var failures []FileHash
+	for _, fileHash := range HashFiles(paths, concurrency) {
+		if fileHash.Err != nil {
+			failures = append(failures, fileHash)
+			continue
+		}
+		info := infos[fileHash.Path]
+		record, ok := records[fileHash.Path]
+		if ok && record.Size == info.Size() && record.ModTime.Equal(info.ModTime()) && record.Hash != fileHash.Hash {
+			mismatches = append(mismatches, IntegrityMismatch{Path: fileHash.Path, RecordedHash: record.Hash, ActualHash: fileHash.Hash})
+		}
+		records[fileHash.Path] = IntegrityRecord{Size: info.Size(), ModTime: info.ModTime(), Hash: fileHash.Hash}
+	}
+	return mismatches, failures
+}
+
+// the sorted paths of the local files, with their file info
+func localFilePaths(localDB map[string]*db.SwitchFile) ([]string, map[string]os.FileInfo) {
+	var paths []string
+	infos := map[string]os.FileInfo{}
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			path := filepath.Join(f.BaseFolder, f.Info.Name())
+			if _, ok := infos[path]; ok {
+				//archives hold several titles
+				continue
+			}
+			infos[path] = f.Info
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, infos
+}
+
+const HASH_SIDECAR_EXTENSION = ".sha256"
+
+// WriteHashSidecars writes a <file>.sha256 file (in the sha256sum format) next to each local file, with the hash
+// recorded by VerifyIntegrity. sidecars already holding the hash are left as is. returns the number of sidecars
+// written, and the files whose sidecar could not be written.
+func WriteHashSidecars(localDB map[string]*db.SwitchFile, records map[string]IntegrityRecord) (int, []FileHash) {
+	paths, _ := localFilePaths(localDB)
+	written := 0
+	var failures []FileHash
+	for _, path := range paths {
+		record, ok := records[path]
+		if !ok {
+			continue
+		}
+		content := []byte(hashSidecarLine(record.Hash, filepath.Base(path)))
+		sidecarPath := path + HASH_SIDECAR_EXTENSION
+		if existing, err := ioutil.ReadFile(sidecarPath); err == nil && string(existing) == string(content) {
+			continue
+		}
+		if err := ioutil.WriteFile(sidecarPath, content, 0644); err != nil {
+			failures = append(failures, FileHash{Path: path, Hash: record.Hash, Err: err})
+			continue
+		}
+		written++
+	}
+	return written, failures
+}
+
+// the sha256sum line of a file, so that the sidecar can be checked with 'sha256sum -c'
+func hashSidecarLine(hash string, fileName string) string {
+	return hash + "  " + fileName + "\n"
+}
@@ -0,0 +1,140 @@
+package process
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+// writeHashedFile writes size bytes to the folder, and returns the file path with its expected sha256
+func writeHashedFile(t testing.TB, folder string, name string, size int) (string, string) {
+	t.Helper()
+	content := bytes.Repeat([]byte{byte(len(name))}, size)
+	filePath := filepath.Join(folder, name)
+	if err := ioutil.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return filePath, hex.EncodeToString(sum[:])
+}
+
+func TestHashFiles(t *testing.T) {
+	folder := newTestFolder(t)
+	var paths []string
+	expected := map[string]string{}
+	for i, size := range []int{0, 1, hashBufferSize - 1, hashBufferSize, 3*hashBufferSize + 7} {
+		filePath, hash := writeHashedFile(t, folder, fmt.Sprintf("file%v.nsp", i), size)
+		paths = append(paths, filePath)
+		expected[filePath] = hash
+	}
+	missing := filepath.Join(folder, "missing.nsp")
+	paths = append(paths, missing)
+
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{"invalid concurrency", 0},
+		{"single worker", 1},
+		{"fewer workers than files", 2},
+		{"more workers than files", 16},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := HashFiles(paths, test.concurrency)
+			if len(result) != len(paths) {
+				t.Fatalf("got %v hashes, want %v", len(result), len(paths))
+			}
+			for i, fileHash := range result {
+				if fileHash.Path != paths[i] {
+					t.Errorf("hash %v is of %v, want %v", i, fileHash.Path, paths[i])
+				}
+				if fileHash.Path == missing {
+					if fileHash.Err == nil {
+						t.Errorf("expected an error hashing the missing file")
+					}
+					continue
+				}
+				if fileHash.Err != nil || fileHash.Hash != expected[fileHash.Path] {
+					t.Errorf("%v: got %v (%v), want %v", fileHash.Path, fileHash.Hash, fileHash.Err, expected[fileHash.Path])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHashFiles reports the memory allocated to hash the files, which should grow with the concurrency
+// and not with the file sizes
+func BenchmarkHashFiles(b *testing.B) {
+	folder := newTestFolder(b)
+	for _, size := range []int{hashBufferSize, 16 * hashBufferSize} {
+		var paths []string
+		for i := 0; i < 8; i++ {
+			filePath, _ := writeHashedFile(b, folder, fmt.Sprintf("%v-%v.nsp", size, i), size)
+			paths = append(paths, filePath)
+		}
+		for _, concurrency := range []int{1, 4} {
+			b.Run(fmt.Sprintf("size=%vMB/concurrency=%v", size/hashBufferSize, concurrency), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(size * len(paths)))
+				for i := 0; i < b.N; i++ {
+					HashFiles(paths, concurrency)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteHashSidecars(t *testing.T) {
+	folder := newTestFolder(t)
+	basePath, baseHash := writeHashedFile(t, folder, "base.nsp", 100)
+	updatePath, updateHash := writeHashedFile(t, folder, "v1.nsp", 200)
+	localDB := map[string]*db.SwitchFile{"010000000001": {
+		File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+		BaseExist: true,
+		Updates:   map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536)},
+	}}
+	records := map[string]IntegrityRecord{}
+	if _, failures := VerifyIntegrity(localDB, records, 2); len(failures) != 0 {
+		t.Fatalf("got hash failures %v", failures)
+	}
+
+	readSidecar := func(path string) string {
+		content, err := ioutil.ReadFile(path + HASH_SIDECAR_EXTENSION)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	if written, failures := WriteHashSidecars(localDB, records); written != 2 || len(failures) != 0 {
+		t.Fatalf("got %v sidecars written (failures %v), want 2", written, failures)
+	}
+	for path, hash := range map[string]string{basePath: baseHash, updatePath: updateHash} {
+		if got, want := readSidecar(path), hash+"  "+filepath.Base(path)+"\n"; got != want {
+			t.Errorf("got sidecar %q, want %q", got, want)
+		}
+	}
+
+	//up to date sidecars are not written again, stale ones are
+	if err := ioutil.WriteFile(updatePath+HASH_SIDECAR_EXTENSION, []byte("stale  v1.nsp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if written, _ := WriteHashSidecars(localDB, records); written != 1 {
+		t.Errorf("got %v sidecars written, want only the stale one", written)
+	}
+	if got, want := readSidecar(updatePath), updateHash+"  v1.nsp\n"; got != want {
+		t.Errorf("got sidecar %q, want %q", got, want)
+	}
+
+	//files without a recorded hash get no sidecar
+	localDB["010000000001"].Dlc = map[string]db.ExtendedFileInfo{"0100000000011001": newTestFile(t, folder, "dlc.nsp", "0100000000011001", 0)}
+	if written, _ := WriteHashSidecars(localDB, records); written != 0 || fileExists(filepath.Join(folder, "dlc.nsp"+HASH_SIDECAR_EXTENSION)) {
+		t.Errorf("got %v sidecars written, want none for the unhashed file", written)
+	}
+}
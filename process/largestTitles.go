@@ -0,0 +1,55 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+)
+
+type TitleSize struct {
+	TitleId string
+	Name    string
+	Size    int64
+}
+
+// LocalTitleSize sums the size of the base, update and DLC files of a title
+func LocalTitleSize(switchFile *db.SwitchFile) int64 {
+	var size int64
+	seen := map[string]bool{}
+	for _, f := range switchFile.Files() {
+		path := filepath.Join(f.BaseFolder, f.Info.Name())
+		//archives hold several files of the same title
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		size += f.Info.Size()
+	}
+	return size
+}
+
+// FindLargestTitles lists the n local titles taking the most space, largest first
+func FindLargestTitles(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, n int) []TitleSize {
+	var result []TitleSize
+	for idPrefix, switchFile := range localDB {
+		titleSize := TitleSize{Size: LocalTitleSize(switchFile)}
+		if switchFile.BaseExist && switchFile.File.Metadata != nil {
+			titleSize.TitleId = switchFile.File.Metadata.TitleId
+		}
+		if switchTitle, ok := switchDB[idPrefix]; ok {
+			titleSize.Name = switchTitle.Attributes.Name
+			titleSize.TitleId = switchTitle.Attributes.Id
+		}
+		result = append(result, titleSize)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size == result[j].Size {
+			return result[i].TitleId < result[j].TitleId
+		}
+		return result[i].Size > result[j].Size
+	})
+	if n >= 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
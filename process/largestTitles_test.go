@@ -0,0 +1,63 @@
+package process
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestFindLargestTitles(t *testing.T) {
+	folder := newTestFolder(t)
+	sizedFile := func(name string, titleId string, version int, size int) db.ExtendedFileInfo {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return newTestFile(t, folder, name, titleId, version)
+	}
+	localDB := map[string]*db.SwitchFile{
+		//the base, update and DLC sizes add up
+		"010000000001": {File: sizedFile("small.nsp", "0100000000010000", 0, 30), BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{65536: sizedFile("small_v1.nsp", "0100000000010800", 65536, 20)},
+			Dlc:     map[string]db.ExtendedFileInfo{"0100000000011001": sizedFile("small_dlc.nsp", "0100000000011001", 0, 10)}},
+		"010000000002": {File: sizedFile("large.nsp", "0100000000020000", 0, 100), BaseExist: true},
+		"010000000003": {File: sizedFile("medium.nsp", "0100000000030000", 0, 80), BaseExist: true},
+		//an archive holding the base and its update is counted once
+		"010000000004": {File: sizedFile("archive.xci", "0100000000040000", 0, 90), BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "archive.xci", "0100000000040800", 65536)}},
+	}
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Small"}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Large"}},
+	}
+	tests := []struct {
+		name string
+		n    int
+		want []TitleSize
+	}{
+		{"all titles", 10, []TitleSize{
+			{TitleId: "0100000000020000", Name: "Large", Size: 100},
+			{TitleId: "0100000000040000", Size: 90},
+			{TitleId: "0100000000030000", Size: 80},
+			{TitleId: "0100000000010000", Name: "Small", Size: 60},
+		}},
+		{"top 2", 2, []TitleSize{
+			{TitleId: "0100000000020000", Name: "Large", Size: 100},
+			{TitleId: "0100000000040000", Size: 90},
+		}},
+		{"none", 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := FindLargestTitles(localDB, switchDB, test.n)
+			if len(got) == 0 && len(test.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package process
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type OwnedItem struct {
+	TitleId string `json:"title_id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Region  string `json:"region"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+}
+
+var ownedItemCSVHeader = []string{"title_id", "name", "type", "version", "region", "path", "size"}
+
+// ExportLibrary lists every local file (base, updates and DLC), with the names taken from the titles DB when known
+func ExportLibrary(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) []OwnedItem {
+	var result []OwnedItem
+	for k, switchFile := range localDB {
+		title := switchDB[k]
+		for _, f := range switchFile.Files() {
+			if f.Metadata == nil {
+				continue
+			}
+			titleId := strings.ToLower(f.Metadata.TitleId)
+			item := OwnedItem{
+				TitleId: titleId,
+				Type:    f.Metadata.Type,
+				Version: f.Metadata.Version,
+				Region:  f.Region,
+				Path:    filepath.Join(f.BaseFolder, f.Info.Name()),
+				Size:    f.Info.Size(),
+			}
+			if title != nil {
+				item.Name = title.Attributes.Name
+				if dlc, ok := title.Dlc[titleId]; ok {
+					item.Name = dlc.Name
+				}
+				if item.Region == "" {
+					item.Region = title.Attributes.Region
+				}
+			}
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TitleId == result[j].TitleId {
+			return result[i].Version < result[j].Version
+		}
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+// WriteLibraryCSV writes the exported items as csv, with a header line
+func WriteLibraryCSV(writer io.Writer, items []OwnedItem) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(ownedItemCSVHeader)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		err = csvWriter.Write([]string{item.TitleId, item.Name, item.Type, strconv.Itoa(item.Version), item.Region,
+			item.Path, strconv.FormatInt(item.Size, 10)})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteLibraryJSON writes the exported items as a json list
+func WriteLibraryJSON(writer io.Writer, items []OwnedItem) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(items)
+}
@@ -0,0 +1,76 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestExportLibrary(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	file := func(name string, titleId string, version int, metaType string, size int, region string) db.ExtendedFileInfo {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.ExtendedFileInfo{Info: info, BaseFolder: folder, Region: region,
+			Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version, Type: metaType}}
+	}
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game", Region: "US"},
+			Dlc: map[string]db.TitleAttributes{"0100000000011001": {Id: "0100000000011001", Name: "Game, the DLC"}}},
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {
+			File:      file("game.nsp", "0100000000010000", 0, "BASE", 3, ""),
+			BaseExist: true,
+			Updates:   map[int]db.ExtendedFileInfo{65536: file("game v1.nsp", "0100000000010800", 65536, "UPD", 2, "EU")},
+			Dlc:       map[string]db.ExtendedFileInfo{"0100000000011001": file("game dlc.nsp", "0100000000011001", 0, "DLC", 1, "")},
+		},
+		//unknown to the titles DB
+		"010000000002": {File: file("other.nsp", "0100000000020000", 0, "BASE", 4, ""), BaseExist: true},
+	}
+
+	items := ExportLibrary(localDB, switchDB)
+	want := []OwnedItem{
+		{"0100000000010000", "Game", "BASE", 0, "US", filepath.Join(folder, "game.nsp"), 3},
+		{"0100000000010800", "Game", "UPD", 65536, "EU", filepath.Join(folder, "game v1.nsp"), 2},
+		{"0100000000011001", "Game, the DLC", "DLC", 0, "US", filepath.Join(folder, "game dlc.nsp"), 1},
+		{"0100000000020000", "", "BASE", 0, "", filepath.Join(folder, "other.nsp"), 4},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+
+	var csvOutput strings.Builder
+	if err := WriteLibraryCSV(&csvOutput, items[1:3]); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "title_id,name,type,version,region,path,size\n" +
+		"0100000000010800,Game,UPD,65536,EU," + filepath.Join(folder, "game v1.nsp") + ",2\n" +
+		"0100000000011001,\"Game, the DLC\",DLC,0,US," + filepath.Join(folder, "game dlc.nsp") + ",1\n"
+	if csvOutput.String() != wantCSV {
+		t.Errorf("got csv %q, want %q", csvOutput.String(), wantCSV)
+	}
+
+	var jsonOutput strings.Builder
+	if err := WriteLibraryJSON(&jsonOutput, items[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(jsonOutput.String(), `"title_id": "0100000000020000"`) || !strings.Contains(jsonOutput.String(), `"size": 4`) {
+		t.Errorf("got json %q, want the exported item", jsonOutput.String())
+	}
+}
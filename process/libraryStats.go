@@ -0,0 +1,202 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+type LibraryCompletion struct {
+	Owned   int     `json:"owned"`
+	Total   int     `json:"total"`
+	Percent float32 `json:"percent"`
+}
+
+// CalculateCompletion computes how many of the known titles are owned locally, local titles missing from the
+// titles DB are not counted. when countPartial is set, a title with only updates or DLC present (no base) is considered owned.
+func CalculateCompletion(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, countPartial bool) LibraryCompletion {
+	result := LibraryCompletion{Total: len(switchDB)}
+	for idPrefix, switchFile := range localDB {
+		if _, ok := switchDB[idPrefix]; !ok {
+			continue
+		}
+		if switchFile.BaseExist || countPartial {
+			result.Owned++
+		}
+	}
+	if result.Total != 0 {
+		result.Percent = (float32(result.Owned) / float32(result.Total)) * 100
+	}
+	return result
+}
+
+// CalculateStrictCompletion computes how many of the known titles are complete: the base, the latest update
+// and all the DLC are owned locally
+func CalculateStrictCompletion(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) LibraryCompletion {
+	result := LibraryCompletion{Total: len(switchDB)}
+	for idPrefix, switchFile := range localDB {
+		if switchTitle, ok := switchDB[idPrefix]; ok && isCompleteTitle(switchFile, switchTitle) {
+			result.Owned++
+		}
+	}
+	if result.Total != 0 {
+		result.Percent = (float32(result.Owned) / float32(result.Total)) * 100
+	}
+	return result
+}
+
+type CompleteTitle struct {
+	Attributes  db.TitleAttributes
+	LocalUpdate int
+	DLCCount    int
+}
+
+// FindCompleteTitles lists the titles fully owned locally - the base, the latest update and all the DLC -
+// ordered by name
+func FindCompleteTitles(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) []CompleteTitle {
+	var result []CompleteTitle
+	for idPrefix, switchFile := range localDB {
+		switchTitle, ok := switchDB[idPrefix]
+		if !ok || !isCompleteTitle(switchFile, switchTitle) {
+			continue
+		}
+		result = append(result, CompleteTitle{Attributes: switchTitle.Attributes, LocalUpdate: latestLocalVersion(switchFile.Updates),
+			DLCCount: len(switchTitle.Dlc)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Attributes.Name == result[j].Attributes.Name {
+			return result[i].Attributes.Id < result[j].Attributes.Id
+		}
+		return result[i].Attributes.Name < result[j].Attributes.Name
+	})
+	return result
+}
+
+type TitleCompletion struct {
+	Attributes db.TitleAttributes
+	//owned / total items: the base, the latest update (for titles with updates) and each DLC
+	Owned   int
+	Total   int
+	Percent float32
+}
+
+// CalculateTitleCompletion scores each locally owned base game by the share of its base, latest update and DLC
+// owned locally, ordered from the most complete
+func CalculateTitleCompletion(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) []TitleCompletion {
+	var result []TitleCompletion
+	for idPrefix, switchFile := range localDB {
+		switchTitle, ok := switchDB[idPrefix]
+		if !ok || !switchFile.BaseExist {
+			continue
+		}
+		completion := TitleCompletion{Attributes: switchTitle.Attributes, Owned: 1, Total: 1 + len(switchTitle.Dlc)}
+		if latestUpdate := latestAvailableVersion(switchTitle.Updates); latestUpdate > 0 {
+			completion.Total++
+			if latestLocalVersion(switchFile.Updates) >= latestUpdate || isBaseVersion(latestUpdate, switchFile) {
+				completion.Owned++
+			}
+		}
+		for id := range switchTitle.Dlc {
+			if _, ok := switchFile.Dlc[id]; ok {
+				completion.Owned++
+			}
+		}
+		completion.Percent = (float32(completion.Owned) / float32(completion.Total)) * 100
+		result = append(result, completion)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Percent != result[j].Percent {
+			return result[i].Percent > result[j].Percent
+		}
+		if result[i].Attributes.Name != result[j].Attributes.Name {
+			return result[i].Attributes.Name < result[j].Attributes.Name
+		}
+		return result[i].Attributes.Id < result[j].Attributes.Id
+	})
+	return result
+}
+
+func isCompleteTitle(switchFile *db.SwitchFile, switchTitle *db.SwitchTitle) bool {
+	if !switchFile.BaseExist {
+		return false
+	}
+	if latestLocalVersion(switchFile.Updates) < latestAvailableVersion(switchTitle.Updates) {
+		return false
+	}
+	for id := range switchTitle.Dlc {
+		if _, ok := switchFile.Dlc[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func latestLocalVersion(updates map[int]db.ExtendedFileInfo) int {
+	latest := 0
+	for version := range updates {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+func latestAvailableVersion(updates map[int]string) int {
+	latest := 0
+	for version := range updates {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+const UNKNOWN_REGION = "unknown"
+
+// CalculateRegionCompletion computes the completion of each region, based on the region of the titles in the titles DB.
+// local titles missing from the titles DB are not counted, since their region is unknown.
+func CalculateRegionCompletion(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, countPartial bool) map[string]LibraryCompletion {
+	result := map[string]LibraryCompletion{}
+	for idPrefix, switchTitle := range switchDB {
+		region := titleRegion(switchTitle)
+		completion := result[region]
+		completion.Total++
+		if switchFile, ok := localDB[idPrefix]; ok && (switchFile.BaseExist || countPartial) {
+			completion.Owned++
+		}
+		result[region] = completion
+	}
+	for region, completion := range result {
+		completion.Percent = (float32(completion.Owned) / float32(completion.Total)) * 100
+		result[region] = completion
+	}
+	return result
+}
+
+func titleRegion(switchTitle *db.SwitchTitle) string {
+	if switchTitle.Attributes.Region == "" {
+		return UNKNOWN_REGION
+	}
+	return strings.ToUpper(switchTitle.Attributes.Region)
+}
+
+// CalculateDLCCompletion computes how many of the DLC of the locally owned base games are owned
+func CalculateDLCCompletion(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) LibraryCompletion {
+	result := LibraryCompletion{}
+	for idPrefix, switchFile := range localDB {
+		switchTitle, ok := switchDB[idPrefix]
+		if !ok || !switchFile.BaseExist {
+			continue
+		}
+		result.Total += len(switchTitle.Dlc)
+		for id := range switchTitle.Dlc {
+			if _, ok := switchFile.Dlc[id]; ok {
+				result.Owned++
+			}
+		}
+	}
+	if result.Total != 0 {
+		result.Percent = (float32(result.Owned) / float32(result.Total)) * 100
+	}
+	return result
+}
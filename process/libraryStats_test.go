@@ -0,0 +1,239 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+// localTitle is a local title of the given base titleId, with the given update versions and DLC titleIds
+func localTitle(titleId string, base bool, updates []int, dlc ...string) *db.SwitchFile {
+	switchFile := &db.SwitchFile{BaseExist: base, Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}}
+	if base {
+		switchFile.File = db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}}
+	}
+	for _, version := range updates {
+		switchFile.Updates[version] = db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId[0:13] + "800", Version: version}}
+	}
+	for _, id := range dlc {
+		switchFile.Dlc[id] = db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: id}}
+	}
+	return switchFile
+}
+
+// dbTitle is a titles DB entry of the given base titleId, with the given update versions and DLC titleIds
+func dbTitle(titleId string, name string, region string, updates []int, dlc ...string) *db.SwitchTitle {
+	switchTitle := &db.SwitchTitle{Attributes: db.TitleAttributes{Id: titleId, Name: name, Region: region},
+		Updates: map[int]string{}, Dlc: map[string]db.TitleAttributes{}}
+	for _, version := range updates {
+		switchTitle.Updates[version] = "2020-01-01"
+	}
+	for _, id := range dlc {
+		switchTitle.Dlc[id] = db.TitleAttributes{Id: id, Name: name + " DLC", Version: "0"}
+	}
+	return switchTitle
+}
+
+func TestCalculateCompletion(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Owned", "US", []int{65536}),
+		"010000000002": dbTitle("0100000000020000", "Update only", "US", []int{65536}),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, nil),
+		"010000000002": localTitle("0100000000020000", false, []int{65536}),
+		//titles unknown to the DB are not part of the completion, so that it never exceeds 100%
+		"010000000009": localTitle("0100000000090000", true, nil),
+		"01000000000a": localTitle("01000000000a0000", false, []int{65536}),
+	}
+	tests := []struct {
+		name         string
+		countPartial bool
+		want         LibraryCompletion
+	}{
+		{"update only titles not owned", false, LibraryCompletion{Owned: 1, Total: 2, Percent: 50}},
+		{"update only titles owned", true, LibraryCompletion{Owned: 2, Total: 2, Percent: 100}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CalculateCompletion(localDB, switchDB, test.countPartial); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDLCCompletion(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Some DLC", "US", nil, "0100000000011001", "0100000000011002", "0100000000011003"),
+		"010000000002": dbTitle("0100000000020000", "All DLC", "US", nil, "0100000000021001"),
+		"010000000003": dbTitle("0100000000030000", "Not owned", "US", nil, "0100000000031001", "0100000000031002"),
+		"010000000004": dbTitle("0100000000040000", "No DLC", "US", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, nil, "0100000000011002"),
+		"010000000002": localTitle("0100000000020000", true, nil, "0100000000021001"),
+		//the DLC of base games not owned are not counted
+		"010000000003": localTitle("0100000000030000", false, nil, "0100000000031001"),
+		"010000000004": localTitle("0100000000040000", true, nil),
+	}
+
+	if got, want := CalculateDLCCompletion(localDB, switchDB), (LibraryCompletion{Owned: 2, Total: 4, Percent: 50}); got != want {
+		t.Errorf("got DLC completion %+v, want %+v", got, want)
+	}
+	missing := ScanForMissingDLC(localDB, switchDB)
+	if len(missing) != 1 {
+		t.Fatalf("got missing DLC %v, want only the title missing some", missing)
+	}
+	if title := missing["0100000000010000"]; title.OwnedDLC != 1 || title.TotalDLC != 3 {
+		t.Errorf("got %v of %v DLC owned, want 1 of 3", title.OwnedDLC, title.TotalDLC)
+	}
+}
+
+func TestCalculateRegionCompletion(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "US one", "US", nil),
+		"010000000002": dbTitle("0100000000020000", "US two", "us", nil),
+		"010000000003": dbTitle("0100000000030000", "US three", "US", nil),
+		"010000000004": dbTitle("0100000000040000", "US four", "US", nil),
+		"010000000005": dbTitle("0100000000050000", "EU one", "EU", nil),
+		"010000000006": dbTitle("0100000000060000", "EU two", "EU", nil),
+		"010000000007": dbTitle("0100000000070000", "JP one", "JP", nil),
+		"010000000008": dbTitle("0100000000080000", "No region", "", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, nil),
+		"010000000002": localTitle("0100000000020000", true, nil),
+		"010000000003": localTitle("0100000000030000", true, nil),
+		"010000000005": localTitle("0100000000050000", false, []int{65536}),
+		"010000000008": localTitle("0100000000080000", true, nil),
+		//not in the titles DB, so its region is unknown
+		"010000000009": localTitle("0100000000090000", true, nil),
+	}
+	tests := []struct {
+		name         string
+		countPartial bool
+		want         map[string]LibraryCompletion
+	}{
+		{"update only titles not owned", false, map[string]LibraryCompletion{
+			"US":           {Owned: 3, Total: 4, Percent: 75},
+			"EU":           {Owned: 0, Total: 2, Percent: 0},
+			"JP":           {Owned: 0, Total: 1, Percent: 0},
+			UNKNOWN_REGION: {Owned: 1, Total: 1, Percent: 100},
+		}},
+		{"update only titles owned", true, map[string]LibraryCompletion{
+			"US":           {Owned: 3, Total: 4, Percent: 75},
+			"EU":           {Owned: 1, Total: 2, Percent: 50},
+			"JP":           {Owned: 0, Total: 1, Percent: 0},
+			UNKNOWN_REGION: {Owned: 1, Total: 1, Percent: 100},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CalculateRegionCompletion(localDB, switchDB, test.countPartial); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalculateStrictCompletion(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Complete", "US", []int{65536, 131072}, "0100000000011001"),
+		"010000000002": dbTitle("0100000000020000", "Old update", "US", []int{65536, 131072}),
+		"010000000003": dbTitle("0100000000030000", "Missing DLC", "US", nil, "0100000000031001", "0100000000031002"),
+		"010000000004": dbTitle("0100000000040000", "No base", "US", []int{65536}),
+		"010000000005": dbTitle("0100000000050000", "Nothing to add", "US", nil),
+		"010000000006": dbTitle("0100000000060000", "Not owned", "US", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, []int{65536, 131072}, "0100000000011001"),
+		"010000000002": localTitle("0100000000020000", true, []int{65536}),
+		"010000000003": localTitle("0100000000030000", true, nil, "0100000000031001"),
+		"010000000004": localTitle("0100000000040000", false, []int{65536}),
+		"010000000005": localTitle("0100000000050000", true, nil),
+		//not in the titles DB
+		"010000000009": localTitle("0100000000090000", true, nil),
+	}
+
+	if loose := CalculateCompletion(localDB, switchDB, false); loose.Owned != 4 {
+		t.Errorf("got %v titles owned by the loose metric, want 4", loose.Owned)
+	}
+	if got, want := CalculateStrictCompletion(localDB, switchDB), (LibraryCompletion{Owned: 2, Total: 6, Percent: float32(2) / 6 * 100}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFindCompleteTitles(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Zelda", "US", []int{65536, 131072}, "0100000000011001"),
+		"010000000002": dbTitle("0100000000020000", "Old update", "US", []int{65536, 131072}),
+		"010000000003": dbTitle("0100000000030000", "Missing DLC", "US", nil, "0100000000031001", "0100000000031002"),
+		"010000000004": dbTitle("0100000000040000", "No base", "US", []int{65536}),
+		"010000000005": dbTitle("0100000000050000", "Mario", "US", nil),
+		"010000000006": dbTitle("0100000000060000", "Not owned", "US", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, []int{65536, 131072}, "0100000000011001"),
+		"010000000002": localTitle("0100000000020000", true, []int{65536}),
+		"010000000003": localTitle("0100000000030000", true, nil, "0100000000031001"),
+		"010000000004": localTitle("0100000000040000", false, []int{65536}),
+		"010000000005": localTitle("0100000000050000", true, nil),
+		//not in the titles DB
+		"010000000009": localTitle("0100000000090000", true, nil),
+	}
+
+	want := []CompleteTitle{
+		{Attributes: switchDB["010000000005"].Attributes},
+		{Attributes: switchDB["010000000001"].Attributes, LocalUpdate: 131072, DLCCount: 1},
+	}
+	if got := FindCompleteTitles(localDB, switchDB); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCalculateTitleCompletion(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Complete", "US", []int{65536}, "0100000000011001"),
+		"010000000002": dbTitle("0100000000020000", "Old update", "US", []int{65536, 131072}, "0100000000021001"),
+		"010000000003": dbTitle("0100000000030000", "Base only", "US", []int{65536}, "0100000000031001", "0100000000031002"),
+		"010000000004": dbTitle("0100000000040000", "Nothing to add", "US", nil),
+		"010000000005": dbTitle("0100000000050000", "Missing DLC", "US", nil, "0100000000051001", "0100000000051002"),
+		"010000000006": dbTitle("0100000000060000", "No base", "US", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, []int{65536}, "0100000000011001"),
+		"010000000002": localTitle("0100000000020000", true, []int{65536}, "0100000000021001"),
+		"010000000003": localTitle("0100000000030000", true, nil),
+		"010000000004": localTitle("0100000000040000", true, nil),
+		"010000000005": localTitle("0100000000050000", true, nil, "0100000000051001"),
+		"010000000006": localTitle("0100000000060000", false, nil),
+		//not in the titles DB
+		"010000000009": localTitle("0100000000090000", true, nil),
+	}
+
+	type completion struct {
+		name         string
+		owned, total int
+	}
+	want := []completion{
+		{"Complete", 3, 3},
+		{"Nothing to add", 1, 1},
+		//equally complete titles are ordered by name
+		{"Missing DLC", 2, 3},
+		{"Old update", 2, 3},
+		{"Base only", 1, 4},
+	}
+	var got []completion
+	for _, title := range CalculateTitleCompletion(localDB, switchDB) {
+		got = append(got, completion{title.Attributes.Name, title.Owned, title.Total})
+		if want := float32(title.Owned) / float32(title.Total) * 100; title.Percent != want {
+			t.Errorf("got %v percent for %v, want %v", title.Percent, title.Attributes.Name, want)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+)
+
+// MissingSize is the estimated size of the content missing to complete a title
+type MissingSize struct {
+	Bytes int64
+	//number of missing items (update / DLC) without size data, not counted in Bytes
+	Unknown int
+}
+
+// EstimateMissingSize sums the titles DB size of the latest update (when the local one is older) and of the DLC
+// not owned locally
+func EstimateMissingSize(switchFile *db.SwitchFile, switchTitle *db.SwitchTitle) MissingSize {
+	result := MissingSize{}
+	add := func(size int) {
+		if size > 0 {
+			result.Bytes += int64(size)
+		} else {
+			result.Unknown++
+		}
+	}
+	latestUpdate := latestAvailableVersion(switchTitle.Updates)
+	if switchFile.BaseExist && latestLocalVersion(switchFile.Updates) < latestUpdate && !isBaseVersion(latestUpdate, switchFile) {
+		add(switchTitle.UpdateSize)
+	}
+	for id, dlc := range switchTitle.Dlc {
+		if _, ok := switchFile.Dlc[id]; !ok {
+			add(dlc.Size)
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestEstimateMissingSize(t *testing.T) {
+	//a titles DB entry with a 300 bytes update, and DLC of 100 and 50 bytes plus one of unknown size
+	switchTitle := func() *db.SwitchTitle {
+		switchTitle := dbTitle("0100000000010000", "Game", "US", []int{65536, 131072}, "0100000000011001", "0100000000011002", "0100000000011003")
+		switchTitle.UpdateSize = 300
+		for id, size := range map[string]int{"0100000000011001": 100, "0100000000011002": 50} {
+			dlc := switchTitle.Dlc[id]
+			dlc.Size = size
+			switchTitle.Dlc[id] = dlc
+		}
+		return switchTitle
+	}
+	tests := []struct {
+		name       string
+		switchFile *db.SwitchFile
+		want       MissingSize
+	}{
+		{"everything missing", localTitle("0100000000010000", true, nil), MissingSize{Bytes: 450, Unknown: 1}},
+		{"old update", localTitle("0100000000010000", true, []int{65536}, "0100000000011003"), MissingSize{Bytes: 450}},
+		{"latest update owned", localTitle("0100000000010000", true, []int{131072}, "0100000000011001"), MissingSize{Bytes: 50, Unknown: 1}},
+		{"complete", localTitle("0100000000010000", true, []int{131072}, "0100000000011001", "0100000000011002", "0100000000011003"),
+			MissingSize{}},
+		//without a base there is nothing to update
+		{"no base", localTitle("0100000000010000", false, nil, "0100000000011001", "0100000000011002", "0100000000011003"), MissingSize{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := EstimateMissingSize(test.switchFile, switchTitle()); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	//the update size is unknown
+	unknownUpdate := switchTitle()
+	unknownUpdate.UpdateSize = 0
+	if got, want := EstimateMissingSize(localTitle("0100000000010000", true, nil), unknownUpdate), (MissingSize{Bytes: 150, Unknown: 2}); got != want {
+		t.Errorf("got %+v without the update size, want %+v", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+// FindNotOwnedTitles lists the titles DB base games with no local file at all (no base, update or DLC),
+// sorted by name. when regions is not empty, only the titles of these regions are listed.
+func FindNotOwnedTitles(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, regions []string) []db.TitleAttributes {
+	regionFilter := map[string]bool{}
+	for _, region := range regions {
+		regionFilter[strings.ToUpper(region)] = true
+	}
+	var result []db.TitleAttributes
+	for idPrefix, switchTitle := range switchDB {
+		if _, ok := localDB[idPrefix]; ok {
+			continue
+		}
+		//entries with no base game details (such as DLC of unknown games)
+		if switchTitle.Attributes.Id == "" || switchTitle.Attributes.Name == "" {
+			continue
+		}
+		if len(regionFilter) != 0 && !regionFilter[strings.ToUpper(switchTitle.Attributes.Region)] {
+			continue
+		}
+		result = append(result, switchTitle.Attributes)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].Id < result[j].Id
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,45 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestFindNotOwnedTitles(t *testing.T) {
+	//a game owned only through its DLC is owned
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {BaseExist: true},
+		"010000000002": {Dlc: map[string]db.ExtendedFileInfo{"0100000000021001": {}}},
+	}
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Owned", Region: "US"}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Owned DLC", Region: "US"}},
+		"010000000003": {Attributes: db.TitleAttributes{Id: "0100000000030000", Name: "Zelda", Region: "US"}},
+		"010000000004": {Attributes: db.TitleAttributes{Id: "0100000000040000", Name: "Mario", Region: "JP"}},
+		"010000000005": {Attributes: db.TitleAttributes{Id: "0100000000050000", Name: "Mario", Region: "eu"}},
+		//DLC of a game missing from the titles DB
+		"010000000006": {Dlc: map[string]db.TitleAttributes{"0100000000061001": {Id: "0100000000061001", Name: "DLC"}}},
+	}
+	tests := []struct {
+		name    string
+		regions []string
+		want    []string
+	}{
+		{"all regions", nil, []string{"0100000000040000", "0100000000050000", "0100000000030000"}},
+		{"region filter", []string{"us", "EU"}, []string{"0100000000050000", "0100000000030000"}},
+		{"region without titles", []string{"KR"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, title := range FindNotOwnedTitles(localDB, switchDB, test.regions) {
+				got = append(got, title.Id)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+)
+
+type OldUpdate struct {
+	//the titleId prefix of the title (the titleId without its last 4 chars)
+	IdPrefix string
+	File     db.ExtendedFileInfo
+	Version  int
+	//the latest update version found locally for the title
+	LatestLocalVersion int
+}
+
+// FindOldUpdates lists the local update files that are not the latest (non blacklisted) local update of their title -
+// the files DeleteOldUpdates would remove (the dry run of ConsolidateUpdates), sorted by path. nothing is changed.
+func FindOldUpdates(localDB *db.LocalSwitchFilesDB, blacklist Blacklist) []OldUpdate {
+	result := ConsolidateUpdates(localDB, true, blacklist)
+	sort.Slice(result, func(i, j int) bool {
+		return filepath.Join(result[i].File.BaseFolder, result[i].File.Info.Name()) < filepath.Join(result[j].File.BaseFolder, result[j].File.Info.Name())
+	})
+	return result
+}
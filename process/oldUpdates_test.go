@@ -0,0 +1,85 @@
+package process
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestFindOldUpdates(t *testing.T) {
+	tests := []struct {
+		name        string
+		blacklisted map[string][]int
+		want        []OldUpdate
+	}{
+		{"all but the newest", nil, []OldUpdate{
+			{IdPrefix: "010000000001", Version: 65536, LatestLocalVersion: 196608},
+			{IdPrefix: "010000000001", Version: 131072, LatestLocalVersion: 196608},
+			{IdPrefix: "010000000002", Version: 65536, LatestLocalVersion: 131072},
+		}},
+		{"blacklisted newest", map[string][]int{"0100000000010800": {196608}}, []OldUpdate{
+			{IdPrefix: "010000000001", Version: 65536, LatestLocalVersion: 131072},
+			{IdPrefix: "010000000001", Version: 196608, LatestLocalVersion: 131072},
+			{IdPrefix: "010000000002", Version: 65536, LatestLocalVersion: 131072},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := newTestFolder(t)
+			archived := newTestFile(t, folder, "updates.7z", "0100000000020800", 32768)
+			archived.InArchive = true
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{
+				"010000000001": {
+					File:      newTestFile(t, folder, "a base.nsp", "0100000000010000", 0),
+					BaseExist: true,
+					Updates: map[int]db.ExtendedFileInfo{
+						65536:  newTestFile(t, folder, "a v1.nsp", "0100000000010800", 65536),
+						131072: newTestFile(t, folder, "a v2.nsp", "0100000000010800", 131072),
+						196608: newTestFile(t, folder, "a v3.nsp", "0100000000010800", 196608),
+					},
+				},
+				"010000000002": {
+					File:      newTestFile(t, folder, "b.xci", "0100000000020000", 0),
+					BaseExist: true,
+					Updates: map[int]db.ExtendedFileInfo{
+						32768:  archived,
+						65536:  newTestFile(t, folder, "b v1.nsp", "0100000000020800", 65536),
+						131072: newTestFile(t, folder, "b v2.nsp", "0100000000020800", 131072),
+					},
+				},
+				//a single update is never old
+				"010000000003": {
+					File:      newTestFile(t, folder, "c base.nsp", "0100000000030000", 0),
+					BaseExist: true,
+					Updates:   map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "c v1.nsp", "0100000000030800", 65536)},
+				},
+			}}
+			//the update part of the XCI is not a separate file
+			xci := localDB.TitlesMap["010000000002"]
+			xci.Updates[16384] = xci.File
+
+			oldUpdates := FindOldUpdates(localDB, NewBlacklist(test.blacklisted))
+			var got []OldUpdate
+			for _, oldUpdate := range oldUpdates {
+				if oldUpdate.File.Metadata.Version != oldUpdate.Version {
+					t.Errorf("file of version %v listed as version %v", oldUpdate.File.Metadata.Version, oldUpdate.Version)
+				}
+				oldUpdate.File = db.ExtendedFileInfo{}
+				got = append(got, oldUpdate)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if len(localDB.TitlesMap["010000000001"].Updates) != 3 {
+				t.Error("the updates were removed from the DB")
+			}
+			for _, oldUpdate := range oldUpdates {
+				if !fileExists(filepath.Join(oldUpdate.File.BaseFolder, oldUpdate.File.Info.Name())) {
+					t.Errorf("%v was deleted", oldUpdate.File.Info.Name())
+				}
+			}
+		})
+	}
+}
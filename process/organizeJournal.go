@@ -0,0 +1,100 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+)
+
+// the journal is kept in the library folder while organizing, and deleted once the organization completes,
+// so finding it means the previous organization was interrupted
+const ORGANIZE_JOURNAL_FILENAME = "slm_organize_journal.json"
+
+type JournalEntry struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Done bool   `json:"done"`
+}
+
+type organizeJournal struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func createOrganizeJournal(libraryFolder string) (*organizeJournal, error) {
+	file, err := os.OpenFile(filepath.Join(libraryFolder, ORGANIZE_JOURNAL_FILENAME), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &organizeJournal{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (j *organizeJournal) record(entry JournalEntry) {
+	if j == nil {
+		return
+	}
+	if err := j.encoder.Encode(entry); err != nil {
+		zap.S().Errorf("Failed to write the organize journal [%v]\n", err)
+	}
+}
+
+// complete deletes the journal, marking the organization as done
+func (j *organizeJournal) complete() {
+	if j == nil {
+		return
+	}
+	path := j.file.Name()
+	j.file.Close()
+	if err := os.Remove(path); err != nil {
+		zap.S().Errorf("Failed to delete the organize journal [%v]\n", err)
+	}
+}
+
+// FindIncompleteOrganize returns the moves of an interrupted organization which did not complete, or nil if the
+// previous organization completed. A move whose destination exists but source does not is considered done.
+func FindIncompleteOrganize(libraryFolder string) ([]JournalEntry, error) {
+	file, err := os.Open(filepath.Join(libraryFolder, ORGANIZE_JOURNAL_FILENAME))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var started []JournalEntry
+	done := map[JournalEntry]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := JournalEntry{}
+		//the last line may be cut by the interruption
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Done {
+			entry.Done = false
+			done[entry] = true
+			continue
+		}
+		started = append(started, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result := []JournalEntry{}
+	for _, entry := range started {
+		if done[entry] || (!fileExists(entry.From) && fileExists(entry.To)) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
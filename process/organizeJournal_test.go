@@ -0,0 +1,100 @@
+package process
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestResumeInterruptedOrganize(t *testing.T) {
+	folder := newTestFolder(t)
+	settingsObj := useTestSettings(t, newTestFolder(t))
+	settingsObj.OrganizeOptions.CreateFolderPerGame = true
+	settingsObj.Apply = true
+	gameFolder := filepath.Join(folder, "Game")
+	if err := os.Mkdir(gameFolder, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+		File:      newTestFile(t, gameFolder, "base.nsp", "0100000000010000", 0),
+		BaseExist: true,
+		Updates:   map[int]db.ExtendedFileInfo{65536: newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536)},
+		Dlc:       map[string]db.ExtendedFileInfo{"0100000000011001": newTestFile(t, gameFolder, "dlc.nsp", "0100000000011001", 0)},
+	}}}
+
+	//the interrupted organization moved the base, moved the dlc without recording it, and never moved the update
+	baseMove := JournalEntry{From: filepath.Join(folder, "base.nsp"), To: filepath.Join(gameFolder, "base.nsp")}
+	dlcMove := JournalEntry{From: filepath.Join(folder, "dlc.nsp"), To: filepath.Join(gameFolder, "dlc.nsp")}
+	updateMove := JournalEntry{From: filepath.Join(folder, "v1.nsp"), To: filepath.Join(gameFolder, "v1.nsp")}
+	baseDone := baseMove
+	baseDone.Done = true
+	var journal strings.Builder
+	for _, entry := range []JournalEntry{baseMove, baseDone, dlcMove, updateMove} {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		journal.Write(line)
+		journal.WriteString("\n")
+	}
+	//the interruption cut the last line
+	journal.WriteString(`{"from": "`)
+	if err := ioutil.WriteFile(filepath.Join(folder, ORGANIZE_JOURNAL_FILENAME), []byte(journal.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	incomplete, err := FindIncompleteOrganize(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(incomplete, []JournalEntry{updateMove}) {
+		t.Errorf("incomplete moves %v, want only %v", incomplete, updateMove)
+	}
+
+	//resuming plans the organization again from a scan of the current layout
+	titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}},
+	}}
+	OrganizeByFolders(folder, localDB, titlesDB, nil)
+
+	want := []string{filepath.Join("Game", "base.nsp"), filepath.Join("Game", "dlc.nsp"), filepath.Join("Game", "v1.nsp")}
+	if got := listFiles(t, folder); !reflect.DeepEqual(got, want) {
+		t.Errorf("files %v, want %v", got, want)
+	}
+	if incomplete, err := FindIncompleteOrganize(folder); err != nil || incomplete != nil {
+		t.Errorf("incomplete moves %v (error %v) after resuming, want the journal deleted", incomplete, err)
+	}
+}
+
+func TestCompletedOrganizeLeavesNoJournal(t *testing.T) {
+	folder := newTestFolder(t)
+	if incomplete, err := FindIncompleteOrganize(folder); err != nil || incomplete != nil {
+		t.Errorf("incomplete moves %v (error %v) without a journal, want none", incomplete, err)
+	}
+
+	journal, err := createOrganizeJournal(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := filepath.Join(folder, "base.nsp"), filepath.Join(folder, "moved.nsp")
+	newTestFile(t, folder, "base.nsp", "0100000000010000", 0)
+	if err := moveFile(from, to, false, journal); err != nil {
+		t.Fatal(err)
+	}
+	//all the recorded moves completed, but the organization itself did not
+	incomplete, err := FindIncompleteOrganize(folder)
+	if err != nil || !reflect.DeepEqual(incomplete, []JournalEntry{}) {
+		t.Errorf("incomplete moves %v (error %v), want an interrupted organization with no pending move", incomplete, err)
+	}
+	journal.complete()
+	if fileExists(filepath.Join(folder, ORGANIZE_JOURNAL_FILENAME)) {
+		t.Error("the journal of a completed organization was not deleted")
+	}
+}
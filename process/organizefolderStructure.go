@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,8 +19,31 @@ var (
 	folderIllegalCharsRegex = regexp.MustCompile(`[/\\?%*:|"<>]`)
 )
 
-func DeleteOldUpdates(localDB *db.LocalSwitchFilesDB) {
-	for _, v := range localDB.TitlesMap {
+// DeleteOldUpdates removes the old update files of each title, keeping the latest one.
+// nothing is removed in a dry run, or when the settings require applying the changes explicitly (see
+// ChangesNotApplied), the old update files are then only listed.
+func DeleteOldUpdates(settingsObj *settings.AppSettings, localDB *db.LocalSwitchFilesDB, blacklist Blacklist) {
+	if !settingsObj.OrganizeOptions.DryRun && ChangesNotApplied(settingsObj) {
+		zap.S().Infof("--> require_apply is set and the changes were not applied, the old update files are only listed\n")
+		ConsolidateUpdates(localDB, true, blacklist)
+		return
+	}
+	ConsolidateUpdates(localDB, settingsObj.OrganizeOptions.DryRun, blacklist)
+}
+
+// ChangesNotApplied tells whether the organize/delete operations are limited to a dry run, as require_apply is set
+// and the changes were not applied explicitly (the -apply flag)
+func ChangesNotApplied(settingsObj *settings.AppSettings) bool {
+	return settingsObj.RequireApply && !settingsObj.Apply
+}
+
+// ConsolidateUpdates keeps only the latest update file of each title, and returns the old update files.
+// blacklisted versions are never the one kept, titles having only blacklisted newer updates are left as is.
+// when dryRun is set the files are only listed, and the local DB is left untouched.
+// base and DLC files are never removed.
+func ConsolidateUpdates(localDB *db.LocalSwitchFilesDB, dryRun bool, blacklist Blacklist) []OldUpdate {
+	var result []OldUpdate
+	for idPrefix, v := range localDB.Titles() {
 
 		if len(v.Updates) > 1 {
 			//sort the available local versions
@@ -30,67 +54,175 @@ func DeleteOldUpdates(localDB *db.LocalSwitchFilesDB) {
 				i++
 			}
 			sort.Ints(localVersions)
+			latest := -1
+			for i := len(localVersions) - 1; i >= 0; i-- {
+				if !blacklist.Contains(idPrefix, localVersions[i]) {
+					latest = localVersions[i]
+					break
+				}
+			}
+			if latest <= 0 {
+				zap.S().Warnf("--> Only blacklisted updates found for %v, keeping all update files\n", idPrefix)
+				continue
+			}
 
-			for i := 0; i < len(localVersions)-1; i++ {
+			keptUpdates := map[int]db.ExtendedFileInfo{latest: v.Updates[latest]}
+			for i := 0; i < len(localVersions); i++ {
+				if localVersions[i] == latest {
+					continue
+				}
 				if localVersions[i] == 0 {
 					//should not happen, but make sure we do not delete base
 					continue
 				}
-				fileToRemove := filepath.Join(v.Updates[localVersions[i]].BaseFolder, v.Updates[localVersions[i]].Info.Name())
-				zap.S().Infof("--> [Delete] Old update file: %v [latest update:%v]\n", fileToRemove, localVersions[len(localVersions)-1])
+				oldUpdate := v.Updates[localVersions[i]]
+				if v.BaseExist && oldUpdate.Info == v.File.Info {
+					//XCI files hold both the base and an update
+					continue
+				}
+				fileToRemove := filepath.Join(oldUpdate.BaseFolder, oldUpdate.Info.Name())
+				if oldUpdate.InArchive {
+					zap.S().Infof("--> [Read only] Old update inside the archive %v is kept [latest update:%v]\n", fileToRemove, latest)
+					keptUpdates[localVersions[i]] = oldUpdate
+					continue
+				}
+				result = append(result, OldUpdate{IdPrefix: idPrefix, File: oldUpdate, Version: localVersions[i], LatestLocalVersion: latest})
+				if dryRun {
+					zap.S().Infof("--> [Dry run] Would delete old update file: %v [latest update:%v]\n", fileToRemove, latest)
+					continue
+				}
+				zap.S().Infof("--> [Delete] Old update file: %v [latest update:%v]\n", fileToRemove, latest)
 				err := os.Remove(fileToRemove)
+				auditOperation(AUDIT_DELETE, fileToRemove, "", err)
 				if err != nil {
 					zap.S().Errorf("Failed to delete file  %v  [%v]\n", fileToRemove, err)
 				}
 			}
-			v.Updates = map[int]db.ExtendedFileInfo{localVersions[len(localVersions)-1]: v.Updates[localVersions[len(localVersions)-1]]}
+			if !dryRun {
+				localDB.SetUpdates(idPrefix, keptUpdates)
+			}
 		}
 
 	}
+	return result
 }
 
+// ReadOnlyArchives returns the paths of the archives holding local files (sorted), which are never deleted, moved or renamed
+func ReadOnlyArchives(localDB map[string]*db.SwitchFile) []string {
+	paths := map[string]bool{}
+	for _, switchFile := range localDB {
+		for _, file := range switchFile.Files() {
+			if file.InArchive {
+				paths[filepath.Join(file.BaseFolder, file.Info.Name())] = true
+			}
+		}
+	}
+	var result []string
+	for path := range paths {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// OrganizeByFolders organizes the files within the base folder (see OrganizeByScanFolders)
 func OrganizeByFolders(baseFolder string, localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, updateProgress db.ProgressUpdater) {
+	OrganizeByScanFolders([]string{baseFolder}, localDB, titlesDB, updateProgress)
+}
 
-	options := settings.ReadSettings(baseFolder).OrganizeOptions
+// OrganizeByScanFolders organizes the files of each scan folder within that scan folder, as the scan folders may be
+// on different devices (a file can't be renamed across devices). The game folders are created in the scan folder of
+// each file, and files found outside of the scan folders are organized within their own folder.
+func OrganizeByScanFolders(scanFolders []string, localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, updateProgress db.ProgressUpdater) {
+	if len(scanFolders) == 0 {
+		return
+	}
+	settingsObj := settings.ReadSettings(scanFolders[0])
+	options := settingsObj.OrganizeOptions
+	if !options.DryRun && ChangesNotApplied(settingsObj) {
+		zap.S().Infof("--> require_apply is set and the changes were not applied, organizing as a dry run\n")
+		options.DryRun = true
+	}
+	if options.KeepOnlyLatestUpdate && !options.DryRun {
+		//consolidate first, so that old updates are not moved around. a dry run leaves the old updates in the DB,
+		//listing them is left to the caller (ConsolidateUpdates with dryRun set), which usually needs them anyway
+		ConsolidateUpdates(localDB, false, NewBlacklist(settingsObj.BlacklistedVersions))
+	}
+	//a snapshot of the titles, as embedders may change the DB while the files are moved
+	localTitles := localDB.Titles()
+
+	//organizing is idempotent, so an interrupted organization is resumed by planning it again from the current scan
+	journals := map[string]*organizeJournal{}
+	if !options.DryRun {
+		for _, scanFolder := range scanFolders {
+			if incomplete, err := FindIncompleteOrganize(scanFolder); err == nil && incomplete != nil {
+				zap.S().Warnf("--> Resuming an interrupted organization of %v, %v moves did not complete\n", scanFolder, len(incomplete))
+			}
+			journal, err := createOrganizeJournal(scanFolder)
+			if err != nil {
+				zap.S().Errorf("Failed to create the organize journal, an interruption will not be detected [%v]\n", err)
+			}
+			journals[scanFolder] = journal
+		}
+	}
+	folderNameCollisions := findFolderNameCollisions(options, localTitles, titlesDB)
+	plannedFolders := map[string]bool{}
 	i := 0
-	for k, v := range localDB.TitlesMap {
+	for k, v := range localTitles {
 		i++
 		if v.BaseExist == false {
 			continue
 		}
 		if updateProgress != nil {
-			updateProgress.UpdateProgress(i, len(localDB.TitlesMap), v.File.Info.Name())
+			updateProgress.UpdateProgress(i, len(localTitles), v.File.Info.Name())
 		}
 
-		titleName := getTitleName(titlesDB.TitlesMap[k], v)
-
-		templateData := map[string]string{}
+		templateData := getBaseTemplateData(titlesDB.TitlesMap[k], v)
 
-		templateData[settings.TEMPLATE_TITLE_ID] = v.File.Metadata.TitleId
-		//templateData[settings.TEMPLATE_TYPE] = "BASE"
-		templateData[settings.TEMPLATE_TITLE_NAME] = titleName
-		templateData[settings.TEMPLATE_VERSION] = "0"
-
-		var destinationPath = v.File.BaseFolder
-
-		//create folder if needed
+		folderToCreate := ""
 		if options.CreateFolderPerGame {
-			folderToCreate := getFolderName(options, templateData)
-			destinationPath = filepath.Join(baseFolder, folderToCreate)
-			if _, err := os.Stat(destinationPath); os.IsNotExist(err) {
-				err = os.Mkdir(destinationPath, os.ModePerm)
+			folderToCreate = getFolderName(options, templateData)
+			if folderNameCollisions[strings.ToLower(folderToCreate)] > 1 {
+				//different titles would end up in the same folder, add the title id to tell them apart
+				zap.S().Warnf("--> Folder name [%v] is shared by multiple titles, adding title id [%v]\n", folderToCreate, v.File.Metadata.TitleId)
+				folderToCreate = folderToCreate + " [" + strings.ToUpper(v.File.Metadata.TitleId) + "]"
+			}
+		}
+		//the folder a file of the game is moved to, within the scan folder of the file
+		destinationOf := func(file db.ExtendedFileInfo) (string, error) {
+			if !options.CreateFolderPerGame {
+				return file.BaseFolder, nil
+			}
+			destinationPath := filepath.Join(scanFolderOf(file.BaseFolder, scanFolders), folderToCreate)
+			if _, err := os.Stat(destinationPath); os.IsNotExist(err) && options.DryRun {
+				if !plannedFolders[destinationPath] {
+					zap.S().Infof("--> [Dry run] Would create folder %v\n", destinationPath)
+					plannedFolders[destinationPath] = true
+				}
+			} else if os.IsNotExist(err) {
+				err = os.Mkdir(extendedLengthPath(destinationPath), os.ModePerm)
+				auditOperation(AUDIT_MKDIR, destinationPath, "", err)
 				if err != nil {
-					zap.S().Errorf("Failed to create folder %v - %v\n", folderToCreate, err)
-					continue
+					zap.S().Errorf("Failed to create folder %v - %v\n", destinationPath, err)
+					return "", err
 				}
 			}
+			return destinationPath, nil
+		}
+		journalOf := func(file db.ExtendedFileInfo) *organizeJournal {
+			return journals[scanFolderOf(file.BaseFolder, scanFolders)]
 		}
 
 		//process base title
+		destinationPath, err := destinationOf(v.File)
+		if err != nil {
+			continue
+		}
 		from := filepath.Join(v.File.BaseFolder, v.File.Info.Name())
 		to := filepath.Join(destinationPath, getFileName(options, v.File.Info.Name(), templateData))
-		err := moveFile(from, to)
-		if err != nil {
+		if v.File.InArchive {
+			zap.S().Infof("--> [Read only] %v is an archive, it is not moved\n", from)
+		} else if err := moveFile(from, to, options.DryRun, journalOf(v.File)); err != nil {
 			zap.S().Errorf("Failed to move file [%v]\n", err)
 			continue
 		}
@@ -102,13 +234,18 @@ func OrganizeByFolders(baseFolder string, localDB *db.LocalSwitchFilesDB, titles
 			}
 			templateData[settings.TEMPLATE_VERSION] = strconv.Itoa(update)
 			templateData[settings.TEMPLATE_TYPE] = "UPD"
+			templateData[settings.TEMPLATE_REGION] = updateInfo.Region
 			from = filepath.Join(updateInfo.BaseFolder, updateInfo.Info.Name())
-			if options.CreateFolderPerGame {
-				to = filepath.Join(destinationPath, getFileName(options, updateInfo.Info.Name(), templateData))
-			} else {
-				to = filepath.Join(updateInfo.BaseFolder, getFileName(options, updateInfo.Info.Name(), templateData))
+			if updateInfo.InArchive {
+				zap.S().Infof("--> [Read only] %v is an archive, it is not moved\n", from)
+				continue
+			}
+			destinationPath, err := destinationOf(updateInfo)
+			if err != nil {
+				continue
 			}
-			err := moveFile(from, to)
+			to = filepath.Join(destinationPath, getFileName(options, updateInfo.Info.Name(), templateData))
+			err = moveFile(from, to, options.DryRun, journalOf(updateInfo))
 			if err != nil {
 				zap.S().Errorf("Failed to move file [%v]\n", err)
 				continue
@@ -121,15 +258,20 @@ func OrganizeByFolders(baseFolder string, localDB *db.LocalSwitchFilesDB, titles
 				templateData[settings.TEMPLATE_VERSION] = strconv.Itoa(dlc.Metadata.Version)
 			}
 			templateData[settings.TEMPLATE_TYPE] = "DLC"
+			templateData[settings.TEMPLATE_REGION] = dlc.Region
 			templateData[settings.TEMPLATE_TITLE_ID] = id
 			templateData[settings.TEMPLATE_DLC_NAME] = getDlcName(titlesDB.TitlesMap[k], dlc)
 			from = filepath.Join(dlc.BaseFolder, dlc.Info.Name())
-			if options.CreateFolderPerGame {
-				to = filepath.Join(destinationPath, getFileName(options, dlc.Info.Name(), templateData))
-			} else {
-				to = filepath.Join(dlc.BaseFolder, getFileName(options, dlc.Info.Name(), templateData))
+			if dlc.InArchive {
+				zap.S().Infof("--> [Read only] %v is an archive, it is not moved\n", from)
+				continue
+			}
+			destinationPath, err := destinationOf(dlc)
+			if err != nil {
+				continue
 			}
-			err = moveFile(from, to)
+			to = filepath.Join(destinationPath, getFileName(options, dlc.Info.Name(), templateData))
+			err = moveFile(from, to, options.DryRun, journalOf(dlc))
 			if err != nil {
 				zap.S().Errorf("Failed to move file [%v]\n", err)
 				continue
@@ -137,14 +279,57 @@ func OrganizeByFolders(baseFolder string, localDB *db.LocalSwitchFilesDB, titles
 		}
 	}
 
-	if options.DeleteEmptyFolders {
-		err := deleteEmptyFolders(baseFolder)
-		if err != nil {
-			zap.S().Errorf("Failed to delete empty folders [%v]\n", err)
+	for _, journal := range journals {
+		journal.complete()
+	}
+
+	if options.DeleteEmptyFolders && !options.DryRun {
+		for _, scanFolder := range scanFolders {
+			err := deleteEmptyFolders(scanFolder)
+			if err != nil {
+				zap.S().Errorf("Failed to delete empty folders [%v]\n", err)
+			}
 		}
 	}
 }
 
+// scanFolderOf returns the scan folder holding the folder, or the folder itself when it is outside of the scan folders
+func scanFolderOf(folder string, scanFolders []string) string {
+	for _, scanFolder := range scanFolders {
+		if filepath.Clean(scanFolder) == filepath.Clean(folder) || isSubFolder(scanFolder, folder) {
+			return scanFolder
+		}
+	}
+	return folder
+}
+
+func getBaseTemplateData(switchTitle *db.SwitchTitle, v *db.SwitchFile) map[string]string {
+	templateData := map[string]string{}
+
+	templateData[settings.TEMPLATE_TITLE_ID] = v.File.Metadata.TitleId
+	//templateData[settings.TEMPLATE_TYPE] = "BASE"
+	templateData[settings.TEMPLATE_TITLE_NAME] = getTitleName(switchTitle, v)
+	templateData[settings.TEMPLATE_VERSION] = "0"
+	templateData[settings.TEMPLATE_REGION] = v.File.Region
+	return templateData
+}
+
+// count how many distinct titles map to each (case insensitive) folder name
+func findFolderNameCollisions(options settings.OrganizeOptions, localTitles map[string]*db.SwitchFile, titlesDB *db.SwitchTitlesDB) map[string]int {
+	result := map[string]int{}
+	if !options.CreateFolderPerGame {
+		return result
+	}
+	for k, v := range localTitles {
+		if v.BaseExist == false {
+			continue
+		}
+		folderName := getFolderName(options, getBaseTemplateData(titlesDB.TitlesMap[k], v))
+		result[strings.ToLower(folderName)]++
+	}
+	return result
+}
+
 func getDlcName(switchTitle *db.SwitchTitle, file db.ExtendedFileInfo) string {
 	if switchTitle == nil {
 		return ""
@@ -181,20 +366,55 @@ func getFileName(options settings.OrganizeOptions, originalName string, template
 	return result + ext
 }
 
-func moveFile(from string, to string) error {
+func moveFile(from string, to string, dryRun bool, journal *organizeJournal) error {
 	if from == to {
 		return nil
 	}
-	err := os.Rename(from, to)
+	if dryRun {
+		zap.S().Infof("--> [Dry run] Would move %v to %v\n", from, to)
+		if isOverlongPath(to) {
+			zap.S().Warnf("--> [Dry run] Destination path is longer than %v chars, the extended-length path prefix will be used: %v\n", WINDOWS_MAX_PATH, to)
+		}
+		return nil
+	}
+	journal.record(JournalEntry{From: from, To: to})
+	err := os.Rename(extendedLengthPath(from), extendedLengthPath(to))
+	auditOperation(AUDIT_MOVE, from, to, err)
+	if err == nil {
+		journal.record(JournalEntry{From: from, To: to, Done: true})
+	}
 	return err
 }
 
+const WINDOWS_MAX_PATH = 260
+
+func isOverlongPath(path string) bool {
+	return runtime.GOOS == "windows" && len(path) >= WINDOWS_MAX_PATH
+}
+
+// windows fails to handle paths longer than MAX_PATH, unless they use the \\?\ extended-length prefix
+func extendedLengthPath(path string) string {
+	if !isOverlongPath(path) || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(absPath, `\\`) {
+		//UNC path, \\server\share becomes \\?\UNC\server\share
+		return `\\?\UNC\` + absPath[2:]
+	}
+	return `\\?\` + absPath
+}
+
 func applyTemplate(templateData map[string]string, template string) string {
 	result := strings.Replace(template, "{"+settings.TEMPLATE_TITLE_NAME+"}", templateData[settings.TEMPLATE_TITLE_NAME], 1)
 	result = strings.Replace(result, "{"+settings.TEMPLATE_TITLE_ID+"}", strings.ToUpper(templateData[settings.TEMPLATE_TITLE_ID]), 1)
 	result = strings.Replace(result, "{"+settings.TEMPLATE_VERSION+"}", templateData[settings.TEMPLATE_VERSION], 1)
 	result = strings.Replace(result, "{"+settings.TEMPLATE_TYPE+"}", templateData[settings.TEMPLATE_TYPE], 1)
 	result = strings.Replace(result, "{"+settings.TEMPLATE_DLC_NAME+"}", templateData[settings.TEMPLATE_DLC_NAME], 1)
+	result = strings.Replace(result, "{"+settings.TEMPLATE_REGION+"}", templateData[settings.TEMPLATE_REGION], 1)
 	result = strings.ReplaceAll(result, "[]", "")
 	result = strings.ReplaceAll(result, "()", "")
 	result = strings.ReplaceAll(result, "<>", "")
@@ -231,7 +451,8 @@ func deleteEmptyFolder(path string) error {
 	}
 
 	zap.S().Infof("\nDeleting empty folder [%v]", path)
-	_ = os.Remove(path)
+	err = os.Remove(path)
+	auditOperation(AUDIT_RMDIR, path, "", err)
 
 	return nil
 }
@@ -0,0 +1,406 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+// newTestFolder creates a temp folder, removed once the test completes
+func newTestFolder(t testing.TB) string {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "slm-process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(folder) })
+	return folder
+}
+
+// newTestFile creates an empty file in the folder, and returns it as a local file of the given titleId and version
+func newTestFile(t *testing.T, folder string, name string, titleId string, version int) db.ExtendedFileInfo {
+	t.Helper()
+	filePath := filepath.Join(folder, name)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.ExtendedFileInfo{Info: info, BaseFolder: folder,
+		Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version}}
+}
+
+func TestOrganizeSeparatesTitlesSharingAFolderName(t *testing.T) {
+	tests := []struct {
+		name      string
+		dryRun    bool
+		wantFiles []string
+	}{
+		{"dry run", true, []string{"first.nsp", "other.nsp", "second.nsp", "third.nsp"}},
+		{"apply", false, []string{
+			filepath.Join("Game- One [0100000000010000]", "first.nsp"),
+			filepath.Join("Game- One [0100000000020000]", "second.nsp"),
+			filepath.Join("Other", "other.nsp"),
+			filepath.Join("game- one [0100000000030000]", "third.nsp"),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := newTestFolder(t)
+			settingsObj := useTestSettings(t, newTestFolder(t))
+			settingsObj.OrganizeOptions.CreateFolderPerGame = true
+			settingsObj.OrganizeOptions.DryRun = test.dryRun
+
+			//the names differ, but sanitize to the same folder name (ignoring case)
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{
+				"010000000001": {File: newTestFile(t, folder, "first.nsp", "0100000000010000", 0), BaseExist: true},
+				"010000000002": {File: newTestFile(t, folder, "second.nsp", "0100000000020000", 0), BaseExist: true},
+				"010000000003": {File: newTestFile(t, folder, "third.nsp", "0100000000030000", 0), BaseExist: true},
+				"010000000004": {File: newTestFile(t, folder, "other.nsp", "0100000000040000", 0), BaseExist: true},
+			}}
+			titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+				"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game: One"}},
+				"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Game? One"}},
+				"010000000003": {Attributes: db.TitleAttributes{Id: "0100000000030000", Name: "game| one"}},
+				"010000000004": {Attributes: db.TitleAttributes{Id: "0100000000040000", Name: "Other"}},
+			}}
+
+			collisions := findFolderNameCollisions(settingsObj.OrganizeOptions, localDB.TitlesMap, titlesDB)
+			if collisions["game- one"] != 3 || collisions["other"] != 1 {
+				t.Errorf("collisions %v, want 3 titles sharing [game- one]", collisions)
+			}
+			OrganizeByFolders(folder, localDB, titlesDB, nil)
+			if got := listFiles(t, folder); !reflect.DeepEqual(got, test.wantFiles) {
+				t.Errorf("files %v, want %v", got, test.wantFiles)
+			}
+		})
+	}
+}
+
+// listFiles lists the files in the folder (relative paths, sorted)
+func listFiles(t *testing.T, folder string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			relative, _ := filepath.Rel(folder, path)
+			files = append(files, relative)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestConsolidateUpdatesKeepsArchives(t *testing.T) {
+	tests := []struct {
+		name         string
+		dryRun       bool
+		wantRemoved  []string
+		wantFiles    []string
+		wantVersions []int
+	}{
+		{"dry run", true, []string{"v1.nsp"}, []string{"base.nsp", "updates.7z", "v1.nsp", "v3.nsp"}, []int{65536, 131072, 196608}},
+		{"delete", false, []string{"v1.nsp"}, []string{"base.nsp", "updates.7z", "v3.nsp"}, []int{131072, 196608}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := newTestFolder(t)
+			archived := newTestFile(t, folder, "updates.7z", "0100000000010800", 131072)
+			archived.InArchive = true
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+				File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+				BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{
+					65536:  newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536),
+					131072: archived,
+					196608: newTestFile(t, folder, "v3.nsp", "0100000000010800", 196608),
+				},
+			}}}
+
+			var removed []string
+			for _, f := range ConsolidateUpdates(localDB, test.dryRun, NewBlacklist(nil)) {
+				removed = append(removed, f.File.Info.Name())
+			}
+			if !reflect.DeepEqual(removed, test.wantRemoved) {
+				t.Errorf("removed %v, want %v", removed, test.wantRemoved)
+			}
+			if got := listFiles(t, folder); !reflect.DeepEqual(got, test.wantFiles) {
+				t.Errorf("files %v, want %v", got, test.wantFiles)
+			}
+			var versions []int
+			for version := range localDB.TitlesMap["010000000001"].Updates {
+				versions = append(versions, version)
+			}
+			sort.Ints(versions)
+			if !reflect.DeepEqual(versions, test.wantVersions) {
+				t.Errorf("versions %v, want %v", versions, test.wantVersions)
+			}
+		})
+	}
+}
+
+func TestReadOnlyArchives(t *testing.T) {
+	folder := newTestFolder(t)
+	archivedUpdate := newTestFile(t, folder, "b.7z", "0100000000010800", 65536)
+	archivedUpdate.InArchive = true
+	archivedDlc := newTestFile(t, folder, "a.rar", "0100000000011001", 0)
+	archivedDlc.InArchive = true
+	//both files of the same archive are listed once
+	sameArchive := newTestFile(t, folder, "a.rar", "0100000000011002", 0)
+	sameArchive.InArchive = true
+	localDB := map[string]*db.SwitchFile{"010000000001": {
+		File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+		BaseExist: true,
+		Updates:   map[int]db.ExtendedFileInfo{65536: archivedUpdate},
+		Dlc:       map[string]db.ExtendedFileInfo{"0100000000011001": archivedDlc, "0100000000011002": sameArchive},
+	}}
+	want := []string{filepath.Join(folder, "a.rar"), filepath.Join(folder, "b.7z")}
+	if got := ReadOnlyArchives(localDB); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// useTestSettings replaces the settings (OrganizeByFolders reads them) by settings saved in the folder, holding
+// only the default folder name template, and returns them
+func useTestSettings(t *testing.T, folder string) *settings.AppSettings {
+	t.Helper()
+	return settings.SaveSettings(&settings.AppSettings{OrganizeOptions: settings.OrganizeOptions{
+		FolderNameTemplate: "{" + settings.TEMPLATE_TITLE_NAME + "}"}}, folder)
+}
+
+func TestOrganizeKeepsOnlyTheLatestUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		dryRun       bool
+		wantFiles    []string
+		wantVersions []int
+	}{
+		{"dry run", true, []string{"base.nsp", "dlc.nsp", "v1.nsp", "v2.nsp", "v3.nsp"}, []int{65536, 131072, 196608}},
+		{"apply", false, []string{filepath.Join("Game", "base.nsp"), filepath.Join("Game", "dlc.nsp"), filepath.Join("Game", "v3.nsp")}, []int{196608}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := newTestFolder(t)
+			settingsObj := useTestSettings(t, newTestFolder(t))
+			settingsObj.OrganizeOptions.KeepOnlyLatestUpdate = true
+			settingsObj.OrganizeOptions.CreateFolderPerGame = true
+			settingsObj.OrganizeOptions.DryRun = test.dryRun
+			settingsObj.Apply = true
+
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+				File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+				BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{
+					65536:  newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536),
+					131072: newTestFile(t, folder, "v2.nsp", "0100000000010800", 131072),
+					196608: newTestFile(t, folder, "v3.nsp", "0100000000010800", 196608),
+				},
+				Dlc: map[string]db.ExtendedFileInfo{"0100000000011001": newTestFile(t, folder, "dlc.nsp", "0100000000011001", 0)},
+			}}}
+			titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+				"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}},
+			}}
+
+			OrganizeByFolders(folder, localDB, titlesDB, nil)
+
+			var files []string
+			_ = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() && filepath.Ext(path) == ".nsp" {
+					relative, _ := filepath.Rel(folder, path)
+					files = append(files, relative)
+				}
+				return nil
+			})
+			sort.Strings(files)
+			if !reflect.DeepEqual(files, test.wantFiles) {
+				t.Errorf("files %v, want %v", files, test.wantFiles)
+			}
+			var versions []int
+			for version := range localDB.TitlesMap["010000000001"].Updates {
+				versions = append(versions, version)
+			}
+			sort.Ints(versions)
+			if !reflect.DeepEqual(versions, test.wantVersions) {
+				t.Errorf("versions %v, want %v", versions, test.wantVersions)
+			}
+		})
+	}
+}
+
+func TestChangesNeedApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		requireApply bool
+		apply        bool
+		wantChanged  bool
+	}{
+		{"required, not applied", true, false, false},
+		{"required, applied", true, true, true},
+		{"not required", false, false, true},
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := newTestFolder(t)
+			settingsObj := useTestSettings(t, newTestFolder(t))
+			settingsObj.OrganizeOptions.CreateFolderPerGame = true
+			settingsObj.OrganizeOptions.DeleteOldUpdateFiles = true
+			settingsObj.RequireApply = test.requireApply
+			settingsObj.Apply = test.apply
+
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+				File:      newTestFile(t, folder, "base.nsp", "0100000000010000", 0),
+				BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{
+					65536:  newTestFile(t, folder, "v1.nsp", "0100000000010800", 65536),
+					131072: newTestFile(t, folder, "v2.nsp", "0100000000010800", 131072),
+				},
+			}}}
+			titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+				"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}},
+			}}
+
+			if got := ChangesNotApplied(settingsObj); got == test.wantChanged {
+				t.Errorf("ChangesNotApplied %v, want %v", got, !test.wantChanged)
+			}
+			DeleteOldUpdates(settingsObj, localDB, NewBlacklist(nil))
+			if exists(filepath.Join(folder, "v1.nsp")) == test.wantChanged {
+				t.Errorf("v1.nsp exists: %v, want changes: %v", exists(filepath.Join(folder, "v1.nsp")), test.wantChanged)
+			}
+			OrganizeByFolders(folder, localDB, titlesDB, nil)
+			if exists(filepath.Join(folder, "Game", "base.nsp")) != test.wantChanged {
+				t.Errorf("base.nsp organized: %v, want changes: %v", exists(filepath.Join(folder, "Game", "base.nsp")), test.wantChanged)
+			}
+		})
+	}
+}
+
+func TestExtendedLengthPath(t *testing.T) {
+	long := strings.Repeat("a very long game name ", 12)
+	tests := []struct {
+		name        string
+		path        string
+		wantWindows string
+	}{
+		{"short path", `C:\games\Game\game.nsp`, `C:\games\Game\game.nsp`},
+		{"overlong path", `C:\games\` + long + `\game.nsp`, `\\?\C:\games\` + long + `\game.nsp`},
+		{"overlong UNC path", `\\nas\games\` + long + `\game.nsp`, `\\?\UNC\nas\games\` + long + `\game.nsp`},
+		{"already extended", `\\?\C:\games\` + long + `\game.nsp`, `\\?\C:\games\` + long + `\game.nsp`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//only windows limits the length of paths
+			want := test.path
+			if runtime.GOOS == "windows" {
+				want = test.wantWindows
+			}
+			if got := extendedLengthPath(test.path); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			if isOverlongPath(test.path) != (runtime.GOOS == "windows" && len(test.path) >= WINDOWS_MAX_PATH) {
+				t.Errorf("overlong %v for a path of %v chars", isOverlongPath(test.path), len(test.path))
+			}
+		})
+	}
+}
+
+func TestOrganizeWithinEachScanFolder(t *testing.T) {
+	tests := []struct {
+		name                string
+		createFolderPerGame bool
+		wantFirst           []string
+		wantSecond          []string
+	}{
+		{"folder per game", true,
+			[]string{filepath.Join("Game", "base.nsp"), filepath.Join("Game", "v1.nsp")},
+			[]string{filepath.Join("Game", "dlc.nsp"), filepath.Join("Game", "v2.nsp")}},
+		{"in place", false,
+			[]string{"base.nsp", "v1.nsp"},
+			[]string{"dlc.nsp", filepath.Join("nested", "v2.nsp")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, second := newTestFolder(t), newTestFolder(t)
+			nested := filepath.Join(second, "nested")
+			if err := os.Mkdir(nested, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			settingsObj := useTestSettings(t, newTestFolder(t))
+			settingsObj.OrganizeOptions.CreateFolderPerGame = test.createFolderPerGame
+			settingsObj.OrganizeOptions.DeleteEmptyFolders = true
+			settingsObj.Apply = true
+
+			//the base and the first update are in the first scan folder, the rest of the game in the second
+			localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {
+				File:      newTestFile(t, first, "base.nsp", "0100000000010000", 0),
+				BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{
+					65536:  newTestFile(t, first, "v1.nsp", "0100000000010800", 65536),
+					131072: newTestFile(t, nested, "v2.nsp", "0100000000010800", 131072),
+				},
+				Dlc: map[string]db.ExtendedFileInfo{"0100000000011001": newTestFile(t, second, "dlc.nsp", "0100000000011001", 0)},
+			}}}
+			titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+				"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"}},
+			}}
+
+			OrganizeByScanFolders([]string{first, second}, localDB, titlesDB, nil)
+
+			if got := listFiles(t, first); !reflect.DeepEqual(got, test.wantFirst) {
+				t.Errorf("first folder %v, want %v", got, test.wantFirst)
+			}
+			if got := listFiles(t, second); !reflect.DeepEqual(got, test.wantSecond) {
+				t.Errorf("second folder %v, want %v", got, test.wantSecond)
+			}
+			for _, folder := range []string{first, second} {
+				if fileExists(filepath.Join(folder, ORGANIZE_JOURNAL_FILENAME)) {
+					t.Errorf("the organize journal of %v was not deleted", folder)
+				}
+			}
+			if test.createFolderPerGame && fileExists(nested) {
+				t.Error("the emptied folder was not deleted")
+			}
+		})
+	}
+}
+
+func TestScanFolderOf(t *testing.T) {
+	scanFolders := []string{filepath.Join("library", "games"), filepath.Join("other", "library")}
+	tests := []struct {
+		name   string
+		folder string
+		want   string
+	}{
+		{"scan folder", filepath.Join("library", "games"), filepath.Join("library", "games")},
+		{"sub folder", filepath.Join("other", "library", "Game", "updates"), filepath.Join("other", "library")},
+		{"similar name", filepath.Join("library", "games2"), filepath.Join("library", "games2")},
+		{"outside", "elsewhere", "elsewhere"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := scanFolderOf(test.folder, scanFolders); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
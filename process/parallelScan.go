@@ -0,0 +1,52 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"runtime"
+	"sync"
+)
+
+// scanTitles runs scan on each local title, spread over workers goroutines (one per CPU when workers <= 0).
+// each worker fills its own result map, and the maps are merged once all the workers are done. the keys are
+// titleIds, unique to a single local title, so the result does not depend on the number of workers.
+func scanTitles(localDB map[string]*db.SwitchFile, workers int,
+	scan func(idPrefix string, switchFile *db.SwitchFile, result map[string]IncompleteTitle)) map[string]IncompleteTitle {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers == 1 || len(localDB) < 2 {
+		result := map[string]IncompleteTitle{}
+		for idPrefix, switchFile := range localDB {
+			scan(idPrefix, switchFile, result)
+		}
+		return result
+	}
+
+	idPrefixes := make(chan string, len(localDB))
+	for idPrefix := range localDB {
+		idPrefixes <- idPrefix
+	}
+	close(idPrefixes)
+
+	results := make([]map[string]IncompleteTitle, workers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		results[i] = map[string]IncompleteTitle{}
+		wg.Add(1)
+		go func(result map[string]IncompleteTitle) {
+			defer wg.Done()
+			for idPrefix := range idPrefixes {
+				scan(idPrefix, localDB[idPrefix], result)
+			}
+		}(results[i])
+	}
+	wg.Wait()
+
+	merged := map[string]IncompleteTitle{}
+	for _, result := range results {
+		for k, v := range result {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	//trademark symbols are common in eshop names, and can simply be dropped
+	droppedSymbolsReplacer = strings.NewReplacer("™", "", "®", "", "©", "")
+)
+
+type ProblematicFileName struct {
+	File          db.ExtendedFileInfo
+	SuggestedName string
+}
+
+// ScanForProblematicFileNames lists local files whose names contain non-ASCII or filesystem unsafe characters
+func ScanForProblematicFileNames(localDB map[string]*db.SwitchFile) []ProblematicFileName {
+	var result []ProblematicFileName
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			name := f.Info.Name()
+			if !isProblematicFileName(name) {
+				continue
+			}
+			result = append(result, ProblematicFileName{File: f, SuggestedName: sanitizeFileName(name)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File.Info.Name() < result[j].File.Info.Name()
+	})
+	return result
+}
+
+func isProblematicFileName(name string) bool {
+	if folderIllegalCharsRegex.MatchString(name) {
+		return true
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeFileName(name string) string {
+	name = droppedSymbolsReplacer.Replace(name)
+	name = folderIllegalCharsRegex.ReplaceAllString(name, "-")
+	name = strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.ReplaceAll(name, "  ", " ")
+}
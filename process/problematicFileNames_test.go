@@ -0,0 +1,77 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name            string
+		wantProblematic bool
+		want            string
+	}{
+		{"Game [0100000000010000][v0].nsp", false, "Game [0100000000010000][v0].nsp"},
+		{"Game™ [0100000000010000][v0].nsp", true, "Game [0100000000010000][v0].nsp"},
+		{"Game® Deluxe©.nsp", true, "Game Deluxe.nsp"},
+		{"Pokémon.nsp", true, "Pok_mon.nsp"},
+		{"Game: Part 2?.nsp", true, "Game- Part 2-.nsp"},
+		{"Game \"Edition\" <1|2>*.nsp", true, "Game -Edition- -1-2--.nsp"},
+		{"Game\tTab.nsp", true, "Game_Tab.nsp"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isProblematicFileName(test.name); got != test.wantProblematic {
+				t.Errorf("got problematic %v, want %v", got, test.wantProblematic)
+			}
+			if got := sanitizeFileName(test.name); got != test.want {
+				t.Errorf("got sanitized name %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanForProblematicFileNames(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	file := func(name string) db.ExtendedFileInfo {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.ExtendedFileInfo{Info: info, BaseFolder: folder}
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {
+			File:      file("Pokémon [0100000000010000][v0].nsp"),
+			BaseExist: true,
+			Updates:   map[int]db.ExtendedFileInfo{65536: file("Pokémon [0100000000010800][v65536].nsp")},
+			Dlc:       map[string]db.ExtendedFileInfo{"0100000000011001": file("Pokemon DLC [0100000000011001][v0].nsp")},
+		},
+		"010000000002": {File: file("Game™ [0100000000020000][v0].nsp"), BaseExist: true},
+	}
+
+	var got [][]string
+	for _, problematic := range ScanForProblematicFileNames(localDB) {
+		got = append(got, []string{problematic.File.Info.Name(), problematic.SuggestedName})
+	}
+	want := [][]string{
+		{"Game™ [0100000000020000][v0].nsp", "Game [0100000000020000][v0].nsp"},
+		{"Pokémon [0100000000010000][v0].nsp", "Pok_mon [0100000000010000][v0].nsp"},
+		{"Pokémon [0100000000010800][v65536].nsp", "Pok_mon [0100000000010800][v65536].nsp"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"time"
+)
+
+// FindRecentlyAdded lists the local files modified within the last days, newest first
+func FindRecentlyAdded(localDB map[string]*db.SwitchFile, days int, now time.Time) []db.ExtendedFileInfo {
+	var result []db.ExtendedFileInfo
+	since := now.AddDate(0, 0, -days)
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			if f.Info.ModTime().After(since) {
+				result = append(result, f)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Info.ModTime().Equal(result[j].Info.ModTime()) {
+			return result[i].Info.Name() < result[j].Info.Name()
+		}
+		return result[i].Info.ModTime().After(result[j].Info.ModTime())
+	})
+	return result
+}
@@ -0,0 +1,70 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestFindRecentlyAdded(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-recent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	//a local file of the given titleId, modified the given hours before now
+	file := func(name string, titleId string, hoursAgo int) db.ExtendedFileInfo {
+		filePath := filepath.Join(folder, name)
+		if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-time.Duration(hoursAgo) * time.Hour)
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.ExtendedFileInfo{Info: info, BaseFolder: folder, Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}}
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {
+			File:      file("game one.nsp", "0100000000010000", 30*24),
+			BaseExist: true,
+			Updates:   map[int]db.ExtendedFileInfo{65536: file("game one v1.nsp", "0100000000010800", 2*24)},
+			Dlc:       map[string]db.ExtendedFileInfo{"0100000000011001": file("game one dlc.nsp", "0100000000011001", 5*24)},
+		},
+		"010000000002": {File: file("game two.nsp", "0100000000020000", 24), BaseExist: true},
+		"010000000003": {File: file("game three.nsp", "0100000000030000", 12), BaseExist: true},
+	}
+
+	tests := []struct {
+		name string
+		days int
+		want []string
+	}{
+		{"no window", 0, nil},
+		{"last week", 7, []string{"game three.nsp", "game two.nsp", "game one v1.nsp", "game one dlc.nsp"}},
+		{"last 3 days", 3, []string{"game three.nsp", "game two.nsp", "game one v1.nsp"}},
+		{"last year", 365, []string{"game three.nsp", "game two.nsp", "game one v1.nsp", "game one dlc.nsp", "game one.nsp"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, f := range FindRecentlyAdded(localDB, test.days, now) {
+				got = append(got, f.Info.Name())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
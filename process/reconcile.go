@@ -0,0 +1,95 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io"
+	"sort"
+	"strings"
+)
+
+type InventoryItem struct {
+	TitleId string `json:"title_id"`
+	Version int    `json:"version"`
+}
+
+type VersionMismatch struct {
+	TitleId          string `json:"title_id"`
+	LocalVersion     int    `json:"local_version"`
+	InventoryVersion int    `json:"inventory_version"`
+}
+
+type ReconcileResult struct {
+	MissingHere     []InventoryItem   `json:"missing_here"`
+	ExtraHere       []InventoryItem   `json:"extra_here"`
+	VersionMismatch []VersionMismatch `json:"version_mismatch"`
+}
+
+// ReadInventory parses a json list of titleId + version entries
+func ReadInventory(reader io.Reader) ([]InventoryItem, error) {
+	var items []InventoryItem
+	err := json.NewDecoder(reader).Decode(&items)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// LocalInventory lists every titleId present locally, with the latest version found for it
+func LocalInventory(localDB map[string]*db.SwitchFile) []InventoryItem {
+	versions := map[string]int{}
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			if f.Metadata == nil {
+				continue
+			}
+			titleId := strings.ToLower(f.Metadata.TitleId)
+			if v, ok := versions[titleId]; !ok || f.Metadata.Version > v {
+				versions[titleId] = f.Metadata.Version
+			}
+		}
+	}
+	result := make([]InventoryItem, 0, len(versions))
+	for titleId, version := range versions {
+		result = append(result, InventoryItem{TitleId: titleId, Version: version})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+// ReconcileInventory compares the local library against an inventory exported from another machine
+func ReconcileInventory(localDB map[string]*db.SwitchFile, inventory []InventoryItem) ReconcileResult {
+	return CompareInventories(LocalInventory(localDB), inventory)
+}
+
+// CompareInventories compares two titleId + version lists, from the point of view of the local one
+func CompareInventories(localInventory []InventoryItem, inventory []InventoryItem) ReconcileResult {
+	result := ReconcileResult{}
+	local := map[string]int{}
+	for _, item := range localInventory {
+		local[strings.ToLower(item.TitleId)] = item.Version
+	}
+	expected := map[string]int{}
+	for _, item := range inventory {
+		titleId := strings.ToLower(item.TitleId)
+		expected[titleId] = item.Version
+		localVersion, ok := local[titleId]
+		if !ok {
+			result.MissingHere = append(result.MissingHere, InventoryItem{TitleId: titleId, Version: item.Version})
+		} else if localVersion != item.Version {
+			result.VersionMismatch = append(result.VersionMismatch,
+				VersionMismatch{TitleId: titleId, LocalVersion: localVersion, InventoryVersion: item.Version})
+		}
+	}
+	for titleId, version := range local {
+		if _, ok := expected[titleId]; !ok {
+			result.ExtraHere = append(result.ExtraHere, InventoryItem{TitleId: titleId, Version: version})
+		}
+	}
+	sort.Slice(result.MissingHere, func(i, j int) bool { return result.MissingHere[i].TitleId < result.MissingHere[j].TitleId })
+	sort.Slice(result.ExtraHere, func(i, j int) bool { return result.ExtraHere[i].TitleId < result.ExtraHere[j].TitleId })
+	sort.Slice(result.VersionMismatch, func(i, j int) bool { return result.VersionMismatch[i].TitleId < result.VersionMismatch[j].TitleId })
+	return result
+}
@@ -0,0 +1,76 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestReconcileInventory(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	file := func(titleId string, version int) db.ExtendedFileInfo {
+		filePath := filepath.Join(folder, fmt.Sprintf("[%v][v%v].nsp", titleId, version))
+		if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.ExtendedFileInfo{Info: info, BaseFolder: folder, Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version}}
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {
+			File:      file("0100000000010000", 0),
+			BaseExist: true,
+			Updates: map[int]db.ExtendedFileInfo{
+				65536:  file("0100000000010800", 65536),
+				131072: file("0100000000010800", 131072),
+			},
+			Dlc: map[string]db.ExtendedFileInfo{"0100000000011001": file("0100000000011001", 0)},
+		},
+		"010000000002": {File: file("0100000000020000", 0), BaseExist: true},
+	}
+	inventory, err := ReadInventory(strings.NewReader(`[
+		{"title_id": "0100000000010000", "version": 0},
+		{"title_id": "0100000000010800", "version": 196608},
+		{"title_id": "0100000000011001", "version": 0},
+		{"title_id": "0100000000030000", "version": 0},
+		{"title_id": "0100000000030800", "version": 65536}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := ReconcileInventory(localDB, inventory)
+	want := ReconcileResult{
+		MissingHere:     []InventoryItem{{TitleId: "0100000000030000", Version: 0}, {TitleId: "0100000000030800", Version: 65536}},
+		ExtraHere:       []InventoryItem{{TitleId: "0100000000020000", Version: 0}},
+		VersionMismatch: []VersionMismatch{{TitleId: "0100000000010800", LocalVersion: 131072, InventoryVersion: 196608}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	//the local inventory reconciles with itself
+	if got := ReconcileInventory(localDB, LocalInventory(localDB)); !reflect.DeepEqual(got, ReconcileResult{}) {
+		t.Errorf("got %+v reconciling the local inventory, want no difference", got)
+	}
+}
+
+func TestReadInventoryRejectsInvalidJson(t *testing.T) {
+	if _, err := ReadInventory(strings.NewReader(`{"title_id": "0100000000010000"}`)); err == nil {
+		t.Error("read an inventory that is not a list")
+	}
+}
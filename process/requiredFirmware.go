@@ -0,0 +1,28 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+)
+
+type RequiredFirmware struct {
+	Version int
+	File    db.ExtendedFileInfo
+}
+
+// FindRequiredFirmware returns the highest firmware version required by the local base games and updates,
+// or nil when unknown (the required firmware is only available when the files were deep scanned)
+func FindRequiredFirmware(localDB map[string]*db.SwitchFile) *RequiredFirmware {
+	var result *RequiredFirmware
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			if f.Metadata == nil || f.Metadata.RequiredSystemVersion == 0 {
+				continue
+			}
+			if result == nil || f.Metadata.RequiredSystemVersion > result.Version ||
+				(f.Metadata.RequiredSystemVersion == result.Version && f.Info.Name() < result.File.Info.Name()) {
+				result = &RequiredFirmware{Version: f.Metadata.RequiredSystemVersion, File: f}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestFindRequiredFirmware(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-firmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	//a deep scanned file, requiring the given system version
+	file := func(name string, titleId string, requiredSystemVersion int) db.ExtendedFileInfo {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.ExtendedFileInfo{Info: info, BaseFolder: folder,
+			Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId, RequiredSystemVersion: requiredSystemVersion}}
+	}
+	//the firmware versions 10.0.0, 11.0.1 and 9.2.0
+	v10, v11, v9 := 10<<26, 11<<26|1<<16, 9<<26|2<<20
+
+	tests := []struct {
+		name        string
+		localDB     map[string]*db.SwitchFile
+		wantVersion int
+		wantFile    string
+	}{
+		{"tagged files only", map[string]*db.SwitchFile{
+			"010000000001": {File: file("game.nsp", "0100000000010000", 0), BaseExist: true},
+		}, 0, ""},
+		{"update requiring a newer firmware than its base", map[string]*db.SwitchFile{
+			"010000000001": {File: file("game.nsp", "0100000000010000", v10), BaseExist: true,
+				Updates: map[int]db.ExtendedFileInfo{65536: file("game v1.nsp", "0100000000010800", v11)}},
+			"010000000002": {File: file("other.nsp", "0100000000020000", v9), BaseExist: true},
+		}, v11, "game v1.nsp"},
+		{"base games", map[string]*db.SwitchFile{
+			"010000000001": {File: file("game.nsp", "0100000000010000", v9), BaseExist: true},
+			"010000000002": {File: file("other.nsp", "0100000000020000", v10), BaseExist: true},
+			"010000000003": {File: file("tagged.nsp", "0100000000030000", 0), BaseExist: true},
+		}, v10, "other.nsp"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := FindRequiredFirmware(test.localDB)
+			if test.wantVersion == 0 {
+				if got != nil {
+					t.Errorf("got required firmware %v, want none", got.Version)
+				}
+				return
+			}
+			if got == nil || got.Version != test.wantVersion || got.File.Info.Name() != test.wantFile {
+				t.Errorf("got %+v, want version %v of %v", got, test.wantVersion, test.wantFile)
+			}
+		})
+	}
+	if got := switchfs.FirmwareVersionString(v11); got != "11.0.1" {
+		t.Errorf("got firmware %v, want 11.0.1", got)
+	}
+}
@@ -0,0 +1,120 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunSnapshot is the state of the library recorded by a run, to report what changed on the next one
+type RunSnapshot struct {
+	Time time.Time `json:"time"`
+	//base titleIds owned locally
+	Titles []string `json:"titles"`
+	//titleId -> latest available update, for the titles with a missing update
+	MissingUpdates map[string]int `json:"missing_updates"`
+	//DLC titleIds of the owned base games, missing / owned locally
+	MissingDLC []string `json:"missing_dlc"`
+	OwnedDLC   []string `json:"owned_dlc"`
+}
+
+// RunDelta lists the changes between two snapshots
+type RunDelta struct {
+	//base titleIds added to the library
+	NewTitles []string
+	//titleId -> latest available update, for the updates which became available (or newer)
+	NewUpdates map[string]int
+	//DLC titleIds missing now, which were not known before (newly released, or of a new title)
+	NewMissingDLC []string
+	//DLC titleIds owned now, which were not before
+	AcquiredDLC []string
+}
+
+func (d RunDelta) IsEmpty() bool {
+	return len(d.NewTitles) == 0 && len(d.NewUpdates) == 0 && len(d.NewMissingDLC) == 0 && len(d.AcquiredDLC) == 0
+}
+
+// TakeRunSnapshot records the library, along with the missing updates / DLC found by
+// ScanForMissingUpdates / ScanForMissingDLC
+func TakeRunSnapshot(localDB map[string]*db.SwitchFile, missingUpdates map[string]IncompleteTitle,
+	missingDLC map[string]IncompleteTitle, now time.Time) RunSnapshot {
+	snapshot := RunSnapshot{Time: now, MissingUpdates: map[string]int{}}
+	for _, switchFile := range localDB {
+		if switchFile.BaseExist && switchFile.File.Metadata != nil {
+			snapshot.Titles = append(snapshot.Titles, strings.ToLower(switchFile.File.Metadata.TitleId))
+		}
+		for id := range switchFile.Dlc {
+			snapshot.OwnedDLC = append(snapshot.OwnedDLC, strings.ToLower(id))
+		}
+	}
+	for _, v := range missingUpdates {
+		snapshot.MissingUpdates[strings.ToLower(v.Attributes.Id)] = v.LatestUpdate
+	}
+	for _, v := range missingDLC {
+		for _, id := range v.MissingDLCIds {
+			snapshot.MissingDLC = append(snapshot.MissingDLC, strings.ToLower(id))
+		}
+	}
+	sort.Strings(snapshot.Titles)
+	sort.Strings(snapshot.OwnedDLC)
+	sort.Strings(snapshot.MissingDLC)
+	return snapshot
+}
+
+// DiffRunSnapshots reports the changes from the previous snapshot to the current one
+func DiffRunSnapshots(previous RunSnapshot, current RunSnapshot) RunDelta {
+	delta := RunDelta{NewTitles: missingFrom(current.Titles, previous.Titles), NewUpdates: map[string]int{},
+		AcquiredDLC: missingFrom(current.OwnedDLC, previous.OwnedDLC)}
+	for id, version := range current.MissingUpdates {
+		if previousVersion, ok := previous.MissingUpdates[id]; !ok || version > previousVersion {
+			delta.NewUpdates[id] = version
+		}
+	}
+	delta.NewMissingDLC = missingFrom(missingFrom(current.MissingDLC, previous.MissingDLC), previous.OwnedDLC)
+	return delta
+}
+
+// the sorted items of values which are not in other
+func missingFrom(values []string, other []string) []string {
+	otherSet := make(map[string]bool, len(other))
+	for _, v := range other {
+		otherSet[v] = true
+	}
+	var result []string
+	for _, v := range values {
+		if !otherSet[v] {
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// LoadRunSnapshot reads the snapshot saved by the previous run, false when there is none
+func LoadRunSnapshot(filePath string) (RunSnapshot, bool, error) {
+	snapshot := RunSnapshot{}
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return snapshot, false, err
+	}
+	return snapshot, true, nil
+}
+
+func SaveRunSnapshot(filePath string, snapshot RunSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
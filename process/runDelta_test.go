@@ -0,0 +1,69 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestDiffRunSnapshots(t *testing.T) {
+	owned := func(titleId string, dlc ...string) *db.SwitchFile {
+		switchFile := &db.SwitchFile{BaseExist: true, File: db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}},
+			Dlc: map[string]db.ExtendedFileInfo{}}
+		for _, id := range dlc {
+			switchFile.Dlc[id] = db.ExtendedFileInfo{}
+		}
+		return switchFile
+	}
+	missing := func(titleId string, latestUpdate int, dlc ...string) IncompleteTitle {
+		return IncompleteTitle{Attributes: db.TitleAttributes{Id: titleId}, LatestUpdate: latestUpdate, MissingDLCIds: dlc}
+	}
+	yesterday := TakeRunSnapshot(
+		map[string]*db.SwitchFile{"010000000001": owned("0100000000010000"), "010000000002": owned("0100000000020000")},
+		map[string]IncompleteTitle{"010000000001": missing("0100000000010000", 65536)},
+		map[string]IncompleteTitle{"010000000002": missing("0100000000020000", 0, "0100000000021001", "0100000000021002")},
+		time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name           string
+		localDB        map[string]*db.SwitchFile
+		missingUpdates map[string]IncompleteTitle
+		missingDLC     map[string]IncompleteTitle
+		want           RunDelta
+	}{
+		{"nothing changed",
+			map[string]*db.SwitchFile{"010000000001": owned("0100000000010000"), "010000000002": owned("0100000000020000")},
+			map[string]IncompleteTitle{"010000000001": missing("0100000000010000", 65536)},
+			map[string]IncompleteTitle{"010000000002": missing("0100000000020000", 0, "0100000000021001", "0100000000021002")},
+			RunDelta{NewUpdates: map[string]int{}}},
+		{"new title, newer update, released and acquired DLC",
+			map[string]*db.SwitchFile{"010000000001": owned("0100000000010000"), "010000000002": owned("0100000000020000", "0100000000021001"),
+				"010000000003": owned("0100000000030000")},
+			map[string]IncompleteTitle{"010000000001": missing("0100000000010000", 131072), "010000000003": missing("0100000000030000", 65536)},
+			map[string]IncompleteTitle{"010000000002": missing("0100000000020000", 0, "0100000000021002", "0100000000021003")},
+			RunDelta{NewTitles: []string{"0100000000030000"},
+				NewUpdates:    map[string]int{"0100000000010000": 131072, "0100000000030000": 65536},
+				NewMissingDLC: []string{"0100000000021003"},
+				AcquiredDLC:   []string{"0100000000021001"}}},
+		{"updated and removed titles are not reported",
+			map[string]*db.SwitchFile{"010000000002": owned("0100000000020000")},
+			map[string]IncompleteTitle{},
+			map[string]IncompleteTitle{"010000000002": missing("0100000000020000", 0, "0100000000021001", "0100000000021002")},
+			RunDelta{NewUpdates: map[string]int{}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			today := TakeRunSnapshot(test.localDB, test.missingUpdates, test.missingDLC, time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC))
+			delta := DiffRunSnapshots(yesterday, today)
+			if !reflect.DeepEqual(delta, test.want) {
+				t.Errorf("got %+v, want %+v", delta, test.want)
+			}
+			if delta.IsEmpty() != reflect.DeepEqual(test.want, RunDelta{NewUpdates: map[string]int{}}) {
+				t.Errorf("IsEmpty %v for %+v", delta.IsEmpty(), delta)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// MergeTitleAliases returns copies of the local and titles DB maps, where each aliased title (such as a regional
+// release sharing its content with another release) is merged into its canonical title.
+// aliases maps the alias base titleId to the canonical base titleId. The alias is removed from the titles DB,
+// and the local files of the alias are merged into the canonical title, so owning the alias counts as owning it.
+func MergeTitleAliases(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, aliases map[string]string) (map[string]*db.SwitchFile, map[string]*db.SwitchTitle) {
+	if len(aliases) == 0 {
+		return localDB, switchDB
+	}
+	mergedLocal := make(map[string]*db.SwitchFile, len(localDB))
+	for k, v := range localDB {
+		mergedLocal[k] = v
+	}
+	mergedTitles := make(map[string]*db.SwitchTitle, len(switchDB))
+	for k, v := range switchDB {
+		mergedTitles[k] = v
+	}
+	for alias, canonical := range aliases {
+		aliasPrefix, ok := titleIdPrefix(alias)
+		canonicalPrefix, ok2 := titleIdPrefix(canonical)
+		if !ok || !ok2 {
+			zap.S().Warnf("Ignoring invalid title alias [%v] -> [%v]", alias, canonical)
+			continue
+		}
+		delete(mergedTitles, aliasPrefix)
+		if switchFile, ok := mergedLocal[aliasPrefix]; ok {
+			delete(mergedLocal, aliasPrefix)
+			if canonicalFile, ok := mergedLocal[canonicalPrefix]; ok {
+				mergedLocal[canonicalPrefix] = mergeSwitchFiles(canonicalFile, switchFile)
+			} else {
+				mergedLocal[canonicalPrefix] = switchFile
+			}
+		}
+	}
+	return mergedLocal, mergedTitles
+}
+
+// mergeSwitchFiles returns a new local title holding the files of both titles. the canonical base is kept
+// when both bases are owned, and the canonical files win on conflicting update versions or DLC titleIds.
+func mergeSwitchFiles(canonical *db.SwitchFile, alias *db.SwitchFile) *db.SwitchFile {
+	merged := &db.SwitchFile{File: canonical.File, BaseExist: canonical.BaseExist, LastModified: canonical.LastModified,
+		Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}}
+	if !canonical.BaseExist && alias.BaseExist {
+		merged.File, merged.BaseExist = alias.File, true
+	}
+	if alias.LastModified.After(merged.LastModified) {
+		merged.LastModified = alias.LastModified
+	}
+	for _, switchFile := range []*db.SwitchFile{alias, canonical} {
+		for version, f := range switchFile.Updates {
+			merged.Updates[version] = f
+		}
+		for id, f := range switchFile.Dlc {
+			merged.Dlc[id] = f
+		}
+	}
+	return merged
+}
+
+// ExcludeDemos returns copies of the local and titles DB maps without the demo / pre-release titles of the DB
+func ExcludeDemos(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle) (map[string]*db.SwitchFile, map[string]*db.SwitchTitle) {
+	filteredLocal := make(map[string]*db.SwitchFile, len(localDB))
+	filteredTitles := make(map[string]*db.SwitchTitle, len(switchDB))
+	for k, v := range switchDB {
+		if !v.Attributes.IsDemo {
+			filteredTitles[k] = v
+		}
+	}
+	for k, v := range localDB {
+		if switchTitle, ok := switchDB[k]; !ok || !switchTitle.Attributes.IsDemo {
+			filteredLocal[k] = v
+		}
+	}
+	return filteredLocal, filteredTitles
+}
+
+func titleIdPrefix(titleId string) (string, bool) {
+	if len(titleId) != 16 {
+		return "", false
+	}
+	titleId = strings.ToLower(titleId)
+	return titleId[0 : len(titleId)-4], true
+}
@@ -0,0 +1,196 @@
+package process
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func sortedKeys(incompleteTitles map[string]IncompleteTitle) []string {
+	var keys []string
+	for k := range incompleteTitles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestTitleAliasesAreHonored(t *testing.T) {
+	//the same game released under a US and a EU titleId, only the EU release (without its update) is owned
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Game", "US", []int{65536}, "0100000000011001"),
+		"010000000002": dbTitle("0100000000020000", "Game", "EU", []int{65536}, "0100000000021001"),
+		"010000000003": dbTitle("0100000000030000", "Other", "US", nil),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000002": localTitle("0100000000020000", true, nil),
+	}
+	tests := []struct {
+		name               string
+		aliases            map[string]string
+		wantCompletion     LibraryCompletion
+		wantStrict         LibraryCompletion
+		wantDLC            LibraryCompletion
+		wantMissingUpdates []string
+		wantMissingDLC     []string
+	}{
+		{"no aliases", nil,
+			LibraryCompletion{Owned: 1, Total: 3, Percent: float32(1) / float32(3) * 100},
+			LibraryCompletion{Owned: 0, Total: 3},
+			LibraryCompletion{Owned: 0, Total: 1},
+			[]string{"0100000000020000"}, []string{"0100000000020000"}},
+		{"EU aliased to US", map[string]string{"0100000000020000": "0100000000010000"},
+			LibraryCompletion{Owned: 1, Total: 2, Percent: 50},
+			LibraryCompletion{Owned: 0, Total: 2},
+			LibraryCompletion{Owned: 0, Total: 1},
+			[]string{"0100000000010000"}, []string{"0100000000010000"}},
+		{"invalid alias is ignored", map[string]string{"0100000000020": "0100000000010000"},
+			LibraryCompletion{Owned: 1, Total: 3, Percent: float32(1) / float32(3) * 100},
+			LibraryCompletion{Owned: 0, Total: 3},
+			LibraryCompletion{Owned: 0, Total: 1},
+			[]string{"0100000000020000"}, []string{"0100000000020000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mergedLocal, mergedTitles := MergeTitleAliases(localDB, switchDB, test.aliases)
+			if got := CalculateCompletion(mergedLocal, mergedTitles, true); got != test.wantCompletion {
+				t.Errorf("completion = %+v, want %+v", got, test.wantCompletion)
+			}
+			if got := CalculateStrictCompletion(mergedLocal, mergedTitles); got != test.wantStrict {
+				t.Errorf("strict completion = %+v, want %+v", got, test.wantStrict)
+			}
+			if got := CalculateDLCCompletion(mergedLocal, mergedTitles); got != test.wantDLC {
+				t.Errorf("DLC completion = %+v, want %+v", got, test.wantDLC)
+			}
+			if got := sortedKeys(ScanForMissingUpdates(mergedLocal, mergedTitles)); !reflect.DeepEqual(got, test.wantMissingUpdates) {
+				t.Errorf("missing updates = %v, want %v", got, test.wantMissingUpdates)
+			}
+			if got := sortedKeys(ScanForMissingDLC(mergedLocal, mergedTitles)); !reflect.DeepEqual(got, test.wantMissingDLC) {
+				t.Errorf("missing DLC = %v, want %v", got, test.wantMissingDLC)
+			}
+			//the inputs are left untouched
+			if len(localDB) != 1 || len(switchDB) != 3 {
+				t.Errorf("the input maps were changed")
+			}
+		})
+	}
+}
+
+func TestOwningBothAliasedReleases(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Game", "US", []int{65536}),
+		"010000000002": dbTitle("0100000000020000", "Game", "EU", []int{65536}),
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, []int{65536}),
+		"010000000002": localTitle("0100000000020000", true, nil),
+	}
+	mergedLocal, mergedTitles := MergeTitleAliases(localDB, switchDB, map[string]string{"0100000000020000": "0100000000010000"})
+	//the canonical release is kept, it already has the latest update
+	if got := CalculateCompletion(mergedLocal, mergedTitles, true); got != (LibraryCompletion{Owned: 1, Total: 1, Percent: 100}) {
+		t.Errorf("completion = %+v, want the title owned once", got)
+	}
+	if got := ScanForMissingUpdates(mergedLocal, mergedTitles); len(got) != 0 {
+		t.Errorf("missing updates = %v, want none", sortedKeys(got))
+	}
+}
+
+func TestMergingTheFilesOfAliasedReleases(t *testing.T) {
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": dbTitle("0100000000010000", "Game", "US", []int{65536, 131072}, "0100000000011001", "0100000000011002"),
+		"010000000002": dbTitle("0100000000020000", "Game", "EU", []int{65536}),
+	}
+	aliases := map[string]string{"0100000000020000": "0100000000010000"}
+	tests := []struct {
+		name        string
+		localDB     map[string]*db.SwitchFile
+		wantBase    string
+		wantUpdates []int
+		wantDLC     []string
+	}{
+		{"canonical has only an update, alias has the base", map[string]*db.SwitchFile{
+			"010000000001": localTitle("0100000000010000", false, []int{131072}),
+			"010000000002": localTitle("0100000000020000", true, nil),
+		}, "0100000000020000", []int{131072}, nil},
+		{"the DLC and updates of both are kept", map[string]*db.SwitchFile{
+			"010000000001": localTitle("0100000000010000", true, nil, "0100000000011001"),
+			"010000000002": localTitle("0100000000020000", true, []int{65536}, "0100000000011002"),
+		}, "0100000000010000", []int{65536}, []string{"0100000000011001", "0100000000011002"}},
+		{"only the alias is owned", map[string]*db.SwitchFile{
+			"010000000002": localTitle("0100000000020000", true, []int{65536}),
+		}, "0100000000020000", []int{65536}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mergedLocal, mergedTitles := MergeTitleAliases(test.localDB, switchDB, aliases)
+			switchFile, ok := mergedLocal["010000000001"]
+			if !ok || len(mergedLocal) != 1 {
+				t.Fatalf("got local titles %v, want the canonical title only", mergedLocal)
+			}
+			if !switchFile.BaseExist || switchFile.File.Metadata.TitleId != test.wantBase {
+				t.Errorf("base = %v (owned %v), want %v", switchFile.File.Metadata, switchFile.BaseExist, test.wantBase)
+			}
+			var updates []int
+			for version := range switchFile.Updates {
+				updates = append(updates, version)
+			}
+			sort.Ints(updates)
+			if !reflect.DeepEqual(updates, test.wantUpdates) {
+				t.Errorf("updates = %v, want %v", updates, test.wantUpdates)
+			}
+			var dlc []string
+			for id := range switchFile.Dlc {
+				dlc = append(dlc, id)
+			}
+			sort.Strings(dlc)
+			if !reflect.DeepEqual(dlc, test.wantDLC) {
+				t.Errorf("DLC = %v, want %v", dlc, test.wantDLC)
+			}
+			if got := CalculateCompletion(mergedLocal, mergedTitles, false); got.Owned != 1 {
+				t.Errorf("completion = %+v, want the title owned", got)
+			}
+			//the input maps and titles are left untouched
+			if canonical, ok := test.localDB["010000000001"]; ok && len(canonical.Dlc) > 1 {
+				t.Errorf("the canonical title was changed")
+			}
+		})
+	}
+}
+
+func TestExcludeDemos(t *testing.T) {
+	titles := `{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game", "region": "US"},
+		"0100000000020000": {"id": "0100000000020000", "name": "Game Demo", "region": "US", "isDemo": true},
+		"0100000000030000": {"id": "0100000000030000", "name": "Other", "region": "US"}
+	}`
+	titlesDB, err := db.CreateSwitchTitleDB(strings.NewReader(titles), strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !titlesDB.TitlesMap["010000000002"].Attributes.IsDemo || titlesDB.TitlesMap["010000000001"].Attributes.IsDemo {
+		t.Fatalf("got titles %+v, want only the demo flagged", titlesDB.TitlesMap)
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": localTitle("0100000000010000", true, nil),
+		"010000000002": localTitle("0100000000020000", true, nil),
+		//not in the titles DB
+		"010000000009": localTitle("0100000000090000", true, nil),
+	}
+
+	if got, want := CalculateCompletion(localDB, titlesDB.TitlesMap, false), (LibraryCompletion{Owned: 2, Total: 3, Percent: float32(2) / 3 * 100}); got != want {
+		t.Errorf("got %+v with the demos, want %+v", got, want)
+	}
+	filteredLocal, filteredTitles := ExcludeDemos(localDB, titlesDB.TitlesMap)
+	if got, want := CalculateCompletion(filteredLocal, filteredTitles, false), (LibraryCompletion{Owned: 1, Total: 2, Percent: 50}); got != want {
+		t.Errorf("got %+v without the demos, want %+v", got, want)
+	}
+	if _, ok := filteredLocal["010000000009"]; !ok || len(filteredLocal) != 2 {
+		t.Errorf("got local titles %v, want only the demo removed", filteredLocal)
+	}
+	if len(localDB) != 3 || len(titlesDB.TitlesMap) != 3 {
+		t.Error("the demos were removed from the original maps")
+	}
+}
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type TitleIdMismatch struct {
+	File db.ExtendedFileInfo
+	//the [titleId] tag of the file name
+	FileNameTitleId string
+	//the titleId read from the file content
+	MetadataTitleId string
+}
+
+// ScanForTitleIdMismatches lists the files whose [titleId] name tag differs from the titleId read from their content.
+// when the files were identified by their name (no keys or deep scan disabled), the two always match.
+func ScanForTitleIdMismatches(localDB map[string]*db.SwitchFile) []TitleIdMismatch {
+	var result []TitleIdMismatch
+	for _, switchFile := range localDB {
+		for _, f := range switchFile.Files() {
+			//archives hold several titles, so their name cannot match all of them
+			extension := strings.ToLower(filepath.Ext(f.Info.Name()))
+			if f.Metadata == nil || (extension != ".nsp" && extension != ".nsz" && extension != ".xci") {
+				continue
+			}
+			nameTitleId, err := db.ParseTitleIdFromFileName(f.Info.Name())
+			if err != nil {
+				continue
+			}
+			if !strings.EqualFold(*nameTitleId, f.Metadata.TitleId) {
+				result = append(result, TitleIdMismatch{File: f, FileNameTitleId: *nameTitleId, MetadataTitleId: f.Metadata.TitleId})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File.Info.Name() < result[j].File.Info.Name()
+	})
+	return result
+}
@@ -0,0 +1,41 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+)
+
+func TestScanForTitleIdMismatches(t *testing.T) {
+	folder := newTestFolder(t)
+	//the metadata stands for the titleId read from the content of the file
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": {BaseExist: true, File: newTestFile(t, folder, "Game [0100000000010000][v0].nsp", "0100000000010000", 0),
+			Updates: map[int]db.ExtendedFileInfo{
+				65536: newTestFile(t, folder, "Game [0100000000020800][v65536].nsp", "0100000000010800", 65536),
+			},
+			Dlc: map[string]db.ExtendedFileInfo{
+				"0100000000011001": newTestFile(t, folder, "Game DLC [0100000000011001][v0].NSZ", "0100000000011001", 0),
+			}},
+		"010000000003": {BaseExist: true, File: newTestFile(t, folder, "Renamed [0100000000040000][v0].xci", "0100000000030000", 0)},
+		//no titleId tag to contradict
+		"010000000005": {BaseExist: true, File: newTestFile(t, folder, "Untagged.nsp", "0100000000050000", 0)},
+		//archives hold several titles
+		"010000000006": {BaseExist: true, File: newTestFile(t, folder, "Bundle [0100000000070000].zip", "0100000000060000", 0)},
+		//the tag case differs from the content titleId
+		"01000000000a": {BaseExist: true, File: newTestFile(t, folder, "Lower [01000000000a0000][v0].nsp", "01000000000A0000", 0)},
+	}
+
+	var got [][]string
+	for _, mismatch := range ScanForTitleIdMismatches(localDB) {
+		got = append(got, []string{mismatch.File.Info.Name(), mismatch.FileNameTitleId, mismatch.MetadataTitleId})
+	}
+	want := [][]string{
+		{"Game [0100000000020800][v65536].nsp", "0100000000020800", "0100000000010800"},
+		{"Renamed [0100000000040000][v0].xci", "0100000000040000", "0100000000030000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got mismatches %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	nameTagsRegex = regexp.MustCompile(`[\[(][^\])]*[\])]`)
+)
+
+type UnrecognizedTitle struct {
+	File       db.ExtendedFileInfo
+	TitleId    string
+	ParsedName string
+	//the closest titles DB entry by name, nil when nothing is similar enough
+	Suggestion *db.TitleAttributes
+	Similarity float64
+	//the file is newer than the titles DB, so the title was likely released after the DB was refreshed
+	LikelyNewRelease bool
+}
+
+// ScanForUnrecognizedTitles lists the local titles that are missing from the titles DB, suggesting the DB entry
+// whose name is at least threshold (0-1) similar to the name parsed from the local file
+func ScanForUnrecognizedTitles(localDB map[string]*db.SwitchFile, switchDB map[string]*db.SwitchTitle, threshold float64) []UnrecognizedTitle {
+	var result []UnrecognizedTitle
+	for k, switchFile := range localDB {
+		if _, ok := switchDB[k]; ok {
+			continue
+		}
+		files := switchFile.Files()
+		if len(files) == 0 {
+			continue
+		}
+		file := files[0]
+		titleId := ""
+		if file.Metadata != nil {
+			titleId = file.Metadata.TitleId
+		}
+		parsedName := parseTitleName(file.Info.Name())
+		unrecognized := UnrecognizedTitle{File: file, TitleId: titleId, ParsedName: parsedName}
+		if parsedName != "" {
+			unrecognized.Suggestion, unrecognized.Similarity = findClosestTitle(parsedName, switchDB, threshold)
+		}
+		result = append(result, unrecognized)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ParsedName == result[j].ParsedName {
+			return result[i].File.Info.Name() < result[j].File.Info.Name()
+		}
+		return result[i].ParsedName < result[j].ParsedName
+	})
+	return result
+}
+
+// MarkNewReleases flags the unrecognized titles whose file was modified after the titles DB was refreshed,
+// or up to grace before it (new titles take a while to be added to the DB)
+func MarkNewReleases(unrecognized []UnrecognizedTitle, dbRefreshed time.Time, grace time.Duration) {
+	since := dbRefreshed.Add(-grace)
+	for i := range unrecognized {
+		unrecognized[i].LikelyNewRelease = unrecognized[i].File.Info.ModTime().After(since)
+	}
+}
+
+// strips the extension and the [tags]/(tags) from a file name
+func parseTitleName(fileName string) string {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	name = nameTagsRegex.ReplaceAllString(name, "")
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func findClosestTitle(name string, switchDB map[string]*db.SwitchTitle, threshold float64) (*db.TitleAttributes, float64) {
+	var best *db.TitleAttributes
+	bestSimilarity := 0.0
+	normalizedName := normalizeTitleName(name)
+	for _, title := range switchDB {
+		if title.Attributes.Name == "" {
+			continue
+		}
+		similarity := nameSimilarity(normalizedName, normalizeTitleName(title.Attributes.Name))
+		if similarity < threshold || similarity < bestSimilarity {
+			continue
+		}
+		//equally similar titles are picked by titleId, so the suggestion does not depend on the map order
+		if best != nil && similarity == bestSimilarity && title.Attributes.Id >= best.Id {
+			continue
+		}
+		attributes := title.Attributes
+		best = &attributes
+		bestSimilarity = similarity
+	}
+	return best, bestSimilarity
+}
+
+// lower case letters and digits only, so punctuation and trademark symbols don't count as differences
+func normalizeTitleName(name string) string {
+	name = droppedSymbolsReplacer.Replace(strings.ToLower(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// 1 - the levenshtein distance relative to the longest name
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	if maxLen == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestScanForUnrecognizedTitles(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-unrecognized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	//a local base game of the given titleId, named by the file name
+	title := func(name string, titleId string) *db.SwitchFile {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &db.SwitchFile{BaseExist: true, File: db.ExtendedFileInfo{Info: info, BaseFolder: folder,
+			Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}}}
+	}
+	switchDB := map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "The Legend of Zelda: Breath of the Wild"}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Super Mario Odyssey™"}},
+		"010000000003": {Attributes: db.TitleAttributes{Id: "0100000000030000", Name: "Celeste"}},
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000003": title("Celeste [0100000000030000][v0].nsp", "0100000000030000"),
+		//a bad titleId tag
+		"010000000009": title("The Legend of Zelda Breath of the Wlid [0100000000090000][v0].nsp", "0100000000090000"),
+		"010000000008": title("Super Mario Odysey [0100000000080000][v0] (US).nsp", "0100000000080000"),
+		"010000000007": title("Unknown Homebrew [0100000000070000][v0].nsp", "0100000000070000"),
+	}
+
+	tests := []struct {
+		name       string
+		threshold  float64
+		parsedName string
+		suggestion string
+	}{
+		{"misspelled name", 0.8, "The Legend of Zelda Breath of the Wlid", "0100000000010000"},
+		{"missing letter and trademark", 0.8, "Super Mario Odysey", "0100000000020000"},
+		{"nothing similar", 0.8, "Unknown Homebrew", ""},
+		{"stricter threshold", 0.99, "Super Mario Odysey", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unrecognized := ScanForUnrecognizedTitles(localDB, switchDB, test.threshold)
+			if len(unrecognized) != 3 {
+				t.Fatalf("got %v unrecognized titles, want the 3 missing from the titles DB", len(unrecognized))
+			}
+			for _, title := range unrecognized {
+				if title.ParsedName != test.parsedName {
+					continue
+				}
+				suggestion := ""
+				if title.Suggestion != nil {
+					suggestion = title.Suggestion.Id
+				}
+				if suggestion != test.suggestion {
+					t.Errorf("got suggestion %q (similarity %v), want %q", suggestion, title.Similarity, test.suggestion)
+				}
+				return
+			}
+			t.Errorf("no unrecognized title named %q in %v", test.parsedName, unrecognized)
+		})
+	}
+}
+
+func TestMarkNewReleases(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-unrecognized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	dbRefreshed := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	//an unrecognized title of a file modified at the given time
+	title := func(name string, modTime time.Time) UnrecognizedTitle {
+		filePath := filepath.Join(folder, name)
+		if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return UnrecognizedTitle{File: db.ExtendedFileInfo{Info: info, BaseFolder: folder}}
+	}
+	tests := []struct {
+		name    string
+		modTime time.Time
+		grace   time.Duration
+		want    bool
+	}{
+		{"file newer than the titles DB", dbRefreshed.Add(time.Hour), 0, true},
+		{"file older than the titles DB", dbRefreshed.Add(-time.Hour), 0, false},
+		{"file older than the titles DB within the grace", dbRefreshed.Add(-24 * time.Hour), 2 * 24 * time.Hour, true},
+		{"file older than the grace", dbRefreshed.Add(-3 * 24 * time.Hour), 2 * 24 * time.Hour, false},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unrecognized := []UnrecognizedTitle{title(fmt.Sprintf("title%v.nsp", i), test.modTime)}
+			MarkNewReleases(unrecognized, dbRefreshed, test.grace)
+			if unrecognized[0].LikelyNewRelease != test.want {
+				t.Errorf("got likely new release %v, want %v", unrecognized[0].LikelyNewRelease, test.want)
+			}
+		})
+	}
+}
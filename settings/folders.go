@@ -0,0 +1,96 @@
+package settings
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const FOLDERS_FILENAME = "folders.txt"
+
+// ParseFoldersFile reads one folder per line, skipping blank lines and lines starting with #
+func ParseFoldersFile(reader io.Reader) ([]string, error) {
+	var folders []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		folders = append(folders, line)
+	}
+	return folders, scanner.Err()
+}
+
+// ParseFolderList splits a comma separated list of folders (such as the -f flag). a doubled comma stands for
+// a comma in a path, and a value which is an existing path as is is never split.
+func ParseFolderList(value string) []string {
+	if _, err := os.Stat(value); err == nil {
+		return []string{value}
+	}
+	var folders []string
+	seen := map[string]bool{}
+	var current strings.Builder
+	add := func() {
+		folder := strings.TrimSpace(current.String())
+		current.Reset()
+		if folder == "" || seen[filepath.Clean(folder)] {
+			return
+		}
+		seen[filepath.Clean(folder)] = true
+		folders = append(folders, folder)
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != ',' {
+			current.WriteByte(value[i])
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == ',' {
+			current.WriteByte(',')
+			i++
+			continue
+		}
+		add()
+	}
+	add()
+	return folders
+}
+
+// ScanFolders returns the folders to scan - the folder from the settings, followed by the ones listed
+// in the folders file (folders.txt in the base folder, unless folders_file is set)
+func ScanFolders(baseFolder string, settingsObj *AppSettings) ([]string, error) {
+	var folders []string
+	if settingsObj.Folder != "" {
+		folders = append(folders, settingsObj.Folder)
+	}
+	foldersFile := settingsObj.FoldersFile
+	if foldersFile == "" {
+		foldersFile = filepath.Join(baseFolder, FOLDERS_FILENAME)
+		if _, err := os.Stat(foldersFile); err != nil {
+			return folders, nil
+		}
+	}
+	file, err := os.Open(foldersFile)
+	if err != nil {
+		return folders, err
+	}
+	defer file.Close()
+	listed, err := ParseFoldersFile(file)
+	if err != nil {
+		return folders, err
+	}
+	seen := map[string]bool{}
+	for _, folder := range folders {
+		seen[filepath.Clean(folder)] = true
+	}
+	for _, folder := range listed {
+		if seen[filepath.Clean(folder)] {
+			continue
+		}
+		seen[filepath.Clean(folder)] = true
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
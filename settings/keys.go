@@ -1,13 +1,25 @@
 package settings
 
 import (
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/magiconair/properties"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 var (
 	keysInstance *switchKeys
+	keysContent  string
+)
+
+const (
+	PROD_KEYS_FILENAME  = "prod.keys"
+	TITLE_KEYS_FILENAME = "title.keys"
+	KEYS_ENV_VAR        = "SLM_KEYS"
 )
 
 type switchKeys struct {
@@ -22,21 +34,117 @@ func SwitchKeys() (*switchKeys, error) {
 	return keysInstance, nil
 }
 
+// SetKeysContent supplies the keys directly (in the prod.keys format), instead of reading them from the environment or files
+func SetKeysContent(content string) {
+	keysContent = content
+	keysInstance = nil
+}
+
+// InitSwitchKeys loads the keys, by order of precedence, from the content set by SetKeysContent (the command line),
+// the SLM_KEYS environment variable, or the files - the keys_files from the settings, or when not set,
+// prod.keys and title.keys (each found in the app folder or under ${HOME}/.switch/).
+// keys from later files override earlier ones.
 func InitSwitchKeys(baseFolder string) (*switchKeys, error) {
+	content := keysContent
+	if content == "" {
+		content = os.Getenv(KEYS_ENV_VAR)
+	}
+	if strings.TrimSpace(content) != "" {
+		p, err := properties.LoadString(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the supplied keys - %v", err)
+		}
+		return initKeys(p.Map())
+	}
 
-	// init from a file
-	p, err := properties.LoadFile(filepath.Join(baseFolder, "prod.keys"), properties.UTF8)
-	if err != nil {
-		p, err = properties.LoadFile("${HOME}/.switch/prod.keys", properties.UTF8)
+	keyFiles := ReadSettings(baseFolder).KeysFiles
+	if len(keyFiles) == 0 {
+		var err error
+		keyFiles, err = defaultKeyFiles(baseFolder)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if err != nil {
+	if len(keyFiles) == 0 {
 		return nil, errors.New("couldn't find keys.prod")
 	}
-	keysInstance = &switchKeys{keys: map[string]string{}}
-	for _, key := range p.Keys() {
-		value, _ := p.Get(key)
-		keysInstance.keys[key] = value
+
+	// init from the files
+	keys := map[string]string{}
+	for _, keyFile := range keyFiles {
+		resolved, err := resolveKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		p, err := properties.LoadFile(resolved, properties.UTF8)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys file %v - %v", keyFile, err)
+		}
+		for _, key := range p.Keys() {
+			value, _ := p.Get(key)
+			keys[key] = value
+		}
+	}
+	return initKeys(keys)
+}
+
+func initKeys(keys map[string]string) (*switchKeys, error) {
+	if err := validateKeys(keys); err != nil {
+		return nil, err
 	}
+	keysInstance = &switchKeys{keys: keys}
 
 	return keysInstance, nil
 }
+
+func defaultKeyFiles(baseFolder string) ([]string, error) {
+	var result []string
+	for _, fileName := range []string{PROD_KEYS_FILENAME, TITLE_KEYS_FILENAME} {
+		for _, folder := range []string{baseFolder, os.ExpandEnv("${HOME}/.switch")} {
+			path := filepath.Join(folder, fileName)
+			//Lstat, so that a link to a missing target is reported rather than ignored
+			if _, err := os.Lstat(path); err != nil {
+				continue
+			}
+			if _, err := resolveKeyFile(path); err != nil {
+				return nil, err
+			}
+			result = append(result, path)
+			break
+		}
+	}
+	return result, nil
+}
+
+// resolveKeyFile follows the symlinks (and junctions) of a keys file path, failing when the link target is missing
+func resolveKeyFile(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keys file %v - %v", path, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return path, nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		target, _ := os.Readlink(path)
+		return "", fmt.Errorf("keys file %v is a link to %v, which could not be found - %v", path, target, err)
+	}
+	return resolved, nil
+}
+
+// every key is expected to be a hex string, a line missing its value (or its separator) is malformed
+func validateKeys(keys map[string]string) error {
+	var invalid []string
+	for key, value := range keys {
+		value = strings.TrimSpace(value)
+		if _, err := hex.DecodeString(value); err != nil || value == "" {
+			invalid = append(invalid, key)
+		}
+	}
+	if len(invalid) != 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid (empty or non hex) value for keys %v", strings.Join(invalid, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package settings
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTestKeys isolates the keys lookup: no keys supplied by the command line or the environment, and no keys in
+// the home folder. returns the app folder, which holds the settings file
+func useTestKeys(t *testing.T) string {
+	t.Helper()
+	folder := useTestSettingsFile(t, "")
+	home := filepath.Join(folder, "home")
+	if err := os.Mkdir(home, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv(KEYS_ENV_VAR, "")
+	SetKeysContent("")
+	t.Cleanup(func() { SetKeysContent("") })
+	return folder
+}
+
+func writeKeysFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadingKeyFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		//keys files, relative to the app folder
+		files map[string]string
+		//keys_files setting, relative to the app folder
+		keysFiles []string
+		want      map[string]string
+		wantErr   string
+	}{
+		{"prod.keys", map[string]string{PROD_KEYS_FILENAME: "header_key = aabb\nkey_area_key_application_00 = ccdd\n"}, nil,
+			map[string]string{"header_key": "aabb", "key_area_key_application_00": "ccdd"}, ""},
+		{"title.keys supplies the keys missing from prod.keys", map[string]string{
+			PROD_KEYS_FILENAME:  "header_key = aabb\n",
+			TITLE_KEYS_FILENAME: "01000000000100000000000000000000 = eeff\n",
+		}, nil, map[string]string{"header_key": "aabb", "01000000000100000000000000000000": "eeff"}, ""},
+		{"prod.keys in the home folder", map[string]string{filepath.Join("home", ".switch", PROD_KEYS_FILENAME): "header_key = aabb\n"}, nil,
+			map[string]string{"header_key": "aabb"}, ""},
+		{"later keys files override earlier ones", map[string]string{
+			"first.keys":  "header_key = aabb\nkey_area_key_application_00 = ccdd\n",
+			"second.keys": "header_key = 1122\n",
+		}, []string{"first.keys", "second.keys"}, map[string]string{"header_key": "1122", "key_area_key_application_00": "ccdd"}, ""},
+		{"no keys file", nil, nil, nil, "couldn't find keys.prod"},
+		{"listed keys file missing", map[string]string{"first.keys": "header_key = aabb\n"}, []string{"first.keys", "missing.keys"},
+			nil, "failed to read keys file"},
+		{"line without a value", map[string]string{PROD_KEYS_FILENAME: "header_key = aabb\nkey_area_key_application_00\n"}, nil,
+			nil, "invalid (empty or non hex) value for keys key_area_key_application_00"},
+		{"non hex value", map[string]string{PROD_KEYS_FILENAME: "header_key = aabb\nkey_area_key_application_00 = not hex\n"}, nil,
+			nil, "invalid (empty or non hex) value for keys key_area_key_application_00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestKeys(t)
+			for name, content := range test.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(folder, name)), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				writeKeysFile(t, filepath.Join(folder, name), content)
+			}
+			if test.keysFiles != nil {
+				var keysFiles []string
+				for _, name := range test.keysFiles {
+					keysFiles = append(keysFiles, filepath.Join(folder, name))
+				}
+				content, err := json.Marshal(map[string][]string{"keys_files": keysFiles})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(folder, SETTINGS_FILENAME), content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			keys, err := InitSwitchKeys(folder)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("got error %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys.keys) != len(test.want) {
+				t.Errorf("got keys %v, want %v", keys.keys, test.want)
+			}
+			for name, value := range test.want {
+				if got := keys.GetKey(name); got != value {
+					t.Errorf("got %v = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestSuppliedKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdin   string
+		env     string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{"file", "", "", "header_key = aa", "aa", ""},
+		{"environment over the file", "", "header_key = bb", "header_key = aa", "bb", ""},
+		{"stdin over the environment", "header_key = cc", "header_key = bb", "header_key = aa", "cc", ""},
+		{"environment without a keys file", "", "header_key = bb", "", "bb", ""},
+		{"blank environment falls back to the file", "", " \n", "header_key = aa", "aa", ""},
+		{"invalid supplied keys", "header_key = not hex", "", "header_key = aa", "", "invalid (empty or non hex) value for keys header_key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestKeys(t)
+			if test.file != "" {
+				writeKeysFile(t, filepath.Join(folder, PROD_KEYS_FILENAME), test.file)
+			}
+			t.Setenv(KEYS_ENV_VAR, test.env)
+			//the -keys-stdin flag supplies the piped content
+			SetKeysContent(test.stdin)
+
+			keys, err := InitSwitchKeys(folder)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("got error %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := keys.GetKey("header_key"); got != test.want {
+				t.Errorf("got header_key %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSymlinkedKeysFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		missingTarget bool
+		listed        bool
+		wantErr       string
+	}{
+		{"link in the app folder", false, false, ""},
+		{"listed link", false, true, ""},
+		{"link to a missing file", true, false, "which could not be found"},
+		{"listed link to a missing file", true, true, "which could not be found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestKeys(t)
+			shared := filepath.Join(folder, "shared")
+			if err := os.Mkdir(shared, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			target := filepath.Join(shared, PROD_KEYS_FILENAME)
+			if !test.missingTarget {
+				writeKeysFile(t, target, "header_key = aabb\n")
+			}
+			link := filepath.Join(folder, PROD_KEYS_FILENAME)
+			if err := os.Symlink(target, link); err != nil {
+				t.Skipf("symlinks are not supported - %v", err)
+			}
+			if test.listed {
+				content, err := json.Marshal(map[string][]string{"keys_files": {link}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(folder, SETTINGS_FILENAME), content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			keys, err := InitSwitchKeys(folder)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) || !strings.Contains(err.Error(), target) {
+					t.Errorf("got error %v, want an error naming the missing target %v", err, target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := keys.GetKey("header_key"); got != "aabb" {
+				t.Errorf("got header_key %q, want the key of the link target", got)
+			}
+		})
+	}
+}
@@ -13,16 +13,25 @@ import (
 
 var (
 	settingsInstance *AppSettings
+	//overrides the default settings file location (<base folder>/settings.json)
+	settingsFilePath string
 )
 
 const (
-	SETTINGS_FILENAME      = "settings.json"
-	TITLE_JSON_FILENAME    = "titles.json"
-	VERSIONS_JSON_FILENAME = "versions.json"
-	SLM_VERSION_FILE       = "slm.json"
-	TITLES_JSON_URL        = "https://tinfoil.media/repo/db/titles.json"
-	VERSIONS_JSON_URL      = "https://tinfoil.media/repo/db/versions.json"
-	SLM_VERSION_URL        = "https://raw.githubusercontent.com/giwty/switch-library-manager/master/slm.json"
+	SETTINGS_FILENAME            = "settings.json"
+	TITLE_JSON_FILENAME          = "titles.json"
+	VERSIONS_JSON_FILENAME       = "versions.json"
+	SLM_VERSION_FILE             = "slm.json"
+	TITLES_DB_CACHE_FILENAME     = "titles_db_cache.json"
+	TITLES_JSON_URL              = "https://tinfoil.media/repo/db/titles.json"
+	VERSIONS_JSON_URL            = "https://tinfoil.media/repo/db/versions.json"
+	SLM_VERSION_URL              = "https://raw.githubusercontent.com/giwty/switch-library-manager/master/slm.json"
+	CACHE_FOLDER_ENV             = "SLM_CACHE_FOLDER"
+	TITLE_DATES_FILENAME         = "title_dates.json"
+	TITLE_OVERRIDES_FILENAME     = "title_overrides.json"
+	INTEGRITY_FILENAME           = "integrity_hashes.json"
+	DLC_REPORT_SNAPSHOT_FILENAME = "last_dlc_report.json"
+	RUN_SNAPSHOT_FILENAME        = "last_run.json"
 )
 
 const (
@@ -31,6 +40,61 @@ const (
 	TEMPLATE_DLC_NAME   = "DLC_NAME"
 	TEMPLATE_VERSION    = "VERSION"
 	TEMPLATE_TYPE       = "TYPE"
+	TEMPLATE_REGION     = "REGION"
+)
+
+const (
+	TEMPLATE_ARCHIVE = "ARCHIVE"
+	TEMPLATE_OUTPUT  = "OUTPUT"
+)
+
+const (
+	DATE_FORMAT_ISO8601 = "iso8601"
+	DATE_FORMAT_US      = "us"
+	DATE_FORMAT_EU      = "eu"
+)
+
+const (
+	COLUMN_INDEX          = "index"
+	COLUMN_TITLE          = "title"
+	COLUMN_TITLE_ID       = "title_id"
+	COLUMN_LOCAL_VERSION  = "local_version"
+	COLUMN_LATEST_VERSION = "latest_version"
+	COLUMN_UPDATE_DATE    = "update_date"
+	COLUMN_REGION         = "region"
+	COLUMN_SIZE           = "size"
+	COLUMN_MISSING_SIZE   = "missing_size"
+)
+
+var (
+	MissingUpdatesColumns = []string{COLUMN_INDEX, COLUMN_TITLE, COLUMN_TITLE_ID, COLUMN_LOCAL_VERSION,
+		COLUMN_LATEST_VERSION, COLUMN_UPDATE_DATE, COLUMN_REGION, COLUMN_SIZE, COLUMN_MISSING_SIZE}
+	DefaultMissingUpdatesColumns = []string{COLUMN_INDEX, COLUMN_TITLE, COLUMN_TITLE_ID, COLUMN_LOCAL_VERSION,
+		COLUMN_LATEST_VERSION, COLUMN_UPDATE_DATE}
+)
+
+const (
+	OUTPUT_FORMAT_TABLE    = "table"
+	OUTPUT_FORMAT_MARKDOWN = "markdown"
+	OUTPUT_FORMAT_BBCODE   = "bbcode"
+)
+
+const (
+	TABLE_STYLE_AUTO           = "auto"
+	TABLE_STYLE_COLORED_BRIGHT = "colored_bright"
+	TABLE_STYLE_DEFAULT        = "default"
+	TABLE_STYLE_LIGHT          = "light"
+	TABLE_STYLE_ROUNDED        = "rounded"
+	TABLE_STYLE_DOUBLE         = "double"
+	TABLE_STYLE_BOLD           = "bold"
+)
+
+const (
+	DEFAULT_SCAN_RETRY_DELAY_MS       = 500
+	DEFAULT_FUZZY_MATCH_THRESHOLD     = 0.8
+	DEFAULT_ALMOST_COMPLETE_THRESHOLD = 90
+	DEFAULT_HASH_CONCURRENCY          = 2
+	DEFAULT_PROGRESS_INTERVAL_MS      = 200
 )
 
 type OrganizeOptions struct {
@@ -38,44 +102,146 @@ type OrganizeOptions struct {
 	RenameFiles          bool   `json:"rename_files"`
 	DeleteEmptyFolders   bool   `json:"delete_empty_folders"`
 	DeleteOldUpdateFiles bool   `json:"delete_old_update_files"`
+	KeepOnlyLatestUpdate bool   `json:"keep_only_latest_update"`
+	DryRun               bool   `json:"dry_run"`
 	FolderNameTemplate   string `json:"folder_name_template"`
 	FileNameTemplate     string `json:"file_name_template"`
 }
 
 type AppSettings struct {
-	VersionsEtag           string          `json:"versions_etag"`
-	TitlesEtag             string          `json:"titles_etag"`
-	Folder                 string          `json:"folder"`
-	GUI                    bool            `json:"gui"`
-	Debug                  bool            `json:"debug"`
-	CheckForMissingUpdates bool            `json:"check_for_missing_updates"`
-	CheckForMissingDLC     bool            `json:"check_for_missing_dlc"`
-	OrganizeOptions        OrganizeOptions `json:"organize_options"`
-	ScanRecursively        bool            `json:"scan_recursively"`
-	GuiPagingSize          int             `json:"gui_page_size"`
+	VersionsEtag            string            `json:"versions_etag"`
+	TitlesEtag              string            `json:"titles_etag"`
+	VersionsContentLength   int64             `json:"versions_content_length"`
+	VersionsLastModified    string            `json:"versions_last_modified"`
+	TitlesContentLength     int64             `json:"titles_content_length"`
+	TitlesLastModified      string            `json:"titles_last_modified"`
+	CheckContentChanges     bool              `json:"check_content_changes"`
+	Folder                  string            `json:"folder"`
+	FoldersFile             string            `json:"folders_file"`
+	KeysFiles               []string          `json:"keys_files"`
+	GUI                     bool              `json:"gui"`
+	Debug                   bool              `json:"debug"`
+	CheckForMissingUpdates  bool              `json:"check_for_missing_updates"`
+	CheckForMissingDLC      bool              `json:"check_for_missing_dlc"`
+	CheckForAppUpdates      bool              `json:"check_for_app_updates"`
+	Offline                 bool              `json:"offline"`
+	OrganizeOptions         OrganizeOptions   `json:"organize_options"`
+	ScanRecursively         bool              `json:"scan_recursively"`
+	DisableDeepScan         bool              `json:"disable_deep_scan"`
+	ScanRetryCount          int               `json:"scan_retry_count"`
+	ScanRetryDelayMs        int               `json:"scan_retry_delay_ms"`
+	ScanDeadlineSeconds     int               `json:"scan_deadline_seconds"`
+	MaxFileSizeMB           int               `json:"max_file_size_mb"`
+	GuiPagingSize           int               `json:"gui_page_size"`
+	OutputFormat            string            `json:"output_format"`
+	CountPartialAsOwned     bool              `json:"count_partial_titles_as_owned"`
+	CacheFolder             string            `json:"cache_folder"`
+	RecentlyAddedDays       int               `json:"recently_added_days"`
+	RecentDLCDays           int               `json:"recent_dlc_days"`
+	DateFormat              string            `json:"date_format"`
+	DownloaderCommand       string            `json:"downloader_command"`
+	MissingUpdatesColumns   []string          `json:"missing_updates_columns"`
+	ArchiveExtractorCommand string            `json:"archive_extractor_command"`
+	DownloadBudgetMB        int64             `json:"download_budget_mb"`
+	DownloadStrategy        string            `json:"download_strategy"`
+	FavoriteTitles          []string          `json:"favorite_titles"`
+	FuzzyMatchThreshold     float64           `json:"fuzzy_match_threshold"`
+	NewReleaseGraceDays     int               `json:"new_release_grace_days"`
+	TableStyle              string            `json:"table_style"`
+	AlmostCompleteThreshold int               `json:"almost_complete_threshold"`
+	TitleAliases            map[string]string `json:"title_aliases"`
+	BlacklistedVersions     map[string][]int  `json:"blacklisted_versions"`
+	RegionAliases           map[string]string `json:"region_aliases"`
+	ExcludeDemos            bool              `json:"exclude_demos"`
+	ShowMissingSize         bool              `json:"show_missing_size"`
+	RequireApply            bool              `json:"require_apply"`
+	VerifyIntegrity         bool              `json:"verify_integrity"`
+	HashConcurrency         int               `json:"hash_concurrency"`
+	WriteHashSidecars       bool              `json:"write_hash_sidecars"`
+	AnalysisWorkers         int               `json:"analysis_workers"`
+	ReportNotOwnedTitles    bool              `json:"report_not_owned_titles"`
+	NotOwnedRegions         []string          `json:"not_owned_regions"`
+	SkipHiddenFiles         bool              `json:"skip_hidden_files"`
+	ProgressIntervalMs      int               `json:"progress_interval_ms"`
+	ReportOldUpdates        bool              `json:"report_old_updates"`
+	ReportCompleteTitles    bool              `json:"report_complete_titles"`
+	ReportTitleCompletion   bool              `json:"report_title_completion"`
+	LargestTitlesCount      int               `json:"largest_titles_count"`
+	SaveReports             bool              `json:"save_reports"`
+	ReportsToKeep           int               `json:"reports_to_keep"`
+	PreferredFormat         string            `json:"preferred_format"`
+	XciBaseOnly             bool              `json:"xci_base_only"`
+	XciBundledUpdate        bool              `json:"xci_bundled_update"`
+	SkipUpdates             bool              `json:"skip_updates"`
+	SkipDLC                 bool              `json:"skip_dlc"`
+	TypeByTitlesDB          bool              `json:"type_by_titles_db"`
+	NameConflictRule        string            `json:"name_conflict_rule"`
+	VersionsDuplicateRule   string            `json:"versions_duplicate_rule"`
+	PreferredRegion         string            `json:"preferred_region"`
+	ShowCompletionBar       bool              `json:"show_completion_bar"`
+
+	//set by the -apply flag, the organize/delete operations then change files even though RequireApply is set. never saved
+	Apply bool `json:"-"`
 }
 
 func ReadSettingsAsJSON(baseFolder string) string {
-	if _, err := os.Stat(filepath.Join(baseFolder, SETTINGS_FILENAME)); err != nil {
+	if _, err := os.Stat(settingsPath(baseFolder)); err != nil {
 		saveDefaultSettings(baseFolder)
 	}
-	file, _ := os.Open(filepath.Join(baseFolder, SETTINGS_FILENAME))
+	file, _ := os.Open(settingsPath(baseFolder))
 	bytes, _ := ioutil.ReadAll(file)
 	return string(bytes)
 }
 
+// SetSettingsFilePath makes ReadSettings/SaveSettings use the given file instead of the default settings.json
+func SetSettingsFilePath(path string) {
+	settingsFilePath = path
+	settingsInstance = nil
+}
+
+func settingsPath(baseFolder string) string {
+	if settingsFilePath != "" {
+		return settingsFilePath
+	}
+	return filepath.Join(baseFolder, SETTINGS_FILENAME)
+}
+
+// SettingsFilePath returns the path of the settings file in use
+func SettingsFilePath(baseFolder string) string {
+	return settingsPath(baseFolder)
+}
+
+// ValidateSettingsFile checks that the settings file exists and is a valid json
+func ValidateSettingsFile(baseFolder string) error {
+	file, err := os.Open(settingsPath(baseFolder))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	settings := AppSettings{}
+	err = json.NewDecoder(file).Decode(&settings)
+	if err != nil {
+		return fmt.Errorf("failed to parse %v - %v", SETTINGS_FILENAME, err)
+	}
+	return nil
+}
+
 func ReadSettings(baseFolder string) *AppSettings {
 	if settingsInstance != nil {
 		return settingsInstance
 	}
-	settingsInstance = &AppSettings{Debug: false, GuiPagingSize: 100}
-	if _, err := os.Stat(filepath.Join(baseFolder, SETTINGS_FILENAME)); err == nil {
-		file, err := os.Open(filepath.Join(baseFolder, SETTINGS_FILENAME))
+	settingsInstance = &AppSettings{Debug: false, GuiPagingSize: 100, CountPartialAsOwned: true, FuzzyMatchThreshold: DEFAULT_FUZZY_MATCH_THRESHOLD, TableStyle: TABLE_STYLE_AUTO,
+		AlmostCompleteThreshold: DEFAULT_ALMOST_COMPLETE_THRESHOLD, HashConcurrency: DEFAULT_HASH_CONCURRENCY,
+		SkipHiddenFiles: true, ProgressIntervalMs: DEFAULT_PROGRESS_INTERVAL_MS,
+		ScanRetryDelayMs: DEFAULT_SCAN_RETRY_DELAY_MS, PreferredFormat: "nsp", ShowCompletionBar: true, CheckForAppUpdates: true}
+	if _, err := os.Stat(settingsPath(baseFolder)); err == nil {
+		file, err := os.Open(settingsPath(baseFolder))
 		if err != nil {
 			zap.S().Warnf("Missing or corrupted config file, creating a new one")
 			return saveDefaultSettings(baseFolder)
 		} else {
 			_ = json.NewDecoder(file).Decode(&settingsInstance)
+			settingsInstance.MissingUpdatesColumns = validateColumns(settingsInstance.MissingUpdatesColumns)
 			return settingsInstance
 		}
 	} else {
@@ -85,15 +251,47 @@ func ReadSettings(baseFolder string) *AppSettings {
 
 func saveDefaultSettings(baseFolder string) *AppSettings {
 	settingsInstance = &AppSettings{
-		TitlesEtag:             "W/\"7cda5dea264d61:0\"",
-		VersionsEtag:           "W/\"413d981bf65ed61:0\"",
-		Folder:                 "",
-		GUI:                    true,
-		GuiPagingSize:          100,
-		CheckForMissingUpdates: true,
-		CheckForMissingDLC:     true,
-		ScanRecursively:        true,
-		Debug:                  false,
+		TitlesEtag:              "W/\"7cda5dea264d61:0\"",
+		VersionsEtag:            "W/\"413d981bf65ed61:0\"",
+		Folder:                  "",
+		FoldersFile:             "",
+		KeysFiles:               []string{},
+		GUI:                     true,
+		GuiPagingSize:           100,
+		CheckForMissingUpdates:  true,
+		CheckForMissingDLC:      true,
+		CheckForAppUpdates:      true,
+		Offline:                 false,
+		ScanRecursively:         true,
+		Debug:                   false,
+		OutputFormat:            OUTPUT_FORMAT_TABLE,
+		CountPartialAsOwned:     true,
+		DateFormat:              DATE_FORMAT_ISO8601,
+		MissingUpdatesColumns:   DefaultMissingUpdatesColumns,
+		ScanRetryDelayMs:        DEFAULT_SCAN_RETRY_DELAY_MS,
+		FavoriteTitles:          []string{},
+		FuzzyMatchThreshold:     DEFAULT_FUZZY_MATCH_THRESHOLD,
+		NewReleaseGraceDays:     0,
+		TableStyle:              TABLE_STYLE_AUTO,
+		RequireApply:            true,
+		AlmostCompleteThreshold: DEFAULT_ALMOST_COMPLETE_THRESHOLD,
+		ExcludeDemos:            false,
+		ShowMissingSize:         false,
+		ScanDeadlineSeconds:     0,
+		MaxFileSizeMB:           0,
+		HashConcurrency:         DEFAULT_HASH_CONCURRENCY,
+		WriteHashSidecars:       false,
+		AnalysisWorkers:         0,
+		SkipHiddenFiles:         true,
+		PreferredFormat:         "nsp",
+		TypeByTitlesDB:          false,
+		NameConflictRule:        "first",
+		VersionsDuplicateRule:   "merge",
+		ShowCompletionBar:       true,
+		ProgressIntervalMs:      DEFAULT_PROGRESS_INTERVAL_MS,
+		ReportOldUpdates:        false,
+		ReportCompleteTitles:    false,
+		ReportTitleCompletion:   false,
 		OrganizeOptions: OrganizeOptions{
 			RenameFiles:         false,
 			CreateFolderPerGame: false,
@@ -107,14 +305,65 @@ func saveDefaultSettings(baseFolder string) *AppSettings {
 	return SaveSettings(settingsInstance, baseFolder)
 }
 
+// drop unknown column names, falling back to the default columns if none are left
+func validateColumns(columns []string) []string {
+	var result []string
+	for _, column := range columns {
+		known := false
+		for _, c := range MissingUpdatesColumns {
+			if c == column {
+				known = true
+				break
+			}
+		}
+		if !known {
+			zap.S().Warnf("Ignoring unknown column [%v] in settings, supported columns are %v", column, MissingUpdatesColumns)
+			continue
+		}
+		result = append(result, column)
+	}
+	if len(result) == 0 {
+		return DefaultMissingUpdatesColumns
+	}
+	return result
+}
+
 func SaveSettings(settings *AppSettings, baseFolder string) *AppSettings {
 	file, _ := json.MarshalIndent(settings, "", " ")
-	_ = ioutil.WriteFile(filepath.Join(baseFolder, SETTINGS_FILENAME), file, 0644)
+	_ = ioutil.WriteFile(settingsPath(baseFolder), file, 0644)
 	settingsInstance = settings
 	return settings
 }
 
+// CacheFolder returns the folder holding the downloaded titles/versions json files.
+// the SLM_CACHE_FOLDER environment variable takes precedence over the settings, and both default to the
+// folder of the settings file.
+func CacheFolder(baseFolder string) (string, error) {
+	cacheFolder := ReadSettings(baseFolder).CacheFolder
+	if envFolder := os.Getenv(CACHE_FOLDER_ENV); envFolder != "" {
+		cacheFolder = envFolder
+	}
+	if cacheFolder == "" && settingsFilePath != "" {
+		//keep the cache next to the chosen settings file, as it goes with its etags
+		cacheFolder = filepath.Dir(settingsFilePath)
+	}
+	if cacheFolder == "" {
+		return baseFolder, nil
+	}
+	err := os.MkdirAll(cacheFolder, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return cacheFolder, nil
+}
+
+// CheckForUpdates compares the local version with the latest release, unless check_for_app_updates is disabled
+// or the app runs offline
 func CheckForUpdates(workingFolder string) (bool, error) {
+	settingsObj := ReadSettings(workingFolder)
+	if !settingsObj.CheckForAppUpdates || settingsObj.Offline {
+		return false, nil
+	}
 	file, err := os.Open(filepath.Join(workingFolder, SLM_VERSION_FILE))
 	if err != nil {
 		return false, err
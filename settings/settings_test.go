@@ -0,0 +1,252 @@
+package settings
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheFolder(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		env      string
+		//the cache folder, relative to the base folder
+		want string
+	}{
+		{"default", `{}`, "", ""},
+		{"settings", `{"cache_folder": "%v/settings cache"}`, "", "settings cache"},
+		{"environment over the settings", `{"cache_folder": "%v/settings cache"}`, "%v/env cache", "env cache"},
+		{"missing parent folders", `{}`, "%v/cache/of/titles", filepath.Join("cache", "of", "titles")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-cache")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			content := strings.ReplaceAll(test.settings, "%v", filepath.ToSlash(folder))
+			if err := ioutil.WriteFile(filepath.Join(folder, SETTINGS_FILENAME), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			settingsInstance = nil
+			defer func() { settingsInstance = nil }()
+			t.Setenv(CACHE_FOLDER_ENV, strings.ReplaceAll(test.env, "%v", folder))
+
+			got, err := CacheFolder(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(folder, test.want); filepath.Clean(got) != want {
+				t.Errorf("got cache folder %v, want %v", got, want)
+			}
+			if info, err := os.Stat(got); err != nil || !info.IsDir() {
+				t.Errorf("the cache folder %v was not created (%v)", got, err)
+			}
+		})
+	}
+}
+
+func TestSettingsFilePath(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	baseFolder := filepath.Join(folder, "app")
+	configFolder := filepath.Join(folder, "library two")
+	for _, f := range []string{baseFolder, configFolder} {
+		if err := os.Mkdir(f, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(configFolder, "library.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"folder": "/games/two"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(CACHE_FOLDER_ENV, "")
+	SetSettingsFilePath(configFile)
+	defer SetSettingsFilePath("")
+
+	settingsObj := ReadSettings(baseFolder)
+	if settingsObj.Folder != "/games/two" {
+		t.Errorf("got folder %q, want the one of the chosen settings file", settingsObj.Folder)
+	}
+	settingsObj.TitlesEtag = "etag"
+	SaveSettings(settingsObj, baseFolder)
+	if content, err := ioutil.ReadFile(configFile); err != nil || !strings.Contains(string(content), `"titles_etag": "etag"`) {
+		t.Errorf("got settings file %q (error %v), want the etag saved to the chosen settings file", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(baseFolder, SETTINGS_FILENAME)); err == nil {
+		t.Error("the settings were saved to the app folder")
+	}
+	//the cache goes with the etags of the chosen settings
+	if cacheFolder, err := CacheFolder(baseFolder); err != nil || cacheFolder != configFolder {
+		t.Errorf("got cache folder %v (error %v), want %v", cacheFolder, err, configFolder)
+	}
+}
+
+// useTestSettingsFile writes the given settings json to a temp folder, and points the settings at it
+func useTestSettingsFile(t *testing.T, content string) string {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "slm-settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(folder)
+		SetSettingsFilePath("")
+	})
+	if content != "" {
+		if err := ioutil.WriteFile(filepath.Join(folder, SETTINGS_FILENAME), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetSettingsFilePath(filepath.Join(folder, SETTINGS_FILENAME))
+	return folder
+}
+
+func TestReadSettingsDefaults(t *testing.T) {
+	tests := []struct {
+		name                string
+		content             string
+		wantRequireApply    bool
+		wantPreferredFormat string
+	}{
+		{"new settings file", "", true, "nsp"},
+		//existing settings files keep changing the files, as before require_apply was added
+		{"missing settings", `{"folder": ""}`, false, "nsp"},
+		{"turned on", `{"require_apply": true}`, true, "nsp"},
+		{"set", `{"require_apply": false, "preferred_format": "xci"}`, false, "xci"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestSettingsFile(t, test.content)
+			settingsObj := ReadSettings(folder)
+			if settingsObj.RequireApply != test.wantRequireApply {
+				t.Errorf("require_apply %v, want %v", settingsObj.RequireApply, test.wantRequireApply)
+			}
+			if settingsObj.PreferredFormat != test.wantPreferredFormat {
+				t.Errorf("preferred_format %v, want %v", settingsObj.PreferredFormat, test.wantPreferredFormat)
+			}
+			if settingsObj.Apply {
+				t.Error("apply is set")
+			}
+		})
+	}
+}
+
+func TestParseFoldersFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"empty", "", nil},
+		{"comments and blank lines", "# library\n/games/switch\n\n   \n  # backup drive\n/mnt/backup/switch  \n", []string{"/games/switch", "/mnt/backup/switch"}},
+		{"windows line endings", "D:\\Switch\r\n#E:\\Old\r\nE:\\Switch\r\n", []string{"D:\\Switch", "E:\\Switch"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseFoldersFile(strings.NewReader(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanFoldersMergesTheFoldersFile(t *testing.T) {
+	folder := useTestSettingsFile(t, "")
+	if err := ioutil.WriteFile(filepath.Join(folder, FOLDERS_FILENAME), []byte("# more games\n/games/b\n/games/a/\n\n/games/c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ScanFolders(folder, &AppSettings{Folder: "/games/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/games/a", "/games/b", "/games/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// countingTransport answers every request with the given body, counting the requests
+type countingTransport struct {
+	requests int32
+	body     string
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(c.body)), Request: req}, nil
+}
+
+func TestCheckForUpdates(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantRequests int32
+		wantUpdate   bool
+	}{
+		{"enabled", `{"check_for_app_updates": true}`, 1, true},
+		{"disabled", `{"check_for_app_updates": false}`, 0, false},
+		{"offline", `{"check_for_app_updates": true, "offline": true}`, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := useTestSettingsFile(t, test.content)
+			if err := ioutil.WriteFile(filepath.Join(folder, SLM_VERSION_FILE), []byte(`{"version": "1.0.0"}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			transport := &countingTransport{body: `{"version": "9.0.0"}`}
+			defaultTransport := http.DefaultTransport
+			http.DefaultTransport = transport
+			defer func() { http.DefaultTransport = defaultTransport }()
+
+			newUpdate, err := CheckForUpdates(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if newUpdate != test.wantUpdate || transport.requests != test.wantRequests {
+				t.Errorf("got update %v with %v requests, want update %v with %v requests",
+					newUpdate, transport.requests, test.wantUpdate, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestParseFolderList(t *testing.T) {
+	existing, err := ioutil.TempDir("", "slm-folder,with comma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(existing)
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"single", "/a", []string{"/a"}},
+		{"several", "/a,/b,/c", []string{"/a", "/b", "/c"}},
+		{"spaces and empty entries", " /a , ,/b,", []string{"/a", "/b"}},
+		{"duplicates", "/a,/b,/a/", []string{"/a", "/b"}},
+		{"escaped comma", "/games,,new,/b", []string{"/games,new", "/b"}},
+		{"existing path with a comma", existing, []string{existing}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseFolderList(test.value); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
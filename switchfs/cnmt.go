@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -24,6 +25,10 @@ type ContentMetaAttributes struct {
 	TitleId string `json:"title_id"`
 	Version int    `json:"version"`
 	Type    string `json:"type"`
+	//minimal base application version (update) needed to use a DLC, 0 when unknown
+	RequiredApplicationVersion int `json:"required_application_version"`
+	//minimal firmware version needed to run a base game or an update, 0 when unknown
+	RequiredSystemVersion int `json:"required_system_version"`
 }
 
 type ContentMeta struct {
@@ -41,10 +46,11 @@ type ContentMeta struct {
 		Hash          string `xml:"Hash"`
 		KeyGeneration string `xml:"KeyGeneration"`
 	} `xml:"Content"`
-	Digest                string `xml:"Digest"`
-	KeyGenerationMin      string `xml:"KeyGenerationMin"`
-	RequiredSystemVersion string `xml:"RequiredSystemVersion"`
-	OriginalId            string `xml:"OriginalId"`
+	Digest                     string `xml:"Digest"`
+	KeyGenerationMin           string `xml:"KeyGenerationMin"`
+	RequiredSystemVersion      string `xml:"RequiredSystemVersion"`
+	RequiredApplicationVersion int    `xml:"RequiredApplicationVersion"`
+	OriginalId                 string `xml:"OriginalId"`
 }
 
 func readBinaryCnmt(pfs0 *PFS0, data []byte) (*ContentMetaAttributes, error) {
@@ -56,15 +62,30 @@ func readBinaryCnmt(pfs0 *PFS0, data []byte) (*ContentMetaAttributes, error) {
 	titleId := binary.LittleEndian.Uint64(cnmt[0:0x8])
 	version := binary.LittleEndian.Uint32(cnmt[0x8:0xC])
 	metaType := ""
+	requiredApplicationVersion := 0
+	requiredSystemVersion := 0
 	switch cnmt[0xC:0xD][0] {
 	case ContentMetaType_Application:
 		metaType = "BASE"
+		//https://switchbrew.org/wiki/CNMT#Application_Extended_Header
+		if len(cnmt) >= 0x2C {
+			requiredSystemVersion = int(binary.LittleEndian.Uint32(cnmt[0x28:0x2C]))
+		}
 	case ContentMetaType_AddOnContent:
 		metaType = "DLC"
+		//https://switchbrew.org/wiki/CNMT#AddOnContent_Extended_Header
+		if len(cnmt) >= 0x2C {
+			requiredApplicationVersion = int(binary.LittleEndian.Uint32(cnmt[0x28:0x2C]))
+		}
 	case ContentMetaType_Patch:
 		metaType = "UPD"
+		//https://switchbrew.org/wiki/CNMT#Patch_Extended_Header
+		if len(cnmt) >= 0x2C {
+			requiredSystemVersion = int(binary.LittleEndian.Uint32(cnmt[0x28:0x2C]))
+		}
 	}
-	return &ContentMetaAttributes{Version: int(version), TitleId: fmt.Sprintf("0%x", titleId), Type: metaType}, nil
+	return &ContentMetaAttributes{Version: int(version), TitleId: fmt.Sprintf("0%x", titleId), Type: metaType,
+		RequiredApplicationVersion: requiredApplicationVersion, RequiredSystemVersion: requiredSystemVersion}, nil
 }
 
 func readXmlCnmt(xmlBytes []byte) (*ContentMetaAttributes, error) {
@@ -74,5 +95,12 @@ func readXmlCnmt(xmlBytes []byte) (*ContentMetaAttributes, error) {
 		return nil, err
 	}
 	titleId := strings.Replace(cmt.ID, "0x", "", 1)
-	return &ContentMetaAttributes{Version: cmt.Version, TitleId: titleId, Type: cmt.Type}, nil
+	requiredSystemVersion, _ := strconv.Atoi(cmt.RequiredSystemVersion)
+	return &ContentMetaAttributes{Version: cmt.Version, TitleId: titleId, Type: cmt.Type,
+		RequiredApplicationVersion: cmt.RequiredApplicationVersion, RequiredSystemVersion: requiredSystemVersion}, nil
+}
+
+// FirmwareVersionString decodes a system version number (as found in the cnmt) into major.minor.micro
+func FirmwareVersionString(version int) string {
+	return fmt.Sprintf("%v.%v.%v", version>>26, (version>>20)&0x3F, (version>>16)&0xF)
 }
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"github.com/jedib0t/go-pretty/table"
+	"strings"
+)
+
+// renderBBCode renders the table as a forum friendly BBCode table.
+// go-pretty has no BBCode support, so the table is rendered as markdown and converted.
+func renderBBCode(t table.Writer) string {
+	t.SetOutputMirror(nil)
+	markdown := t.RenderMarkdown()
+
+	var out strings.Builder
+	out.WriteString("[table]\n")
+	header := true
+	for _, line := range strings.Split(markdown, "\n") {
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		//the markdown separator line ends the header rows
+		if strings.Trim(line, "|-: ") == "" {
+			header = false
+			continue
+		}
+		cellTag := "td"
+		if header {
+			cellTag = "th"
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "| "), " |")
+		out.WriteString("[tr]")
+		for _, cell := range strings.Split(line, " | ") {
+			cell = strings.ReplaceAll(strings.TrimSpace(cell), "\\|", "|")
+			cell = strings.ReplaceAll(cell, "<br/>", "\n")
+			out.WriteString("[" + cellTag + "]" + cell + "[/" + cellTag + "]")
+		}
+		out.WriteString("[/tr]\n")
+	}
+	out.WriteString("[/table]")
+	return out.String()
+}
@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/jedib0t/go-pretty/table"
+)
+
+func TestRenderBBCode(t *testing.T) {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"#", "Title", "TitleId"})
+	tw.AppendRow(table.Row{0, "Game | Deluxe", "0100000000010000"})
+	tw.AppendRow(table.Row{1, "Other", "0100000000020000"})
+	tw.AppendFooter(table.Row{"", "Total", 2})
+
+	want := "[table]\n" +
+		"[tr][th]#[/th][th]Title[/th][th]TitleId[/th][/tr]\n" +
+		"[tr][td]0[/td][td]Game | Deluxe[/td][td]0100000000010000[/td][/tr]\n" +
+		"[tr][td]1[/td][td]Other[/td][td]0100000000020000[/td][/tr]\n" +
+		"[tr][td][/td][td]Total[/td][td]2[/td][/tr]\n" +
+		"[/table]"
+	if got := renderBBCode(tw); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
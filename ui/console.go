@@ -1,61 +1,113 @@
 package ui
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"github.com/briandowns/spinner"
 	"github.com/giwty/switch-library-manager/db"
 	"github.com/giwty/switch-library-manager/process"
 	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
 	"github.com/jedib0t/go-pretty/table"
 	"go.uber.org/zap"
-	"io/ioutil"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
 var (
-	nspFolder = flag.String("f", "", "path to NSP folder")
-	recursive = flag.Bool("r", true, "recursively scan sub folders")
-	mode      = flag.String("m", "", "**deprecated**")
-	s         = spinner.New(spinner.CharSets[26], 100*time.Millisecond)
+	nspFolder     = flag.String("f", "", "path to NSP folder, or a comma separated list of folders")
+	recursive     = flag.Bool("r", true, "recursively scan sub folders")
+	reconcileFile = flag.String("reconcile", "", "path to an inventory json file to compare the local library against")
+	exportFile    = flag.String("export", "", "path to a .csv or .json file to export the full local library to")
+	apply         = flag.Bool("apply", false, "execute the organize/delete operations, when require_apply is set")
+	notOwnedFile  = flag.String("not-owned", "", "path to a csv file to export the titles not owned at all to")
+	haveListFile  = flag.String("have-list", "", "path to write the library have list to")
+	compareFile   = flag.String("compare-have-list", "", "path to another library's have list to compare against")
+	titleQuery    = flag.String("title", "", "full or partial titleId of a single title to show")
+	assumeYes     = flag.Bool("yes", false, "do not ask for confirmation before changing files")
+	sinceLastRun  = flag.Bool("since-last-run", false, "only report the changes since the previous -since-last-run run")
+	noDeepScan    = flag.Bool("disable-deep-scan", false, "identify files by their name tags only, even if keys are available")
+	mode          = flag.String("m", "", "**deprecated**")
+	s             = spinner.New(spinner.CharSets[26], 100*time.Millisecond)
 )
 
+var missingUpdatesColumnHeaders = map[string]string{
+	settings.COLUMN_INDEX:          "#",
+	settings.COLUMN_TITLE:          "Title",
+	settings.COLUMN_TITLE_ID:       "TitleId",
+	settings.COLUMN_LOCAL_VERSION:  "Local version",
+	settings.COLUMN_LATEST_VERSION: "Latest Version",
+	settings.COLUMN_UPDATE_DATE:    "Update Date",
+	settings.COLUMN_REGION:         "Region",
+	settings.COLUMN_SIZE:           "Size",
+	settings.COLUMN_MISSING_SIZE:   "Missing size (MB)",
+}
+
 type Console struct {
-	baseFolder  string
-	sugarLogger *zap.SugaredLogger
+	baseFolder     string
+	sugarLogger    *zap.SugaredLogger
+	titlesProvider db.TitlesProvider
+	now            func() time.Time
 }
 
 func CreateConsole(baseFolder string, sugarLogger *zap.SugaredLogger) *Console {
-	return &Console{baseFolder: baseFolder, sugarLogger: sugarLogger}
+	return &Console{baseFolder: baseFolder, sugarLogger: sugarLogger, titlesProvider: db.NewJsonTitlesProvider(baseFolder, nil),
+		now: time.Now}
+}
+
+// SetClock overrides the current time used by the reports (such as the recently added files), for reproducible output
+func (c *Console) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// SetTitlesProvider overrides the source of the titles metadata (defaults to the tinfoil json files)
+func (c *Console) SetTitlesProvider(provider db.TitlesProvider) {
+	c.titlesProvider = provider
 }
 
 func (c *Console) Start() {
 	flag.Parse()
 
+	//an interrupt cancels the run, Start then returns so that the deferred work still runs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.handleInterrupt(cancel)
+
 	if mode != nil && *mode != "" {
-		fmt.Println("note : the mode option ('-m') is deprecated, please use the settings.json to control options.")
+		fmt.Println(legacyModeGuidance(*mode))
 	}
 
 	settingsObj := settings.ReadSettings(c.baseFolder)
-
-	//1. load the titles JSON object
-	fmt.Printf("Downlading latest switch titles json file")
-	titleFile, titlesEtag, err := db.LoadAndUpdateFile(settings.TITLES_JSON_URL, settings.TITLE_JSON_FILENAME, settingsObj.TitlesEtag)
-	if err != nil {
-		fmt.Printf("title json file doesn't exist\n")
-		return
+	db.SetRetryDelay(time.Duration(settingsObj.ScanRetryDelayMs) * time.Millisecond)
+	if settingsObj.SaveReports {
+		stopReport, err := startReport(c.baseFolder, settingsObj.ReportsToKeep)
+		if err != nil {
+			fmt.Printf("\nfailed to create the report file %v\n", err)
+		} else {
+			defer stopReport()
+		}
+	}
+	if settingsObj.ProgressIntervalMs > 0 {
+		s.Delay = time.Duration(settingsObj.ProgressIntervalMs) * time.Millisecond
 	}
-	settingsObj.TitlesEtag = titlesEtag
 
-	//2. load the versions JSON object
-	versionsFile, versionsEtag, err := db.LoadAndUpdateFile(settings.VERSIONS_JSON_URL, settings.VERSIONS_JSON_FILENAME, settingsObj.VersionsEtag)
+	//1. load the titles DB
+	fmt.Printf("Downlading latest switch titles json file")
+	titlesDB, err := c.titlesProvider.LoadTitlesDB()
 	if err != nil {
-		fmt.Printf("version json file doesn't exist\n")
+		fmt.Printf("\n%v\n", err)
 		return
 	}
-	settingsObj.VersionsEtag = versionsEtag
 
 	newUpdate, err := settings.CheckForUpdates(c.baseFolder)
 
@@ -63,29 +115,26 @@ func (c *Console) Start() {
 		fmt.Printf("\n=== New version available, download from Github ===\n")
 	}
 
-	//3. update the config file with new etag
-	settings.SaveSettings(settingsObj, c.baseFolder)
-
-	//4. create switch title db
-	titlesDB, err := db.CreateSwitchTitleDB(titleFile, versionsFile)
-
-	//5. read local files
-	folderToScan := settingsObj.Folder
+	//2. read local files
+	foldersToScan, err := settings.ScanFolders(c.baseFolder, settingsObj)
+	if err != nil {
+		fmt.Printf("\nfailed to read the folders file\n %v", err)
+	}
 	if nspFolder != nil && *nspFolder != "" {
-		folderToScan = *nspFolder
+		foldersToScan = settings.ParseFolderList(*nspFolder)
 	}
 
-	if folderToScan == "" {
+	if len(foldersToScan) == 0 {
 		fmt.Printf("\n\nNo folder to scan was defined.\n")
 		return
 	}
-	s.Restart()
-	fmt.Printf("\n\nScanning folder [%v]", folderToScan)
-	files, err := ioutil.ReadDir(folderToScan)
-	if err != nil {
-		fmt.Printf("\nfailed accessing NSP folder\n %v", err)
+	startSpinner(settingsObj)
+	fmt.Printf("\n\nScanning folder [%v]", strings.Join(foldersToScan, ", "))
+	scanFolders, singleFile := readScanTargets(foldersToScan, settingsObj.ScanRetryCount)
+	if len(scanFolders) == 0 {
 		return
 	}
+	organizeFolders := organizedFolders(scanFolders)
 
 	keys, _ := settings.InitSwitchKeys(c.baseFolder)
 	if keys == nil || keys.GetKey("header_key") == "" {
@@ -97,53 +146,689 @@ func (c *Console) Start() {
 		recursiveMode = *recursive
 	}
 
-	localDB, err := db.CreateLocalSwitchFilesDB(files, folderToScan, nil, recursiveMode)
+	scanOptions := db.ScanOptions{
+		Recursive:               recursiveMode,
+		DisableDeepScan:         settingsObj.DisableDeepScan || (noDeepScan != nil && *noDeepScan),
+		ArchiveExtractorCommand: settingsObj.ArchiveExtractorCommand,
+		RetryCount:              settingsObj.ScanRetryCount,
+		IncludeHidden:           !settingsObj.SkipHiddenFiles,
+		PreferredFormat:         settingsObj.PreferredFormat,
+		XciBaseOnly:             settingsObj.XciBaseOnly,
+		XciBundledUpdate:        settingsObj.XciBundledUpdate,
+		MaxFileSize:             int64(settingsObj.MaxFileSizeMB) * 1024 * 1024,
+		SkipUpdates:             settingsObj.SkipUpdates,
+		SkipDLC:                 settingsObj.SkipDLC,
+		Context:                 ctx,
+	}
+	if settingsObj.TypeByTitlesDB {
+		scanOptions.TitlesDB = titlesDB
+	}
+	if settingsObj.ScanDeadlineSeconds > 0 {
+		deadlineCtx, cancelDeadline := context.WithTimeout(ctx, time.Duration(settingsObj.ScanDeadlineSeconds)*time.Second)
+		defer cancelDeadline()
+		scanOptions.Context = deadlineCtx
+	}
+
+	localDB, err := db.CreateLocalSwitchFilesDBFromFolders(scanFolders, newProgressUpdater(settingsObj), scanOptions)
 	if err != nil {
 		fmt.Printf("\nfailed to process local folder\n %v", err)
 		return
 	}
 
+	if ctx.Err() != nil {
+		fmt.Printf("\nInterrupted, exiting\n")
+		return
+	}
+
+	recordTitleDates(c.baseFolder, localDB)
+
 	fmt.Printf("\nFinished scan\n ")
 
+	if localDB.Partial {
+		fmt.Printf("\n!!WARNING!!: the scan was stopped after %v seconds (scan_deadline_seconds), the reports below are based on the files scanned so far\n",
+			settingsObj.ScanDeadlineSeconds)
+	}
+
+	if len(localDB.UnreachableFolders) != 0 {
+		fmt.Printf("\n!!WARNING!!: %v folders could not be read, the reports below are missing their files:\n", len(localDB.UnreachableFolders))
+		for _, folder := range localDB.UnreachableFolders {
+			fmt.Printf("  %v\n", folder)
+		}
+	}
+
+	if singleFile {
+		s.Stop()
+		processSingleFile(localDB, titlesDB, settingsObj)
+		return
+	}
+
+	if titleQuery != nil && *titleQuery != "" {
+		s.Stop()
+		processTitleQuery(localDB, titlesDB, *titleQuery, settingsObj)
+		return
+	}
+
+	if sinceLastRun != nil && *sinceLastRun {
+		s.Stop()
+		c.processSinceLastRun(localDB, titlesDB, settingsObj)
+		return
+	}
+
+	if len(localDB.InProgress) != 0 {
+		fmt.Printf("\nSkipped %v files which are still being downloaded:\n", len(localDB.InProgress))
+		for _, f := range localDB.InProgress {
+			fmt.Printf("  %v\n", filepath.Join(f.BaseFolder, f.Info.Name()))
+		}
+	}
+
 	s.Stop()
-	p := (float32(len(localDB.TitlesMap)) / float32(len(titlesDB.TitlesMap))) * 100
 
-	fmt.Printf("Local library completion status: %.2f%% (have %d titles, out of %d titles)\n", p, len(localDB.TitlesMap), len(titlesDB.TitlesMap))
+	if len(localDB.DuplicateUpdates) != 0 {
+		processDuplicateUpdates(localDB, settingsObj)
+	}
+
+	if len(localDB.DuplicateBases) != 0 {
+		processDuplicateBases(localDB, settingsObj)
+	}
+
+	processLibraryStats(localDB, titlesDB, settingsObj)
+	processRegionStats(localDB, titlesDB, settingsObj)
+
+	if requiredFirmware := process.FindRequiredFirmware(localDB.Titles()); requiredFirmware != nil {
+		fmt.Printf("\nRequired firmware: %v (for %v)\n", switchfs.FirmwareVersionString(requiredFirmware.Version), requiredFirmware.File.Info.Name())
+	}
+
+	organizeOptions := settingsObj.OrganizeOptions
+	blacklist := process.NewBlacklist(settingsObj.BlacklistedVersions)
+	processBlacklistedUpdates(localDB, blacklist, settingsObj)
+
+	changesFiles := organizeOptions.DeleteOldUpdateFiles || organizeOptions.RenameFiles ||
+		organizeOptions.CreateFolderPerGame || organizeOptions.KeepOnlyLatestUpdate
+	if overlaps := process.FindFolderOverlaps(foldersToScan); changesFiles && len(overlaps) != 0 && !organizeOptions.DryRun {
+		fmt.Printf("\n!!ERROR!!: the scan folders overlap, no files will be changed:\n")
+		for _, overlap := range overlaps {
+			fmt.Printf("  [%v] is inside [%v]\n", overlap.Folder, overlap.Parent)
+		}
+		//OrganizeByScanFolders reads the options from the settings instance, this override is never saved
+		settingsObj.OrganizeOptions.DryRun = true
+		organizeOptions = settingsObj.OrganizeOptions
+	}
+	if changesFiles && localDB.Partial && !organizeOptions.DryRun {
+		fmt.Printf("\nThe scan is partial, no files will be changed\n")
+		//OrganizeByScanFolders reads the options from the settings instance, this override is never saved
+		settingsObj.OrganizeOptions.DryRun = true
+		organizeOptions = settingsObj.OrganizeOptions
+	}
+	//never saved, like the dry run overrides below
+	settingsObj.Apply = apply != nil && *apply
+	if changesFiles && process.ChangesNotApplied(settingsObj) && !organizeOptions.DryRun {
+		fmt.Printf("\nNo files will be changed, run again with -apply to execute the planned changes\n")
+		//OrganizeByScanFolders reads the options from the settings instance, this override is never saved
+		settingsObj.OrganizeOptions.DryRun = true
+		organizeOptions = settingsObj.OrganizeOptions
+	}
+
+	if changesFiles {
+		processReadOnlyArchives(localDB)
+	}
+
+	prompt := changesFiles && !organizeOptions.DryRun && needsConfirmation(assumeYes != nil && *assumeYes, os.Stdin)
+	//the old update files consolidating would remove, listed by the prompt and the dry run
+	var oldUpdates []process.OldUpdate
+	if (organizeOptions.KeepOnlyLatestUpdate || organizeOptions.DeleteOldUpdateFiles) && (organizeOptions.DryRun || prompt) {
+		oldUpdates = process.ConsolidateUpdates(localDB, true, blacklist)
+	}
+
+	if prompt {
+		printPlannedChanges(organizeFolders, organizeOptions, oldUpdates)
+		if !confirm(os.Stdin, "Continue?") {
+			fmt.Printf("\nNo files will be changed\n")
+			settingsObj.OrganizeOptions.DryRun = true
+			organizeOptions = settingsObj.OrganizeOptions
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("\nInterrupted, exiting\n")
+		return
+	}
 
-	if settingsObj.OrganizeOptions.DeleteOldUpdateFiles {
-		s.Restart()
+	if organizeOptions.DeleteOldUpdateFiles && !organizeOptions.DryRun {
+		startSpinner(settingsObj)
 		fmt.Printf("\nDeleting old updates\n")
-		process.DeleteOldUpdates(localDB)
+		process.DeleteOldUpdates(settingsObj, localDB, blacklist)
 		s.Stop()
 	}
 
-	if settingsObj.OrganizeOptions.RenameFiles || settingsObj.OrganizeOptions.CreateFolderPerGame {
-		s.Restart()
+	if (organizeOptions.KeepOnlyLatestUpdate || organizeOptions.DeleteOldUpdateFiles) && organizeOptions.DryRun {
+		fmt.Printf("\n[Dry run] %v old update files would be removed:\n", len(oldUpdates))
+		for _, f := range oldUpdates {
+			fmt.Printf("  %v\n", filepath.Join(f.File.BaseFolder, f.File.Info.Name()))
+		}
+	}
+
+	if organizeOptions.RenameFiles || organizeOptions.CreateFolderPerGame || organizeOptions.KeepOnlyLatestUpdate {
+		startSpinner(settingsObj)
 		fmt.Printf("\nStarting library organization\n")
-		process.OrganizeByFolders(folderToScan, localDB, titlesDB, nil)
+		for _, folder := range organizeFolders {
+			if incomplete, err := process.FindIncompleteOrganize(folder); err == nil && incomplete != nil {
+				fmt.Printf("The previous organization of [%v] was interrupted (%v moves did not complete), resuming it\n", folder, len(incomplete))
+				for _, entry := range incomplete {
+					fmt.Printf("  %v -> %v\n", entry.From, entry.To)
+				}
+			}
+		}
+		if organizeOptions.DryRun {
+			fmt.Printf("[Dry run] no files will be changed, the planned changes are written to slm.log\n")
+		}
+		process.OrganizeByScanFolders(organizeFolders, localDB, titlesDB, newProgressUpdater(settingsObj))
 		s.Stop()
 	}
 
 	if settingsObj.CheckForMissingUpdates {
-		s.Restart()
+		startSpinner(settingsObj)
 		fmt.Printf("\nChecking for missing updates\n")
-		processMissingUpdates(localDB, titlesDB)
+		processMissingUpdates(localDB, titlesDB, settingsObj)
 		s.Stop()
 	}
 
+	if settingsObj.CheckForMissingUpdates {
+		processOrphanUpdates(localDB, titlesDB, settingsObj)
+		processDLCRequiringUpdate(localDB, titlesDB, settingsObj)
+	}
+
 	if settingsObj.CheckForMissingDLC {
-		s.Restart()
+		startSpinner(settingsObj)
 		fmt.Printf("\nChecking for missing DLC\n")
-		processMissingDLC(localDB, titlesDB)
+		processMissingDLC(localDB, titlesDB, settingsObj)
+		s.Stop()
+		processOrphanDLC(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.DownloaderCommand != "" {
+		fmt.Printf("\nRunning downloader for missing content\n")
+		processDownloads(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.DownloadBudgetMB > 0 {
+		processDownloadPlan(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.VerifyIntegrity {
+		startSpinner(settingsObj)
+		fmt.Printf("\nVerifying files integrity\n")
+		c.processIntegrity(localDB, settingsObj)
 		s.Stop()
 	}
 
+	c.processUnrecognizedTitles(localDB, titlesDB, settingsObj)
+
+	if settingsObj.ReportNotOwnedTitles || (notOwnedFile != nil && *notOwnedFile != "") {
+		processNotOwnedTitles(localDB, titlesDB, settingsObj)
+	}
+
+	processProblematicFileNames(localDB, settingsObj)
+
+	processTitleIdMismatches(localDB, settingsObj)
+
+	if settingsObj.ReportTitleCompletion {
+		processTitleCompletion(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.ReportCompleteTitles {
+		processCompleteTitles(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.ReportOldUpdates {
+		processOldUpdates(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.LargestTitlesCount > 0 {
+		processLargestTitles(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.RecentDLCDays > 0 {
+		c.processDLCReleases(localDB, titlesDB, settingsObj)
+	}
+
+	if settingsObj.RecentlyAddedDays > 0 {
+		processRecentlyAdded(localDB, settingsObj, c.now())
+	}
+
+	if reconcileFile != nil && *reconcileFile != "" {
+		processReconcile(localDB, *reconcileFile, settingsObj)
+	}
+
+	if haveListFile != nil && *haveListFile != "" {
+		processHaveList(localDB, *haveListFile)
+	}
+
+	if compareFile != nil && *compareFile != "" {
+		processCompareHaveList(localDB, *compareFile, settingsObj)
+	}
+
+	if exportFile != nil && *exportFile != "" {
+		processExport(localDB, titlesDB, *exportFile)
+	}
+
 	fmt.Printf("Completed")
 }
 
-func processMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB) {
-	incompleteTitles := process.ScanForMissingUpdates(localDB.TitlesMap, titlesDB.TitlesMap)
-	if len(incompleteTitles) != 0 {
+// persist the last updated date of each title in the cache folder, and restore the dates of titles
+// whose files were replaced by older copies
+func recordTitleDates(baseFolder string, localDB *db.LocalSwitchFilesDB) {
+	cacheFolder, err := settings.CacheFolder(baseFolder)
+	if err != nil {
+		zap.S().Warnf("failed to create the cache folder, the title dates are not saved - %v", err)
+		return
+	}
+	err = db.RecordTitleDates(filepath.Join(cacheFolder, settings.TITLE_DATES_FILENAME), localDB)
+	if err != nil {
+		zap.S().Warnf("failed to save the title dates - %v", err)
+	}
+}
+
+func processReadOnlyArchives(localDB *db.LocalSwitchFilesDB) {
+	archives := process.ReadOnlyArchives(localDB.Titles())
+	if len(archives) == 0 {
+		return
+	}
+	fmt.Printf("\n%v archives are read only, their files are not deleted, moved or renamed:\n", len(archives))
+	for _, archive := range archives {
+		fmt.Printf("  %v\n", archive)
+	}
+}
+
+func processBlacklistedUpdates(localDB *db.LocalSwitchFilesDB, blacklist process.Blacklist, settingsObj *settings.AppSettings) {
+	blacklisted := process.FindBlacklistedUpdates(localDB.Titles(), blacklist)
+	if len(blacklisted) == 0 {
+		return
+	}
+	fmt.Print("\n!!WARNING!!: found update files of blacklisted (known bad) versions, they are not counted as owned:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "TitleId", "Version"})
+	for i, v := range blacklisted {
+		t.AppendRow(table.Row{i, filepath.Join(v.File.BaseFolder, v.File.Info.Name()), v.File.Metadata.TitleId, v.Version})
+	}
+	t.AppendFooter(table.Row{"", "", "Total", len(blacklisted)})
+	renderTable(t, settingsObj)
+}
+
+func printPlannedChanges(folders []string, options settings.OrganizeOptions, oldUpdates []process.OldUpdate) {
+	fmt.Printf("\nThe following changes will be made to [%v]:\n", strings.Join(folders, ", "))
+	if options.DeleteOldUpdateFiles || options.KeepOnlyLatestUpdate {
+		fmt.Printf("  - delete %v old update files\n", len(oldUpdates))
+	}
+	if options.RenameFiles {
+		fmt.Printf("  - rename files\n")
+	}
+	if options.CreateFolderPerGame {
+		fmt.Printf("  - move files to a folder per game\n")
+	}
+}
+
+// the changes are confirmed on a terminal, unless -yes is passed. piped input (scripts) is never asked
+func needsConfirmation(assumeYes bool, input *os.File) bool {
+	return !assumeYes && isTerminal(input)
+}
+
+// confirm asks a y/N question, anything but y/yes (including no input) is a no
+func confirm(input io.Reader, question string) bool {
+	fmt.Printf("%v [y/N] ", question)
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// the settings.json equivalent of each value of the deprecated '-m' option
+var legacyModes = map[string]string{
+	"organize":        `set organize_options.rename_files and/or organize_options.create_folder_per_game to true`,
+	"rename":          `set organize_options.rename_files to true`,
+	"folders":         `set organize_options.create_folder_per_game to true`,
+	"delete":          `set organize_options.delete_old_update_files to true`,
+	"clean":           `set organize_options.delete_old_update_files and organize_options.delete_empty_folders to true`,
+	"updates":         `set check_for_missing_updates to true`,
+	"missing-updates": `set check_for_missing_updates to true`,
+	"dlc":             `set check_for_missing_dlc to true`,
+	"missing-dlc":     `set check_for_missing_dlc to true`,
+	"scan":            `set check_for_missing_updates and check_for_missing_dlc to true`,
+}
+
+func legacyModeGuidance(mode string) string {
+	message := "note : the mode option ('-m') is deprecated, please use the settings.json to control options."
+	if guidance, ok := legacyModes[strings.ToLower(strings.TrimSpace(mode))]; ok {
+		message += fmt.Sprintf("\n       -m %v -> %v", mode, guidance)
+	}
+	return message
+}
+
+// readScanTargets reads the folders (and files) to scan, a file is scanned within its folder. singleFile is set when
+// only files (no folders) are passed, which are then identified rather than reported as a library.
+func readScanTargets(targets []string, retryCount int) (scanFolders []db.ScanFolder, singleFile bool) {
+	singleFile = true
+	for _, target := range targets {
+		files, isFile, err := readScanTarget(target, retryCount)
+		if err != nil {
+			fmt.Printf("\nfailed accessing NSP folder [%v]\n %v", target, err)
+			continue
+		}
+		scanFolder := db.ScanFolder{Path: target, Files: files}
+		if isFile {
+			scanFolder.Path = filepath.Dir(target)
+		} else {
+			singleFile = false
+		}
+		scanFolders = append(scanFolders, scanFolder)
+	}
+	return scanFolders, singleFile && len(scanFolders) != 0
+}
+
+// each file is organized within the folder it was scanned from
+func organizedFolders(scanFolders []db.ScanFolder) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, scanFolder := range scanFolders {
+		if seen[filepath.Clean(scanFolder.Path)] {
+			continue
+		}
+		seen[filepath.Clean(scanFolder.Path)] = true
+		result = append(result, scanFolder.Path)
+	}
+	return result
+}
+
+// the scan target is usually a folder, but can also be a single file to identify
+func readScanTarget(path string, retryCount int) ([]os.FileInfo, bool, error) {
+	info, err := db.StatPath(path, retryCount)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return []os.FileInfo{info}, true, nil
+	}
+	files, err := db.ReadFolder(path, retryCount)
+	return files, false, err
+}
+
+func processTitleQuery(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, query string, settingsObj *settings.AppSettings) {
+	candidates := titlesDB.FindTitlesById(query)
+	if len(candidates) == 0 {
+		fmt.Printf("\nNo title matches [%v]\n", query)
+		return
+	}
+	switchTitle := candidates[0]
+	if len(candidates) > 1 {
+		fmt.Printf("\n%v titles match [%v]:\n\n", len(candidates), query)
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"#", "TitleId", "Title"})
+		for i, v := range candidates {
+			t.AppendRow(table.Row{i, v.Attributes.Id, v.Attributes.Name})
+		}
+		renderTable(t, settingsObj)
+		if !isTerminal(os.Stdin) {
+			return
+		}
+		fmt.Print("Select a title # (empty to cancel): ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		index, err := strconv.Atoi(strings.TrimSpace(answer))
+		if err != nil || index < 0 || index >= len(candidates) {
+			return
+		}
+		switchTitle = candidates[index]
+	}
+	//the titles DB may hold DLC or updates of a game without its base entry
+	if len(switchTitle.Attributes.Id) != 16 {
+		fmt.Printf("\n[%v] matches a title without a valid base titleId [%v] in the titles DB\n", query, switchTitle.Attributes.Id)
+		return
+	}
+
+	localTitle, owned := localDB.GetTitle(strings.ToLower(switchTitle.Attributes.Id[0 : len(switchTitle.Attributes.Id)-4]))
+	fmt.Printf("\n%v [%v]\n", switchTitle.Attributes.Name, switchTitle.Attributes.Id)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Content", "TitleId", "Latest version", "Local version"})
+	baseVersion := "missing"
+	if owned && localTitle.BaseExist {
+		baseVersion = strconv.Itoa(localTitle.File.Metadata.Version)
+	}
+	t.AppendRow(table.Row{"Base", switchTitle.Attributes.Id, "", baseVersion})
+	latestUpdate, localUpdate := 0, "missing"
+	for version := range switchTitle.Updates {
+		if version > latestUpdate {
+			latestUpdate = version
+		}
+	}
+	if owned && len(localTitle.Updates) != 0 {
+		latest := 0
+		for version := range localTitle.Updates {
+			if version > latest {
+				latest = version
+			}
+		}
+		localUpdate = strconv.Itoa(latest)
+	}
+	if latestUpdate != 0 || localUpdate != "missing" {
+		t.AppendRow(table.Row{"Update", switchTitle.Attributes.Id[0:13] + "800", latestUpdate, localUpdate})
+	}
+	if !owned {
+		localTitle = nil
+	}
+	for _, dlc := range process.DLCCatalog(localTitle, switchTitle) {
+		localVersion := "missing"
+		if dlc.Owned && dlc.Local.Metadata != nil {
+			localVersion = strconv.Itoa(dlc.Local.Metadata.Version)
+		}
+		t.AppendRow(table.Row{"DLC - " + dlc.Attributes.Name, dlc.Attributes.Id, dlc.Attributes.Version, localVersion})
+	}
+	renderTable(t, settingsObj)
+}
+
+func processSingleFile(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	titles := localDB.Titles()
+	if len(titles) == 0 {
+		fmt.Print("\nUnable to identify the file\n")
+		reasons := make([]string, 0, len(localDB.Skipped))
+		for _, reason := range localDB.Skipped {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Printf("  %v\n", reason)
+		}
+		return
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"File", "TitleId", "Type", "Version", "Title", "DB Match"})
+	idPrefixes := make([]string, 0, len(titles))
+	for idPrefix := range titles {
+		idPrefixes = append(idPrefixes, idPrefix)
+	}
+	sort.Strings(idPrefixes)
+	for _, idPrefix := range idPrefixes {
+		switchFile := titles[idPrefix]
+		title := titlesDB.TitlesMap[idPrefix]
+		for _, f := range switchFile.Files() {
+			name, match := "", "not found"
+			if title != nil {
+				name, match = title.Attributes.Name, "found"
+				if dlc, ok := title.Dlc[strings.ToLower(f.Metadata.TitleId)]; ok {
+					name = dlc.Name
+				}
+			}
+			t.AppendRow(table.Row{f.Info.Name(), f.Metadata.TitleId, f.Metadata.Type, f.Metadata.Version, name, match})
+		}
+	}
+	renderTable(t, settingsObj)
+}
+
+// stop the spinner and cancel the run when the user aborts a long running scan
+func (c *Console) handleInterrupt(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		//a second interrupt terminates the process right away
+		signal.Stop(signals)
+		s.Stop()
+		c.sugarLogger.Infof("[SLM interrupted: %v]", sig)
+		fmt.Printf("\nInterrupted, stopping\n")
+		cancel()
+	}()
+}
+
+func processDuplicateUpdates(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings) {
+	fmt.Print("\nFound update files with the same title and version as another file (only one of them is used):\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "Same version as", "TitleId", "Version"})
+	for i, v := range localDB.DuplicateUpdates {
+		t.AppendRow(table.Row{i, filepath.Join(v.File.BaseFolder, v.File.Info.Name()),
+			filepath.Join(v.DuplicateOf.BaseFolder, v.DuplicateOf.Info.Name()), v.File.Metadata.TitleId, v.File.Metadata.Version})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", len(localDB.DuplicateUpdates)})
+	renderTable(t, settingsObj)
+}
+
+func processDuplicateBases(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings) {
+	fmt.Print("\nFound duplicate base game files (the kept file is used in the reports):\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Duplicate file", "Kept file", "TitleId", "Version"})
+	for i, v := range localDB.DuplicateBases {
+		t.AppendRow(table.Row{i, filepath.Join(v.File.BaseFolder, v.File.Info.Name()),
+			filepath.Join(v.DuplicateOf.BaseFolder, v.DuplicateOf.Info.Name()), v.File.Metadata.TitleId, v.File.Metadata.Version})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", len(localDB.DuplicateBases)})
+	renderTable(t, settingsObj)
+}
+
+// the titles counted by the completion reports
+func completionTitles(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) (map[string]*db.SwitchFile, map[string]*db.SwitchTitle) {
+	titles := localDB.Titles()
+	if settingsObj.ExcludeDemos {
+		return process.ExcludeDemos(titles, titlesDB.TitlesMap)
+	}
+	return titles, titlesDB.TitlesMap
+}
+
+// the result of the library completion report, rendered by renderLibraryStats
+type libraryStats struct {
+	Completion       process.LibraryCompletion
+	StrictCompletion process.LibraryCompletion
+	DLCCompletion    process.LibraryCompletion
+}
+
+func processLibraryStats(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	renderLibraryStats(computeLibraryStats(localDB, titlesDB, settingsObj), settingsObj)
+}
+
+func computeLibraryStats(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) libraryStats {
+	localTitles, switchTitles := completionTitles(localDB, titlesDB, settingsObj)
+	aliases := settingsObj.TitleAliases
+	mergedLocal, mergedTitles := process.MergeTitleAliases(localTitles, switchTitles, aliases)
+	//blacklisted updates are excluded first, as the blacklist is keyed by the titleIds of the files
+	strictLocal, _ := process.MergeTitleAliases(process.ExcludeBlacklisted(localTitles, process.NewBlacklist(settingsObj.BlacklistedVersions)),
+		switchTitles, aliases)
+	return libraryStats{
+		Completion:       process.CalculateCompletion(mergedLocal, mergedTitles, settingsObj.CountPartialAsOwned),
+		StrictCompletion: process.CalculateStrictCompletion(strictLocal, mergedTitles),
+		DLCCompletion:    process.CalculateDLCCompletion(mergedLocal, mergedTitles),
+	}
+}
+
+func renderLibraryStats(stats libraryStats, settingsObj *settings.AppSettings) {
+	completion, strictCompletion, dlcCompletion := stats.Completion, stats.StrictCompletion, stats.DLCCompletion
+	if !isPlainOutput(settingsObj) {
+		fmt.Printf("Local library completion status: %.2f%% (have %d titles, out of %d titles)\n", completion.Percent, completion.Owned, completion.Total)
+		if settingsObj.ShowCompletionBar && isTerminal(consoleStdout) {
+			fmt.Println(completionBar(completion.Percent, terminalWidth()))
+		}
+		fmt.Printf("Strict completion status: %.2f%% (have %d titles with the base, latest update and all DLC, out of %d titles)\n", strictCompletion.Percent, strictCompletion.Owned, strictCompletion.Total)
+		fmt.Printf("DLC completion status: %.2f%% (have %d DLC, out of %d DLC of the owned titles)\n", dlcCompletion.Percent, dlcCompletion.Owned, dlcCompletion.Total)
+		return
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Stat", "Value"})
+	t.AppendRow(table.Row{"Completion", fmt.Sprintf("%.2f%%", completion.Percent)})
+	t.AppendRow(table.Row{"Owned titles", completion.Owned})
+	t.AppendRow(table.Row{"Total titles", completion.Total})
+	t.AppendRow(table.Row{"Strict completion", fmt.Sprintf("%.2f%%", strictCompletion.Percent)})
+	t.AppendRow(table.Row{"Complete titles", strictCompletion.Owned})
+	t.AppendRow(table.Row{"DLC completion", fmt.Sprintf("%.2f%%", dlcCompletion.Percent)})
+	t.AppendRow(table.Row{"Owned DLC", dlcCompletion.Owned})
+	t.AppendRow(table.Row{"Total DLC", dlcCompletion.Total})
+	renderTable(t, settingsObj)
+}
+
+func processRegionStats(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	regionCompletion := computeRegionStats(localDB, titlesDB, settingsObj)
+	regions := make([]string, 0, len(regionCompletion))
+	for region := range regionCompletion {
+		regions = append(regions, region)
+	}
+	//largest regions first
+	sort.Slice(regions, func(i, j int) bool {
+		if regionCompletion[regions[i]].Total == regionCompletion[regions[j]].Total {
+			return regions[i] < regions[j]
+		}
+		return regionCompletion[regions[i]].Total > regionCompletion[regions[j]].Total
+	})
+	fmt.Print("\nCompletion per region:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Region", "Owned", "Total", "Completion"})
+	for _, region := range regions {
+		completion := regionCompletion[region]
+		t.AppendRow(table.Row{region, completion.Owned, completion.Total, fmt.Sprintf("%.2f%%", completion.Percent)})
+	}
+	renderTable(t, settingsObj)
+}
+
+// the completion per region, the aliased regional releases are counted in the region of their canonical title
+func computeRegionStats(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) map[string]process.LibraryCompletion {
+	localTitles, switchTitles := completionTitles(localDB, titlesDB, settingsObj)
+	mergedLocal, mergedTitles := process.MergeTitleAliases(localTitles, switchTitles, settingsObj.TitleAliases)
+	return process.CalculateRegionCompletion(mergedLocal, mergedTitles, settingsObj.CountPartialAsOwned)
+}
+
+// the result of the missing updates / DLC reports, rendered by renderMissingUpdates / renderMissingDLC
+type missingContentReport struct {
+	//ordered by name and titleId
+	Titles []process.IncompleteTitle
+	//the estimated size of the content missing to complete each title (see missingSize), in the Titles order
+	MissingSizes []string
+	//missing updates only, how far behind the titles are
+	Gaps []process.UpdateGapBucket
+}
+
+func newMissingContentReport(localTitles map[string]*db.SwitchFile, switchTitles map[string]*db.SwitchTitle,
+	incompleteTitles map[string]process.IncompleteTitle) missingContentReport {
+	report := missingContentReport{Titles: process.SortedIncompleteTitles(incompleteTitles)}
+	for _, v := range report.Titles {
+		report.MissingSizes = append(report.MissingSizes, missingSize(localTitles, switchTitles, v.Attributes.Id))
+	}
+	return report
+}
+
+func processMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	renderMissingUpdates(computeMissingUpdates(localDB, titlesDB, settingsObj), settingsObj)
+}
+
+func computeMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) missingContentReport {
+	localTitles, switchTitles := process.MergeTitleAliases(process.ExcludeBlacklisted(localDB.Titles(), process.NewBlacklist(settingsObj.BlacklistedVersions)),
+		titlesDB.TitlesMap, settingsObj.TitleAliases)
+	incompleteTitles := process.ScanForMissingUpdatesWithWorkers(localTitles, switchTitles, settingsObj.AnalysisWorkers)
+	report := newMissingContentReport(localTitles, switchTitles, incompleteTitles)
+	report.Gaps = process.GroupMissingUpdatesByGap(incompleteTitles)
+	return report
+}
+
+func renderMissingUpdates(report missingContentReport, settingsObj *settings.AppSettings) {
+	if len(report.Titles) != 0 {
 		fmt.Print("\nFound available updates:\n\n")
 	} else {
 		fmt.Print("\nAll NSP's are up to date!\n\n")
@@ -151,20 +836,127 @@ func processMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTi
 	}
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.SetStyle(table.StyleColoredBright)
-	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Local version", "Latest Version", "Update Date"})
-	i := 0
-	for _, v := range incompleteTitles {
-		t.AppendRow([]interface{}{i, v.Attributes.Name, v.Attributes.Id, v.LocalUpdate, v.LatestUpdate, v.LatestUpdateDate})
-		i++
+	columns := settingsObj.MissingUpdatesColumns
+	if len(columns) == 0 {
+		columns = settings.DefaultMissingUpdatesColumns
+	}
+	header := table.Row{}
+	for _, column := range columns {
+		header = append(header, missingUpdatesColumnHeaders[column])
+	}
+	t.AppendHeader(header)
+	for i, v := range report.Titles {
+		values := map[string]interface{}{
+			settings.COLUMN_INDEX:          i,
+			settings.COLUMN_TITLE:          v.Attributes.Name,
+			settings.COLUMN_TITLE_ID:       v.Attributes.Id,
+			settings.COLUMN_LOCAL_VERSION:  v.LocalUpdate,
+			settings.COLUMN_LATEST_VERSION: v.LatestUpdate,
+			settings.COLUMN_UPDATE_DATE:    process.FormatDate(v.LatestUpdateDate, settingsObj.DateFormat),
+			settings.COLUMN_REGION:         v.Attributes.Region,
+			settings.COLUMN_SIZE:           v.Attributes.Size,
+			settings.COLUMN_MISSING_SIZE:   report.MissingSizes[i],
+		}
+		row := table.Row{}
+		for _, column := range columns {
+			row = append(row, values[column])
+		}
+		t.AppendRow(row)
+	}
+	footer := make(table.Row, len(columns))
+	for i := range footer {
+		footer[i] = ""
+	}
+	if len(columns) > 1 {
+		footer[len(columns)-2] = "Total"
+	}
+	footer[len(columns)-1] = len(report.Titles)
+	t.AppendFooter(footer)
+	renderTable(t, settingsObj)
+
+	fmt.Print("\nUpdates behind:\n")
+	for _, bucket := range report.Gaps {
+		fmt.Printf("%-12v %-5d %v\n", bucket.Label, bucket.Count, strings.Repeat("#", bucket.Count))
+	}
+}
+
+func processOrphanUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	orphanUpdates := process.ScanForOrphanUpdates(localDB.Titles(), titlesDB.TitlesMap)
+	if len(orphanUpdates) == 0 {
+		return
+	}
+	fmt.Print("\nFound updates for titles unknown to the titles DB:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "TitleId", "Version"})
+	for i, f := range orphanUpdates {
+		t.AppendRow(table.Row{i, f.Info.Name(), f.Metadata.TitleId, f.Metadata.Version})
+	}
+	t.AppendFooter(table.Row{"", "", "Total", len(orphanUpdates)})
+	renderTable(t, settingsObj)
+}
+
+func processOrphanDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	baseNotOwned, baseUnknown := process.ScanForOrphanDLC(localDB.Titles(), titlesDB.TitlesMap)
+	if len(baseNotOwned) != 0 {
+		fmt.Print("\nFound DLC for base games not owned:\n\n")
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"#", "File", "TitleId", "Base game"})
+		for i, f := range baseNotOwned {
+			titleId := f.Metadata.TitleId
+			t.AppendRow(table.Row{i, f.Info.Name(), titleId, titlesDB.TitlesMap[strings.ToLower(titleId[0:len(titleId)-4])].Attributes.Name})
+		}
+		t.AppendFooter(table.Row{"", "", "Total", len(baseNotOwned)})
+		renderTable(t, settingsObj)
+	}
+	if len(baseUnknown) != 0 {
+		fmt.Print("\nFound DLC for base games unknown to the titles DB:\n\n")
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"#", "File", "TitleId"})
+		for i, f := range baseUnknown {
+			t.AppendRow(table.Row{i, f.Info.Name(), f.Metadata.TitleId})
+		}
+		t.AppendFooter(table.Row{"", "Total", len(baseUnknown)})
+		renderTable(t, settingsObj)
 	}
-	t.AppendFooter(table.Row{"", "", "", "", "Total", len(incompleteTitles)})
-	t.Render()
 }
 
-func processMissingDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB) {
-	incompleteTitles := process.ScanForMissingDLC(localDB.TitlesMap, titlesDB.TitlesMap)
-	if len(incompleteTitles) != 0 {
+func processDLCRequiringUpdate(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	dlcRequiringUpdate := process.ScanForDLCRequiringUpdate(localDB.Titles())
+	if len(dlcRequiringUpdate) == 0 {
+		return
+	}
+	fmt.Print("\nFound DLC requiring a newer base game update:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "DLC", "TitleId", "Required update", "Local update"})
+	for i, v := range dlcRequiringUpdate {
+		name := v.Dlc.Info.Name()
+		if title, ok := titlesDB.TitlesMap[v.Dlc.Metadata.TitleId[0:len(v.Dlc.Metadata.TitleId)-4]]; ok {
+			if dlc, ok := title.Dlc[v.Dlc.Metadata.TitleId]; ok && dlc.Name != "" {
+				name = dlc.Name
+			}
+		}
+		t.AppendRow(table.Row{i, name, v.Dlc.Metadata.TitleId, v.RequiredBaseVersion, v.LocalBaseVersion})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", len(dlcRequiringUpdate)})
+	renderTable(t, settingsObj)
+}
+
+func processMissingDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	renderMissingDLC(computeMissingDLC(localDB, titlesDB, settingsObj), settingsObj)
+}
+
+func computeMissingDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) missingContentReport {
+	localTitles, switchTitles := process.MergeTitleAliases(localDB.Titles(), titlesDB.TitlesMap, settingsObj.TitleAliases)
+	incompleteTitles := process.ScanForMissingDLCWithWorkers(localTitles, switchTitles, settingsObj.AnalysisWorkers)
+	return newMissingContentReport(localTitles, switchTitles, incompleteTitles)
+}
+
+func renderMissingDLC(report missingContentReport, settingsObj *settings.AppSettings) {
+	if len(report.Titles) != 0 {
 		fmt.Print("\nFound missing DLCS:\n\n")
 	} else {
 		fmt.Print("\nYou have all the DLCS!\n\n")
@@ -172,13 +964,689 @@ func processMissingDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitles
 	}
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.SetStyle(table.StyleColoredBright)
-	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Missing DLCs (titleId - Name)"})
+	header := table.Row{"#", "Title", "TitleId", "Owned DLC", "Missing DLCs (titleId - Name)"}
+	if settingsObj.ShowMissingSize {
+		header = append(header, missingUpdatesColumnHeaders[settings.COLUMN_MISSING_SIZE])
+	}
+	t.AppendHeader(header)
+	for i, v := range report.Titles {
+		owned := fmt.Sprintf("%v of %v", v.OwnedDLC, v.TotalDLC)
+		if v.IsAlmostComplete(settingsObj.AlmostCompleteThreshold) {
+			owned += " (almost complete)"
+		}
+		row := table.Row{i, v.Attributes.Name, v.Attributes.Id, owned, strings.Join(v.MissingDLC, "\n")}
+		if settingsObj.ShowMissingSize {
+			row = append(row, report.MissingSizes[i])
+		}
+		t.AppendRow(row)
+	}
+	footer := table.Row{"", "", "", "Total", len(report.Titles)}
+	if settingsObj.ShowMissingSize {
+		footer = table.Row{"", "", "", "", "Total", len(report.Titles)}
+	}
+	t.AppendFooter(footer)
+	renderTable(t, settingsObj)
+}
+
+// the estimated size (MB) of the update and DLC missing to complete a title. a "+" marks an estimate
+// missing the size of some of the content, as the titles DB does not always have it.
+func missingSize(localTitles map[string]*db.SwitchFile, switchTitles map[string]*db.SwitchTitle, titleId string) string {
+	if len(titleId) != 16 {
+		return ""
+	}
+	idPrefix := strings.ToLower(titleId[0 : len(titleId)-4])
+	switchFile, ok := localTitles[idPrefix]
+	if !ok {
+		return ""
+	}
+	switchTitle, ok := switchTitles[idPrefix]
+	if !ok {
+		return ""
+	}
+	size := process.EstimateMissingSize(switchFile, switchTitle)
+	if size.Bytes == 0 && size.Unknown != 0 {
+		return "unknown"
+	}
+	result := fmt.Sprintf("%v", size.Bytes/1024/1024)
+	if size.Unknown != 0 {
+		result += "+"
+	}
+	return result
+}
+
+func processLargestTitles(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	largestTitles := process.FindLargestTitles(localDB.Titles(), titlesDB.TitlesMap, settingsObj.LargestTitlesCount)
+	if len(largestTitles) == 0 {
+		return
+	}
+	fmt.Printf("\nLargest %v titles (base, updates and DLC):\n\n", len(largestTitles))
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Size (MB)"})
+	var total int64
+	for i, v := range largestTitles {
+		t.AppendRow(table.Row{i, v.Name, v.TitleId, v.Size / 1024 / 1024})
+		total += v.Size
+	}
+	t.AppendFooter(table.Row{"", "", "Total (MB)", total / 1024 / 1024})
+	renderTable(t, settingsObj)
+}
+
+func processCompleteTitles(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	localTitles := process.ExcludeBlacklisted(localDB.Titles(), process.NewBlacklist(settingsObj.BlacklistedVersions))
+	completeTitles := process.FindCompleteTitles(localTitles, titlesDB.TitlesMap)
+	if len(completeTitles) == 0 {
+		fmt.Print("\nNo title is complete (base, latest update and all DLC) yet\n\n")
+		return
+	}
+	fmt.Print("\nComplete titles (base, latest update and all DLC):\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Update", "DLC"})
+	for i, v := range completeTitles {
+		t.AppendRow(table.Row{i, v.Attributes.Name, v.Attributes.Id, v.LocalUpdate, v.DLCCount})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", len(completeTitles)})
+	renderTable(t, settingsObj)
+}
+
+func processTitleCompletion(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	localTitles := process.ExcludeBlacklisted(localDB.Titles(), process.NewBlacklist(settingsObj.BlacklistedVersions))
+	titleCompletion := process.CalculateTitleCompletion(localTitles, titlesDB.TitlesMap)
+	if len(titleCompletion) == 0 {
+		return
+	}
+	fmt.Print("\nCompletion per title (base, latest update and DLC):\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Owned", "Total", "Percent"})
+	for i, v := range titleCompletion {
+		t.AppendRow(table.Row{i, v.Attributes.Name, v.Attributes.Id, v.Owned, v.Total, fmt.Sprintf("%.2f%%", v.Percent)})
+	}
+	t.AppendFooter(table.Row{"", "", "", "", "Total", len(titleCompletion)})
+	renderTable(t, settingsObj)
+}
+
+func processOldUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	oldUpdates := process.FindOldUpdates(localDB, process.NewBlacklist(settingsObj.BlacklistedVersions))
+	if len(oldUpdates) == 0 {
+		return
+	}
+	fmt.Print("\nUpdates which are not the latest local update of their title:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Title", "Version", "Latest local version", "File", "Size (MB)"})
+	var total int64
+	for i, v := range oldUpdates {
+		name := ""
+		if title, ok := titlesDB.TitlesMap[v.IdPrefix]; ok {
+			name = title.Attributes.Name
+		}
+		t.AppendRow(table.Row{i, name, v.Version, v.LatestLocalVersion, filepath.Join(v.File.BaseFolder, v.File.Info.Name()), v.File.Info.Size() / 1024 / 1024})
+		total += v.File.Info.Size()
+	}
+	t.AppendFooter(table.Row{"", "", "", "", "Total (MB)", total / 1024 / 1024})
+	renderTable(t, settingsObj)
+}
+
+// lists the DLC released recently for the owned games, marking the ones released since the previous run
+func (c *Console) processDLCReleases(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	now := c.now()
+	releases := process.FindDLCReleases(localDB.Titles(), titlesDB.TitlesMap, now.AddDate(0, 0, -settingsObj.RecentDLCDays))
+	lastReport := now
+	cacheFolder, err := settings.CacheFolder(c.baseFolder)
+	if err != nil {
+		fmt.Printf("\nfailed to create cache folder %v\n", err)
+		return
+	}
+	snapshotPath := filepath.Join(cacheFolder, settings.DLC_REPORT_SNAPSHOT_FILENAME)
+	previous, found, err := process.LoadDLCReportSnapshot(snapshotPath)
+	if err != nil {
+		zap.S().Warnf("failed to read the previous DLC report snapshot, the DLC are marked as new from now on - %v", err)
+	}
+	if found {
+		lastReport = previous.Time
+	}
+	//release dates have no time, so a DLC released on the day of the previous run is still new
+	lastReportDay := time.Date(lastReport.Year(), lastReport.Month(), lastReport.Day(), 0, 0, 0, 0, time.UTC)
+	if err = process.SaveDLCReportSnapshot(snapshotPath, process.DLCReportSnapshot{Time: now}); err != nil {
+		zap.S().Errorf("failed to save the DLC report snapshot - %v", err)
+	}
+	if len(releases) == 0 {
+		fmt.Printf("\nNo DLC were released in the last %v days for the games you own\n\n", settingsObj.RecentDLCDays)
+		return
+	}
+	fmt.Printf("\nDLC released in the last %v days for the games you own:\n\n", settingsObj.RecentDLCDays)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Title", "DLC", "TitleId", "Release date", "Owned", ""})
+	for i, v := range releases {
+		owned, isNew := "no", ""
+		if v.Owned {
+			owned = "yes"
+		}
+		if !v.ReleaseDate.Before(lastReportDay) {
+			isNew = "new since the last run"
+		}
+		t.AppendRow(table.Row{i, v.Title.Name, v.Dlc.Name, v.Dlc.Id, process.FormatDate(v.ReleaseDate.Format("2006-01-02"), settingsObj.DateFormat), owned, isNew})
+	}
+	t.AppendFooter(table.Row{"", "", "", "", "", "Total", len(releases)})
+	renderTable(t, settingsObj)
+}
+
+func (c *Console) processSinceLastRun(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	//the files missing from an incomplete scan would be reported as changes, and recorded as gone for the next run
+	if localDB.Partial || len(localDB.UnreachableFolders) != 0 {
+		fmt.Print("\nThe scan is incomplete, the changes since the last run are not reported, and this run is not recorded\n\n")
+		return
+	}
+	cacheFolder, err := settings.CacheFolder(c.baseFolder)
+	if err != nil {
+		fmt.Printf("\nfailed to create cache folder %v\n", err)
+		return
+	}
+	snapshotPath := filepath.Join(cacheFolder, settings.RUN_SNAPSHOT_FILENAME)
+	previous, found, err := process.LoadRunSnapshot(snapshotPath)
+	if err != nil {
+		zap.S().Warnf("failed to read the previous run snapshot, the changes are reported from now on - %v", err)
+	}
+	titles := localDB.Titles()
+	localTitles := process.ExcludeBlacklisted(titles, process.NewBlacklist(settingsObj.BlacklistedVersions))
+	missingUpdates := process.ScanForMissingUpdatesWithWorkers(localTitles, titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	missingDLC := process.ScanForMissingDLCWithWorkers(titles, titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	current := process.TakeRunSnapshot(titles, missingUpdates, missingDLC, c.now())
+	if err = process.SaveRunSnapshot(snapshotPath, current); err != nil {
+		zap.S().Errorf("failed to save the run snapshot - %v", err)
+	}
+	if !found {
+		fmt.Print("\nNo previous run was recorded, the next run will report the changes since now\n\n")
+		return
+	}
+	delta := process.DiffRunSnapshots(previous, current)
+	since := process.FormatDate(previous.Time.Format(time.RFC3339), settingsObj.DateFormat)
+	if delta.IsEmpty() {
+		fmt.Printf("\nNothing changed since the last run (%v)\n\n", since)
+		return
+	}
+	fmt.Printf("\nChanges since the last run (%v):\n\n", since)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Change", "Title", "TitleId", "Version"})
 	i := 0
-	for _, v := range incompleteTitles {
-		t.AppendRow([]interface{}{i, v.Attributes.Name, v.Attributes.Id, strings.Join(v.MissingDLC, "\n")})
+	for _, id := range delta.NewTitles {
+		t.AppendRow(table.Row{i, "New title", titleName(titlesDB, id), id, ""})
+		i++
+	}
+	updateIds := make([]string, 0, len(delta.NewUpdates))
+	for id := range delta.NewUpdates {
+		updateIds = append(updateIds, id)
+	}
+	sort.Strings(updateIds)
+	for _, id := range updateIds {
+		t.AppendRow(table.Row{i, "Update available", titleName(titlesDB, id), id, delta.NewUpdates[id]})
+		i++
+	}
+	for _, id := range delta.NewMissingDLC {
+		t.AppendRow(table.Row{i, "New DLC (missing)", titleName(titlesDB, id), id, ""})
 		i++
 	}
-	t.AppendFooter(table.Row{"", "", "", "", "Total", len(incompleteTitles)})
+	for _, id := range delta.AcquiredDLC {
+		t.AppendRow(table.Row{i, "DLC acquired", titleName(titlesDB, id), id, ""})
+		i++
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", i})
+	renderTable(t, settingsObj)
+}
+
+// the name of a base or DLC titleId in the titles DB, empty when unknown
+func titleName(titlesDB *db.SwitchTitlesDB, titleId string) string {
+	switchTitle, ok := titlesDB.GetTitleById(titleId)
+	if !ok {
+		return ""
+	}
+	if dlc, ok := switchTitle.Dlc[strings.ToLower(titleId)]; ok {
+		return dlc.Name
+	}
+	return switchTitle.Attributes.Name
+}
+
+func processRecentlyAdded(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings, now time.Time) {
+	recentFiles := process.FindRecentlyAdded(localDB.Titles(), settingsObj.RecentlyAddedDays, now)
+	if len(recentFiles) == 0 {
+		fmt.Printf("\nNo files were added in the last %v days\n\n", settingsObj.RecentlyAddedDays)
+		return
+	}
+	fmt.Printf("\nFiles added in the last %v days:\n\n", settingsObj.RecentlyAddedDays)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "TitleId", "Type", "Modified"})
+	for i, f := range recentFiles {
+		t.AppendRow(table.Row{i, f.Info.Name(), f.Metadata.TitleId, f.Metadata.Type, process.FormatDate(f.Info.ModTime().Format(time.RFC3339), settingsObj.DateFormat)})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", len(recentFiles)})
+	renderTable(t, settingsObj)
+}
+
+func (c *Console) processIntegrity(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings) {
+	cacheFolder, err := settings.CacheFolder(c.baseFolder)
+	if err != nil {
+		fmt.Printf("\nfailed to create cache folder %v\n", err)
+		return
+	}
+	recordsPath := filepath.Join(cacheFolder, settings.INTEGRITY_FILENAME)
+	records, err := process.LoadIntegrityRecords(recordsPath)
+	if err != nil {
+		zap.S().Warnf("failed to read integrity records, all the hashes will be recorded again - %v", err)
+		records = map[string]process.IntegrityRecord{}
+	}
+	titles := localDB.Titles()
+	mismatches, failures := process.VerifyIntegrity(titles, records, settingsObj.HashConcurrency)
+	err = process.SaveIntegrityRecords(recordsPath, records)
+	if err != nil {
+		zap.S().Errorf("failed to save integrity records - %v", err)
+	}
+	s.Stop()
+	for _, f := range failures {
+		zap.S().Errorf("failed to hash file %v - %v", f.Path, f.Err)
+	}
+	if settingsObj.WriteHashSidecars {
+		written, sidecarFailures := process.WriteHashSidecars(titles, records)
+		for _, f := range sidecarFailures {
+			zap.S().Errorf("failed to write the hash file of %v - %v", f.Path, f.Err)
+		}
+		fmt.Printf("\nWrote %v %v files (%v could not be written)\n", written, process.HASH_SIDECAR_EXTENSION, len(sidecarFailures))
+	}
+	if len(mismatches) == 0 {
+		fmt.Printf("\nNo corrupted files were found (%v files could not be read)\n", len(failures))
+		return
+	}
+	fmt.Print("\nFound files whose content changed since the last check, without being modified:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "Recorded hash", "Current hash"})
+	for i, v := range mismatches {
+		t.AppendRow(table.Row{i, v.Path, v.RecordedHash, v.ActualHash})
+	}
+	t.AppendFooter(table.Row{"", "", "Total", len(mismatches)})
+	renderTable(t, settingsObj)
+}
+
+func processNotOwnedTitles(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	localTitles, switchTitles := completionTitles(localDB, titlesDB, settingsObj)
+	mergedLocal, mergedTitles := process.MergeTitleAliases(localTitles, switchTitles, settingsObj.TitleAliases)
+	notOwned := process.FindNotOwnedTitles(mergedLocal, mergedTitles, settingsObj.NotOwnedRegions)
+	if notOwnedFile != nil && *notOwnedFile != "" {
+		exportNotOwnedTitles(notOwned, *notOwnedFile)
+	}
+	if !settingsObj.ReportNotOwnedTitles {
+		return
+	}
+	fmt.Printf("\nTitles you don't own (%v):\n\n", len(notOwned))
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetPageSize(settingsObj.GuiPagingSize)
+	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Region"})
+	for i, v := range notOwned {
+		t.AppendRow(table.Row{i, v.Name, v.Id, v.Region})
+	}
+	t.AppendFooter(table.Row{"", "", "Total", len(notOwned)})
+	renderTable(t, settingsObj)
+}
+
+func exportNotOwnedTitles(notOwned []db.TitleAttributes, exportPath string) {
+	file, err := os.Create(exportPath)
+	if err != nil {
+		fmt.Printf("\nfailed to create export file %v\n", err)
+		return
+	}
+	defer file.Close()
+	csvWriter := csv.NewWriter(file)
+	_ = csvWriter.Write([]string{"title_id", "name", "region"})
+	for _, v := range notOwned {
+		_ = csvWriter.Write([]string{v.Id, v.Name, v.Region})
+	}
+	csvWriter.Flush()
+	if err = csvWriter.Error(); err != nil {
+		fmt.Printf("\nfailed to write export file %v\n", err)
+		return
+	}
+	fmt.Printf("\nExported %v titles to %v\n", len(notOwned), exportPath)
+}
+
+func (c *Console) processUnrecognizedTitles(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	unrecognized := process.ScanForUnrecognizedTitles(localDB.Titles(), titlesDB.TitlesMap, settingsObj.FuzzyMatchThreshold)
+	if len(unrecognized) == 0 {
+		return
+	}
+	var newReleases []process.UnrecognizedTitle
+	if settingsObj.NewReleaseGraceDays > 0 {
+		refreshed, err := db.TitlesDBRefreshTime(c.baseFolder, settingsObj)
+		if err != nil {
+			zap.S().Warnf("failed to get the titles DB refresh time - %v", err)
+		} else {
+			process.MarkNewReleases(unrecognized, refreshed, time.Duration(settingsObj.NewReleaseGraceDays)*24*time.Hour)
+			var unknown []process.UnrecognizedTitle
+			for _, v := range unrecognized {
+				if v.LikelyNewRelease {
+					newReleases = append(newReleases, v)
+				} else {
+					unknown = append(unknown, v)
+				}
+			}
+			unrecognized = unknown
+		}
+	}
+	if len(unrecognized) != 0 {
+		fmt.Print("\nFound local titles which are not in the titles DB:\n\n")
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"#", "File", "TitleID", "Suggested title", "Suggested TitleID"})
+		for i, v := range unrecognized {
+			suggestedName, suggestedId := "", ""
+			if v.Suggestion != nil {
+				suggestedName, suggestedId = v.Suggestion.Name, v.Suggestion.Id
+			}
+			t.AppendRow(table.Row{i, v.File.Info.Name(), v.TitleId, suggestedName, suggestedId})
+		}
+		t.AppendFooter(table.Row{"", "Total", len(unrecognized)})
+		renderTable(t, settingsObj)
+	}
+	if len(newReleases) != 0 {
+		fmt.Print("\nLocal titles which are likely newer than the titles DB:\n\n")
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"#", "File", "TitleID", "Modified"})
+		for i, v := range newReleases {
+			t.AppendRow(table.Row{i, v.File.Info.Name(), v.TitleId, process.FormatDate(v.File.Info.ModTime().Format(time.RFC3339), settingsObj.DateFormat)})
+		}
+		t.AppendFooter(table.Row{"", "Total", len(newReleases)})
+		renderTable(t, settingsObj)
+	}
+}
+
+func processTitleIdMismatches(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings) {
+	mismatches := process.ScanForTitleIdMismatches(localDB.Titles())
+	if len(mismatches) == 0 {
+		return
+	}
+	fmt.Print("\nFound files whose titleId tag does not match their content:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "File name TitleId", "Actual TitleId"})
+	for i, v := range mismatches {
+		t.AppendRow(table.Row{i, v.File.Info.Name(), v.FileNameTitleId, v.MetadataTitleId})
+	}
+	t.AppendFooter(table.Row{"", "Total", len(mismatches)})
+	renderTable(t, settingsObj)
+}
+
+func processProblematicFileNames(localDB *db.LocalSwitchFilesDB, settingsObj *settings.AppSettings) {
+	problematicFiles := process.ScanForProblematicFileNames(localDB.Titles())
+	if len(problematicFiles) == 0 {
+		return
+	}
+	fmt.Print("\nFound files with non-ASCII or problematic characters in their name:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "File", "Suggested name"})
+	for i, v := range problematicFiles {
+		t.AppendRow(table.Row{i, v.File.Info.Name(), v.SuggestedName})
+	}
+	t.AppendFooter(table.Row{"", "Total", len(problematicFiles)})
+	renderTable(t, settingsObj)
+}
+
+func processExport(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, exportPath string) {
+	items := process.ExportLibrary(localDB.Titles(), titlesDB.TitlesMap)
+	file, err := os.Create(exportPath)
+	if err != nil {
+		fmt.Printf("\nfailed to create export file %v\n", err)
+		return
+	}
+	defer file.Close()
+	if strings.ToLower(filepath.Ext(exportPath)) == ".json" {
+		err = process.WriteLibraryJSON(file, items)
+	} else {
+		err = process.WriteLibraryCSV(file, items)
+	}
+	if err != nil {
+		fmt.Printf("\nfailed to write export file %v\n", err)
+		return
+	}
+	fmt.Printf("\nExported %v files to %v\n", len(items), exportPath)
+}
+
+func processReconcile(localDB *db.LocalSwitchFilesDB, inventoryPath string, settingsObj *settings.AppSettings) {
+	file, err := os.Open(inventoryPath)
+	if err != nil {
+		fmt.Printf("\nfailed to open inventory file %v\n", err)
+		return
+	}
+	defer file.Close()
+	inventory, err := process.ReadInventory(file)
+	if err != nil {
+		fmt.Printf("\nfailed to parse inventory file %v\n", err)
+		return
+	}
+	result := process.ReconcileInventory(localDB.Titles(), inventory)
+	renderReconcileResult(result, "inventory", settingsObj)
+}
+
+func processHaveList(localDB *db.LocalSwitchFilesDB, haveListPath string) {
+	file, err := os.Create(haveListPath)
+	if err != nil {
+		fmt.Printf("\nfailed to create have list file %v\n", err)
+		return
+	}
+	defer file.Close()
+	err = process.WriteHaveList(file, process.LocalInventory(localDB.Titles()))
+	if err != nil {
+		fmt.Printf("\nfailed to write have list file %v\n", err)
+		return
+	}
+	fmt.Printf("\nHave list written to %v\n", haveListPath)
+}
+
+func processCompareHaveList(localDB *db.LocalSwitchFilesDB, haveListPath string, settingsObj *settings.AppSettings) {
+	file, err := os.Open(haveListPath)
+	if err != nil {
+		fmt.Printf("\nfailed to open have list file %v\n", err)
+		return
+	}
+	defer file.Close()
+	items, _, err := process.ReadHaveList(file)
+	if err != nil {
+		fmt.Printf("\nfailed to parse have list file %v\n", err)
+		return
+	}
+	local := process.LocalInventory(localDB.Titles())
+	if process.HaveListHash(process.CanonicalHaveList(local)) == process.HaveListHash(process.CanonicalHaveList(items)) {
+		fmt.Print("\nLocal library matches the have list!\n\n")
+		return
+	}
+	renderReconcileResult(process.CompareInventories(local, items), "have list", settingsObj)
+}
+
+func renderReconcileResult(result process.ReconcileResult, otherName string, settingsObj *settings.AppSettings) {
+	total := len(result.MissingHere) + len(result.ExtraHere) + len(result.VersionMismatch)
+	if total == 0 {
+		fmt.Printf("\nLocal library matches the %v!\n\n", otherName)
+		return
+	}
+	fmt.Printf("\nDifferences from the %v:\n\n", otherName)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Status", "TitleId", "Local version", otherName + " version"})
+	i := 0
+	for _, v := range result.MissingHere {
+		t.AppendRow(table.Row{i, "missing here", v.TitleId, "", v.Version})
+		i++
+	}
+	for _, v := range result.ExtraHere {
+		t.AppendRow(table.Row{i, "extra here", v.TitleId, v.Version, ""})
+		i++
+	}
+	for _, v := range result.VersionMismatch {
+		t.AppendRow(table.Row{i, "version mismatch", v.TitleId, v.LocalVersion, v.InventoryVersion})
+		i++
+	}
+	t.AppendFooter(table.Row{"", "", "", "Total", total})
+	renderTable(t, settingsObj)
+}
+
+func processDownloads(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	titles := localDB.Titles()
+	missingUpdates := process.ScanForMissingUpdatesWithWorkers(process.ExcludeBlacklisted(titles, process.NewBlacklist(settingsObj.BlacklistedVersions)),
+		titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	missingDLC := process.ScanForMissingDLCWithWorkers(titles, titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	requests := process.DownloadRequests(missingUpdates, missingDLC, titlesDB.TitlesMap)
+	if len(requests) == 0 {
+		return
+	}
+	results := process.RunDownloader(settingsObj.DownloaderCommand, requests)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "TitleId", "Version", "Exit code", "Result"})
+	failed := 0
+	for i, v := range results {
+		status := "success"
+		if v.Error != "" {
+			status = v.Error
+			failed++
+		}
+		t.AppendRow(table.Row{i, v.Item.TitleId, v.Item.Version, v.ExitCode, status})
+	}
+	t.AppendFooter(table.Row{"", "", "", "Failed", failed})
+	renderTable(t, settingsObj)
+}
+
+func processDownloadPlan(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB, settingsObj *settings.AppSettings) {
+	titles := localDB.Titles()
+	missingUpdates := process.ScanForMissingUpdatesWithWorkers(process.ExcludeBlacklisted(titles, process.NewBlacklist(settingsObj.BlacklistedVersions)),
+		titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	missingDLC := process.ScanForMissingDLCWithWorkers(titles, titlesDB.TitlesMap, settingsObj.AnalysisWorkers)
+	candidates := process.DownloadCandidates(missingUpdates, missingDLC, titlesDB.TitlesMap, settingsObj.FavoriteTitles)
+	budget := settingsObj.DownloadBudgetMB * 1024 * 1024
+	chosen, remaining := process.PlanDownloads(candidates, budget, settingsObj.DownloadStrategy)
+
+	fmt.Printf("\nSuggested downloads within %v MB:\n\n", settingsObj.DownloadBudgetMB)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Name", "TitleId", "Version", "Favorite", "Size (MB)"})
+	for i, c := range chosen {
+		favorite := ""
+		if c.Favorite {
+			favorite = "*"
+		}
+		t.AppendRow(table.Row{i, c.Name, c.TitleId, c.Version, favorite, c.Size / 1024 / 1024})
+	}
+	t.AppendFooter(table.Row{"", "", "", "", "Left (MB)", remaining / 1024 / 1024})
+	renderTable(t, settingsObj)
+}
+
+func renderTable(t table.Writer, settingsObj *settings.AppSettings) {
+	switch settingsObj.OutputFormat {
+	case settings.OUTPUT_FORMAT_MARKDOWN:
+		t.RenderMarkdown()
+		fmt.Println()
+		return
+	case settings.OUTPUT_FORMAT_BBCODE:
+		fmt.Println(renderBBCode(t))
+		return
+	}
+	t.SetStyle(tableStyle(settingsObj.TableStyle))
 	t.Render()
 }
+
+var tableStyles = map[string]table.Style{
+	settings.TABLE_STYLE_COLORED_BRIGHT: table.StyleColoredBright,
+	settings.TABLE_STYLE_DEFAULT:        table.StyleDefault,
+	settings.TABLE_STYLE_LIGHT:          table.StyleLight,
+	settings.TABLE_STYLE_ROUNDED:        table.StyleRounded,
+	settings.TABLE_STYLE_DOUBLE:         table.StyleDouble,
+	settings.TABLE_STYLE_BOLD:           table.StyleBold,
+}
+
+// the auto style uses colors on a terminal, and plain ASCII when the output is redirected to a file
+func tableStyle(name string) table.Style {
+	if style, ok := tableStyles[name]; ok {
+		return style
+	}
+	if name != settings.TABLE_STYLE_AUTO && name != "" {
+		zap.S().Warnf("Unknown table style [%v], using the auto style", name)
+	}
+	if isTerminal(consoleStdout) {
+		return table.StyleColoredBright
+	}
+	return table.StyleDefault
+}
+
+const defaultTerminalWidth = 80
+
+// the terminal width is queried from the terminal, falling back to the default width when it is unknown
+func terminalWidth() int {
+	return widthOrDefault(terminalColumns(consoleStdout))
+}
+
+func widthOrDefault(columns int) int {
+	if columns > 0 {
+		return columns
+	}
+	return defaultTerminalWidth
+}
+
+// renders the percentage as a bar, for example [██████░░░░] 60.00%, fitting within width chars
+func completionBar(percent float32, width int) string {
+	label := fmt.Sprintf(" %.2f%%", percent)
+	barWidth := width - len(label) - 2
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	filled := int(float32(barWidth) * percent / 100)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled) + "]" + label
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// markdown and bbcode reports are meant to be pasted elsewhere, so they are kept free of colors and spinner output
+func isPlainOutput(settingsObj *settings.AppSettings) bool {
+	return settingsObj.OutputFormat == settings.OUTPUT_FORMAT_MARKDOWN || settingsObj.OutputFormat == settings.OUTPUT_FORMAT_BBCODE
+}
+
+// spinnerProgress shows the progress of the running operation next to the spinner
+type spinnerProgress struct{}
+
+func (spinnerProgress) UpdateProgress(curr int, total int, message string) {
+	s.Lock()
+	s.Suffix = fmt.Sprintf(" %v/%v", curr, total)
+	s.Unlock()
+}
+
+// newProgressUpdater creates the progress updater of one operation, throttled to the configured interval
+func newProgressUpdater(settingsObj *settings.AppSettings) db.ProgressUpdater {
+	return db.NewThrottledProgressUpdater(spinnerProgress{}, time.Duration(settingsObj.ProgressIntervalMs)*time.Millisecond)
+}
+
+// the spinner is only drawn for the regular table output, to keep the plain output clean
+func startSpinner(settingsObj *settings.AppSettings) {
+	//the progress of the previous operation is not relevant anymore
+	s.Lock()
+	s.Suffix = ""
+	s.Unlock()
+	//the spinner animation is only meaningful on a terminal, and would make redirected output differ between runs
+	if isPlainOutput(settingsObj) || !isTerminal(consoleStdout) {
+		return
+	}
+	s.Restart()
+}
@@ -0,0 +1,699 @@
+package ui
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/process"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"github.com/jedib0t/go-pretty/table"
+	"go.uber.org/zap"
+)
+
+// captureStdout returns what f prints to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	file, err := ioutil.TempFile("", "slm-stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+	f()
+	output, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+// assertMarkdownTables checks that the lines of the tables in the output (the lines starting with a pipe) form
+// markdown tables: a header, a separator line and rows, all with the same number of cells
+func assertMarkdownTables(t *testing.T, output string) {
+	t.Helper()
+	var table []string
+	tables := 0
+	check := func() {
+		if len(table) == 0 {
+			return
+		}
+		tables++
+		if len(table) < 2 || !regexp.MustCompile(`^\|( *-+:? *\|)+$`).MatchString(table[1]) {
+			t.Errorf("table %q has no header separator line", table)
+		}
+		//pipes within the cells are escaped
+		cellsOf := func(line string) int { return strings.Count(strings.ReplaceAll(line, `\|`, ""), "|") }
+		cells := cellsOf(table[0])
+		for _, line := range table {
+			if !strings.HasSuffix(line, "|") || cellsOf(line) != cells {
+				t.Errorf("line %q of table %q does not have the cells of the header", line, table)
+			}
+		}
+		table = nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "|") {
+			table = append(table, line)
+			continue
+		}
+		check()
+	}
+	check()
+	if tables == 0 {
+		t.Errorf("no markdown table in %q", output)
+	}
+}
+
+func TestMarkdownReports(t *testing.T) {
+	titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game | Deluxe"},
+			Updates: map[int]string{65536: "2020-01-01"},
+			Dlc:     map[string]db.TitleAttributes{"0100000000011001": {Id: "0100000000011001", Name: "DLC", Version: "0"}}},
+	}}
+	localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{"010000000001": {BaseExist: true,
+		File:    db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000010000"}},
+		Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}}}}
+	settingsObj := &settings.AppSettings{OutputFormat: settings.OUTPUT_FORMAT_MARKDOWN}
+
+	tests := []struct {
+		name   string
+		report func()
+	}{
+		{"missing updates", func() { processMissingUpdates(localDB, titlesDB, settingsObj) }},
+		{"missing DLC", func() { processMissingDLC(localDB, titlesDB, settingsObj) }},
+		{"library stats", func() { processLibraryStats(localDB, titlesDB, settingsObj) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := captureStdout(t, test.report)
+			assertMarkdownTables(t, output)
+			if strings.Contains(output, "\x1b[") {
+				t.Errorf("got colors in the markdown output %q", output)
+			}
+		})
+	}
+}
+
+// cannedTitlesProvider returns a fixed titles DB instead of downloading the titles/versions json files
+type cannedTitlesProvider struct {
+	titlesDB *db.SwitchTitlesDB
+}
+
+func (p cannedTitlesProvider) LoadTitlesDB() (*db.SwitchTitlesDB, error) {
+	return p.titlesDB, nil
+}
+
+func TestConsoleWithACannedTitlesProvider(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	library := filepath.Join(folder, "library")
+	if err := os.Mkdir(library, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	//without keys the file is identified by its name tags
+	t.Setenv("HOME", folder)
+	t.Setenv("SLM_KEYS", "")
+	if err := ioutil.WriteFile(filepath.Join(library, "game [0100000000010000][v0].nsp"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings.SaveSettings(&settings.AppSettings{Folder: library, CheckForMissingUpdates: true}, folder)
+
+	console := CreateConsole(folder, zap.NewNop().Sugar())
+	console.SetTitlesProvider(cannedTitlesProvider{&db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Canned Game"}, Updates: map[int]string{65536: "2020-01-01"}},
+	}}})
+	output := captureStdout(t, console.Start)
+
+	//the missing update comes from the canned titles DB
+	if !strings.Contains(output, "Found available updates") || !strings.Contains(output, "Canned Game") || !strings.Contains(output, "65536") {
+		t.Errorf("got output %q, want the update of the canned titles DB reported as missing", output)
+	}
+}
+
+func TestTableStyle(t *testing.T) {
+	tests := []struct {
+		style    string
+		wantText string
+	}{
+		{settings.TABLE_STYLE_DEFAULT, "+-------+"},
+		{settings.TABLE_STYLE_LIGHT, "┌───────┐"},
+		{settings.TABLE_STYLE_ROUNDED, "╭───────╮"},
+		{settings.TABLE_STYLE_DOUBLE, "╔═══════╗"},
+		{settings.TABLE_STYLE_COLORED_BRIGHT, "\x1b["},
+	}
+	for _, test := range tests {
+		t.Run(test.style, func(t *testing.T) {
+			var output strings.Builder
+			tw := table.NewWriter()
+			tw.SetOutputMirror(&output)
+			tw.AppendHeader(table.Row{"Title"})
+			tw.AppendRow(table.Row{"Game"})
+			renderTable(tw, &settings.AppSettings{TableStyle: test.style})
+			if !strings.Contains(output.String(), test.wantText) {
+				t.Errorf("got table %q, want it to contain %q", output.String(), test.wantText)
+			}
+		})
+	}
+
+	//the auto style (and an unknown one) depend on the output being a terminal
+	want := table.StyleDefault
+	if isTerminal(os.Stdout) {
+		want = table.StyleColoredBright
+	}
+	for _, style := range []string{settings.TABLE_STYLE_AUTO, "", "unknown"} {
+		if got := tableStyle(style); got.Name != want.Name {
+			t.Errorf("got style %v for %q, want %v", got.Name, style, want.Name)
+		}
+	}
+}
+
+func TestLegacyModeGuidance(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"organize", "-m organize -> set organize_options.rename_files and/or organize_options.create_folder_per_game to true"},
+		{"rename", "-m rename -> set organize_options.rename_files to true"},
+		{"delete", "-m delete -> set organize_options.delete_old_update_files to true"},
+		{"updates", "-m updates -> set check_for_missing_updates to true"},
+		{"dlc", "-m dlc -> set check_for_missing_dlc to true"},
+		{" Scan ", "-m  Scan  -> set check_for_missing_updates and check_for_missing_dlc to true"},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			got := legacyModeGuidance(test.mode)
+			if !strings.Contains(got, "deprecated") || !strings.Contains(got, test.want) {
+				t.Errorf("got %q, want the deprecation note and %q", got, test.want)
+			}
+		})
+	}
+	if got := legacyModeGuidance("unknown"); strings.Contains(got, "->") {
+		t.Errorf("got %q for an unknown mode, want only the deprecation note", got)
+	}
+}
+
+func TestProcessSingleFile(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-single")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game"},
+			Dlc: map[string]db.TitleAttributes{"0100000000011001": {Id: "0100000000011001", Name: "Game DLC"}}},
+	}}
+	tests := []struct {
+		name     string
+		fileName string
+		want     []string
+	}{
+		{"base game", "game [0100000000010000][v0].nsp", []string{"game [0100000000010000][v0].nsp", "0100000000010000", "Game", "found"}},
+		{"DLC", "dlc [0100000000011001][v0].nsp", []string{"0100000000011001", "Game DLC", "found"}},
+		{"missing from the titles DB", "other [0100000000020000][v65536].nsp", []string{"0100000000020000", "65536", "not found"}},
+		{"no tags", "game.nsp", []string{"Unable to identify the file", "unable to determine titileId / version"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(folder, test.fileName)
+			if err := ioutil.WriteFile(filePath, []byte{}, 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			localDB, err := db.CreateLocalSwitchFilesDB([]os.FileInfo{info}, folder, nil, db.ScanOptions{DisableDeepScan: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := captureStdout(t, func() {
+				processSingleFile(localDB, titlesDB, &settings.AppSettings{TableStyle: settings.TABLE_STYLE_DEFAULT})
+			})
+			for _, text := range test.want {
+				if !strings.Contains(output, text) {
+					t.Errorf("got output %q, want it to contain %q", output, text)
+				}
+			}
+		})
+	}
+}
+
+// testLibrary is the same game released under a US and a EU titleId (each with an update and a DLC),
+// of which only the EU base game is owned, and another game whose base and DLC are owned
+func testLibrary() (*db.LocalSwitchFilesDB, *db.SwitchTitlesDB) {
+	titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Game", Region: "US"},
+			Updates: map[int]string{65536: "2020-01-01"},
+			Dlc:     map[string]db.TitleAttributes{"0100000000011001": {Id: "0100000000011001", Name: "Game DLC", Version: "0"}}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Game", Region: "EU"},
+			Updates: map[int]string{65536: "2020-01-01"},
+			Dlc:     map[string]db.TitleAttributes{"0100000000021001": {Id: "0100000000021001", Name: "Game DLC", Version: "0"}}},
+		"010000000003": {Attributes: db.TitleAttributes{Id: "0100000000030000", Name: "Other", Region: "US"},
+			Updates: map[int]string{},
+			Dlc:     map[string]db.TitleAttributes{"0100000000031001": {Id: "0100000000031001", Name: "Other DLC", Version: "0"}}},
+	}}
+	localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{
+		"010000000002": {BaseExist: true, File: db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000020000"}},
+			Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}},
+		"010000000003": {BaseExist: true, File: db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000030000"}},
+			Updates: map[int]db.ExtendedFileInfo{},
+			Dlc:     map[string]db.ExtendedFileInfo{"0100000000031001": {Metadata: &switchfs.ContentMetaAttributes{TitleId: "0100000000031001"}}}},
+	}}
+	return localDB, titlesDB
+}
+
+func TestReportsHonorTitleAliases(t *testing.T) {
+	tests := []struct {
+		name          string
+		aliases       map[string]string
+		wantStats     []string
+		wantMissing   string
+		unwantMissing string
+	}{
+		{"no aliases", nil, []string{"have 2 titles, out of 3 titles", "have 1 titles with the base, latest update and all DLC, out of 3 titles",
+			"have 1 DLC, out of 2 DLC"}, "0100000000020000", "0100000000010000"},
+		{"EU aliased to US", map[string]string{"0100000000020000": "0100000000010000"}, []string{"have 2 titles, out of 2 titles",
+			"have 1 titles with the base, latest update and all DLC, out of 2 titles", "have 1 DLC, out of 2 DLC"}, "0100000000010000", "0100000000020000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB, titlesDB := testLibrary()
+			settingsObj := &settings.AppSettings{CountPartialAsOwned: true, TitleAliases: test.aliases}
+
+			stats := captureStdout(t, func() { processLibraryStats(localDB, titlesDB, settingsObj) })
+			for _, want := range test.wantStats {
+				if !strings.Contains(stats, want) {
+					t.Errorf("got the stats %q, want %q", stats, want)
+				}
+			}
+			reports := map[string]func(){
+				"missing updates": func() { processMissingUpdates(localDB, titlesDB, settingsObj) },
+				"missing DLC":     func() { processMissingDLC(localDB, titlesDB, settingsObj) },
+			}
+			for name, report := range reports {
+				output := captureStdout(t, report)
+				if !strings.Contains(output, test.wantMissing) || strings.Contains(output, test.unwantMissing) {
+					t.Errorf("got the %v %q, want only %v", name, output, test.wantMissing)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionCompletionHonorsTitleAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		want    map[string]process.LibraryCompletion
+	}{
+		{"per region", nil, map[string]process.LibraryCompletion{
+			"US": {Owned: 1, Total: 2, Percent: 50},
+			"EU": {Owned: 1, Total: 1, Percent: 100},
+		}},
+		{"aliased regional release", map[string]string{"0100000000020000": "0100000000010000"}, map[string]process.LibraryCompletion{
+			"US": {Owned: 2, Total: 2, Percent: 100},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localDB, titlesDB := testLibrary()
+			settingsObj := &settings.AppSettings{CountPartialAsOwned: true, TitleAliases: test.aliases}
+			if got := computeRegionStats(localDB, titlesDB, settingsObj); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got the completion per region %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProgressUpdaterShowsThrottledProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		updates    int
+		wantSuffix string
+	}{
+		{"first update", 1, " 1/3"},
+		{"throttled update", 2, " 1/3"},
+		{"final update", 3, " 3/3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			progress := newProgressUpdater(&settings.AppSettings{ProgressIntervalMs: 3600 * 1000})
+			for i := 1; i <= test.updates; i++ {
+				progress.UpdateProgress(i, 3, "file")
+			}
+			if s.Suffix != test.wantSuffix {
+				t.Errorf("suffix %q, want %q", s.Suffix, test.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestCompletionBarFitsTheTerminal(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns int
+		percent float32
+		want    string
+	}{
+		{"unknown width", 0, 50, "[" + strings.Repeat("█", 35) + strings.Repeat("░", 36) + "] 50.00%"},
+		{"terminal width", 30, 100, "[" + strings.Repeat("█", 20) + "] 100.00%"},
+		{"narrow terminal", 5, 0, "[" + strings.Repeat("░", 10) + "] 0.00%"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := completionBar(test.percent, widthOrDefault(test.columns))
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if test.columns >= 20 && utf8.RuneCountInString(got) != test.columns {
+				t.Errorf("the bar is %v chars wide, want %v", utf8.RuneCountInString(got), test.columns)
+			}
+		})
+	}
+}
+
+func TestTerminalColumnsOfRedirectedOutput(t *testing.T) {
+	file, err := ioutil.TempFile("", "slm-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if columns := terminalColumns(file); columns != 0 {
+		t.Errorf("got %v columns for a file", columns)
+	}
+}
+
+func TestReadScanTargets(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	other, err := ioutil.TempDir("", "slm-targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(other)
+	first, second := filepath.Join(folder, "first.nsp"), filepath.Join(folder, "second.nsp")
+	for _, file := range []string{first, second} {
+		if err := ioutil.WriteFile(file, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name            string
+		targets         []string
+		wantSingleFile  bool
+		wantOrganized   []string
+		wantScanFolders int
+	}{
+		{"folders", []string{folder, other}, false, []string{folder, other}, 2},
+		{"single file", []string{first}, true, []string{folder}, 1},
+		{"files of the same folder", []string{first, second}, true, []string{folder}, 2},
+		{"file and folder", []string{first, other}, false, []string{folder, other}, 2},
+		{"missing folder", []string{filepath.Join(other, "missing"), other}, false, []string{other}, 1},
+		{"nothing readable", []string{filepath.Join(other, "missing")}, false, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanFolders, singleFile := readScanTargets(test.targets, 0)
+			if singleFile != test.wantSingleFile {
+				t.Errorf("single file %v, want %v", singleFile, test.wantSingleFile)
+			}
+			if len(scanFolders) != test.wantScanFolders {
+				t.Errorf("%v scan folders, want %v", len(scanFolders), test.wantScanFolders)
+			}
+			if got := organizedFolders(scanFolders); !reflect.DeepEqual(got, test.wantOrganized) {
+				t.Errorf("organized folders %v, want %v", got, test.wantOrganized)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"y", "y\n", true},
+		{"yes", "Yes\n", true},
+		{"spaces", "  y  \n", true},
+		{"without a new line", "y", true},
+		{"n", "n\n", false},
+		{"no", "no\n", false},
+		{"empty line", "\n", false},
+		{"no input", "", false},
+		{"other", "sure\n", false},
+		{"only the first line", "n\ny\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := confirm(strings.NewReader(test.input), "Continue?"); got != test.want {
+				t.Errorf("confirm(%q) = %v, want %v", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPipedInputIsNotAsked(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	tests := []struct {
+		name      string
+		assumeYes bool
+	}{
+		{"piped", false},
+		{"piped with -yes", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if needsConfirmation(test.assumeYes, reader) {
+				t.Error("asked to confirm on piped input")
+			}
+		})
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the reports")
+
+// goldenLibrary is a library of titles missing updates and DLC, with several titles sharing the same name
+func goldenLibrary() (*db.LocalSwitchFilesDB, *db.SwitchTitlesDB) {
+	localDB := &db.LocalSwitchFilesDB{TitlesMap: map[string]*db.SwitchFile{}}
+	titlesDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{}}
+	for i := 0; i < 12; i++ {
+		idPrefix := fmt.Sprintf("0100000000%02x", i)
+		titleId := strings.ToUpper(idPrefix) + "0000"
+		dlcId := strings.ToUpper(idPrefix) + "1001"
+		titlesDB.TitlesMap[idPrefix] = &db.SwitchTitle{Attributes: db.TitleAttributes{Id: titleId, Name: fmt.Sprintf("Game %v", i%5), Region: "US"},
+			Updates: map[int]string{65536: "2020-01-01", 131072: "2020-02-01"},
+			Dlc:     map[string]db.TitleAttributes{dlcId: {Id: dlcId, Name: fmt.Sprintf("Game %v DLC", i%5), Version: "0"}}}
+		localDB.TitlesMap[idPrefix] = &db.SwitchFile{BaseExist: true, File: db.ExtendedFileInfo{Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}},
+			Updates: map[int]db.ExtendedFileInfo{}, Dlc: map[string]db.ExtendedFileInfo{}}
+	}
+	return localDB, titlesDB
+}
+
+func TestReportsMatchTheGoldenFiles(t *testing.T) {
+	localDB, titlesDB := goldenLibrary()
+	settingsObj := &settings.AppSettings{TableStyle: settings.TABLE_STYLE_DEFAULT, DateFormat: settings.DATE_FORMAT_ISO8601}
+	tests := []struct {
+		name   string
+		golden string
+		report func()
+	}{
+		{"missing updates", "missing_updates.golden", func() { processMissingUpdates(localDB, titlesDB, settingsObj) }},
+		{"missing DLC", "missing_dlc.golden", func() { processMissingDLC(localDB, titlesDB, settingsObj) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			goldenPath := filepath.Join("testdata", test.golden)
+			output := captureStdout(t, test.report)
+			if *updateGolden {
+				if err := ioutil.WriteFile(goldenPath, []byte(output), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//the map iteration order changes between runs, so render the report a few times
+			for i := 0; i < 5; i++ {
+				if i != 0 {
+					output = captureStdout(t, test.report)
+				}
+				if output != string(golden) {
+					t.Fatalf("the report differs from %v:\n%v", goldenPath, output)
+				}
+			}
+		})
+	}
+}
+
+func TestTitleQueryWithoutBaseTitleId(t *testing.T) {
+	localDB, titlesDB := testLibrary()
+	//a DLC whose game has no base entry in the titles DB, and a base entry with a truncated id
+	titlesDB.TitlesMap["010000000004"] = &db.SwitchTitle{
+		Dlc: map[string]db.TitleAttributes{"0100000000041001": {Id: "0100000000041001", Name: "Orphan DLC"}}}
+	titlesDB.TitlesMap["010000000005"] = &db.SwitchTitle{Attributes: db.TitleAttributes{Id: "01000000000500", Name: "Truncated"}}
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"DLC of a game without a base entry", "0100000000041001"},
+		{"truncated base titleId", "01000000000500"},
+		{"valid title", "0100000000010000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("query [%v] panicked: %v", test.query, r)
+				}
+			}()
+			processTitleQuery(localDB, titlesDB, test.query, &settings.AppSettings{})
+		})
+	}
+}
+
+func TestDLCReportKeepsItsTimeInTheCacheFolder(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-dlc-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	settings.SetSettingsFilePath(filepath.Join(folder, settings.SETTINGS_FILENAME))
+	defer settings.SetSettingsFilePath("")
+	settingsObj := settings.ReadSettings(folder)
+	settingsObj.RecentDLCDays = 30
+	before, err := ioutil.ReadFile(filepath.Join(folder, settings.SETTINGS_FILENAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localDB, titlesDB := testLibrary()
+	for _, now := range []time.Time{time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)} {
+		c := &Console{baseFolder: folder, now: func() time.Time { return now }}
+		c.processDLCReleases(localDB, titlesDB, settingsObj)
+		snapshot, found, err := process.LoadDLCReportSnapshot(filepath.Join(folder, settings.DLC_REPORT_SNAPSHOT_FILENAME))
+		if err != nil || !found || !snapshot.Time.Equal(now) {
+			t.Errorf("snapshot %v (found %v, %v), want %v", snapshot.Time, found, err, now)
+		}
+	}
+	after, err := ioutil.ReadFile(filepath.Join(folder, settings.SETTINGS_FILENAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Error("the settings file was changed")
+	}
+}
+
+func TestSinceLastRunSkipsIncompleteScans(t *testing.T) {
+	tests := []struct {
+		name         string
+		partial      bool
+		unreachable  []string
+		wantRecorded bool
+	}{
+		{"complete scan", false, nil, true},
+		{"partial scan", true, nil, false},
+		{"unreachable folder", false, []string{"/library/old"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-since-last-run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			settings.SetSettingsFilePath(filepath.Join(folder, settings.SETTINGS_FILENAME))
+			defer settings.SetSettingsFilePath("")
+			settingsObj := settings.ReadSettings(folder)
+			snapshotPath := filepath.Join(folder, settings.RUN_SNAPSHOT_FILENAME)
+			previousRun := process.RunSnapshot{Time: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)}
+			if err := process.SaveRunSnapshot(snapshotPath, previousRun); err != nil {
+				t.Fatal(err)
+			}
+
+			localDB, titlesDB := testLibrary()
+			localDB.Partial, localDB.UnreachableFolders = test.partial, test.unreachable
+			now := time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)
+			c := &Console{baseFolder: folder, now: func() time.Time { return now }}
+			c.processSinceLastRun(localDB, titlesDB, settingsObj)
+
+			snapshot, _, err := process.LoadRunSnapshot(snapshotPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recorded := snapshot.Time.Equal(now); recorded != test.wantRecorded {
+				t.Errorf("run recorded: %v, want %v", recorded, test.wantRecorded)
+			}
+			if !test.wantRecorded && len(snapshot.Titles) != 0 {
+				t.Errorf("the previous run snapshot was changed: %+v", snapshot)
+			}
+		})
+	}
+}
+
+func TestComputedReports(t *testing.T) {
+	localDB, titlesDB := testLibrary()
+	euGame := titlesDB.TitlesMap["010000000002"].Attributes
+	euMeta := localDB.TitlesMap["010000000002"].File.Metadata
+	settingsObj := &settings.AppSettings{CountPartialAsOwned: true}
+	tests := []struct {
+		name    string
+		compute func() interface{}
+		want    interface{}
+	}{
+		{"library stats", func() interface{} { return computeLibraryStats(localDB, titlesDB, settingsObj) }, libraryStats{
+			Completion:       process.LibraryCompletion{Owned: 2, Total: 3, Percent: float32(2) / float32(3) * 100},
+			StrictCompletion: process.LibraryCompletion{Owned: 1, Total: 3, Percent: float32(1) / float32(3) * 100},
+			DLCCompletion:    process.LibraryCompletion{Owned: 1, Total: 2, Percent: 50},
+		}},
+		{"missing updates", func() interface{} { return computeMissingUpdates(localDB, titlesDB, settingsObj) }, missingContentReport{
+			Titles:       []process.IncompleteTitle{{Attributes: euGame, Meta: euMeta, LatestUpdate: 65536, LatestUpdateDate: "2020-01-01"}},
+			MissingSizes: []string{"unknown"},
+			Gaps: []process.UpdateGapBucket{
+				{Label: "1 behind", Min: 1, Max: 1, Count: 1},
+				{Label: "2-5 behind", Min: 2, Max: 5},
+				{Label: "6+ behind", Min: 6},
+			},
+		}},
+		{"missing DLC", func() interface{} { return computeMissingDLC(localDB, titlesDB, settingsObj) }, missingContentReport{
+			Titles: []process.IncompleteTitle{{Attributes: euGame, MissingDLC: []string{"Game DLC [0100000000021001]"},
+				MissingDLCIds: []string{"0100000000021001"}, TotalDLC: 1}},
+			MissingSizes: []string{"unknown"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.compute(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/jedib0t/go-pretty/table"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	checkOk      = "OK"
+	checkWarning = "WARNING"
+	checkFailed  = "FAILED"
+)
+
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func() (string, error)
+}
+
+// RunHealthCheck validates the settings, keys, network access and library folder before a run.
+// returns false if any of the critical checks failed.
+func (c *Console) RunHealthCheck() bool {
+	checks := []healthCheck{
+		{name: "Settings file", critical: true, run: c.checkSettings},
+		{name: "Keys file", critical: false, run: c.checkKeys},
+		{name: "Titles DB host", critical: false, run: checkNetwork},
+		{name: "Library folder", critical: true, run: c.checkFolder},
+	}
+
+	healthy := true
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Check", "Status", "Details"})
+	for _, check := range checks {
+		details, err := check.run()
+		status := checkOk
+		if err != nil {
+			details = err.Error()
+			status = checkWarning
+			if check.critical {
+				status = checkFailed
+				healthy = false
+			}
+		}
+		t.AppendRow(table.Row{check.name, status, details})
+	}
+	renderTable(t, settings.ReadSettings(c.baseFolder))
+	return healthy
+}
+
+func (c *Console) checkSettings() (string, error) {
+	err := settings.ValidateSettingsFile(c.baseFolder)
+	if err != nil {
+		return "", err
+	}
+	return "settings parsed successfully", nil
+}
+
+func (c *Console) checkKeys() (string, error) {
+	keys, err := settings.InitSwitchKeys(c.baseFolder)
+	if err != nil {
+		return "", err
+	}
+	headerKey, err := hex.DecodeString(keys.GetKey("header_key"))
+	if err != nil || len(headerKey) != 32 {
+		return "", fmt.Errorf("invalid or missing header_key, deep scan is disabled")
+	}
+	return "keys loaded, deep scan is enabled", nil
+}
+
+func checkNetwork() (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Head(settings.TITLES_JSON_URL)
+	if err != nil {
+		return "", fmt.Errorf("titles host is unreachable, the cached titles DB will be used - %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("titles host responded with %v", resp.Status)
+	}
+	return "titles host is reachable", nil
+}
+
+func (c *Console) checkFolder() (string, error) {
+	settingsObj := settings.ReadSettings(c.baseFolder)
+	folder := settingsObj.Folder
+	if nspFolder != nil && *nspFolder != "" {
+		folder = *nspFolder
+	}
+	if folder == "" {
+		return "", fmt.Errorf("no folder to scan was defined")
+	}
+	info, err := os.Stat(folder)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("[%v] is not a folder", folder)
+	}
+
+	options := settingsObj.OrganizeOptions
+	if options.RenameFiles || options.CreateFolderPerGame || options.DeleteOldUpdateFiles {
+		file, err := ioutil.TempFile(folder, ".slm-check")
+		if err != nil {
+			return "", fmt.Errorf("folder is not writable, organize options cannot be applied - %v", err)
+		}
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Sprintf("[%v] exists and is writable", folder), nil
+	}
+	return fmt.Sprintf("[%v] exists", folder), nil
+}
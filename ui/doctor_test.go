@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+// unreachableTransport fails every request, as when the titles host cannot be reached
+type unreachableTransport struct{}
+
+func (unreachableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("no route to host")
+}
+
+func TestHealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		folder      string
+		wantHealthy bool
+		wantRows    map[string][]string
+	}{
+		{"missing keys and unreachable titles DB", "library", true, map[string][]string{
+			"Settings file":  {checkOk, "settings parsed successfully"},
+			"Keys file":      {checkWarning, "couldn't find keys.prod"},
+			"Titles DB host": {checkWarning, "titles host is unreachable, the cached titles DB will be used", "no route to host"},
+			"Library folder": {checkOk, "exists"},
+		}},
+		{"missing library folder", "missing", false, map[string][]string{
+			"Keys file":      {checkWarning, "couldn't find keys.prod"},
+			"Library folder": {checkFailed, "no such file"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-doctor")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			if err := os.Mkdir(filepath.Join(folder, "library"), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			settingsJson := `{"folder": "` + filepath.ToSlash(filepath.Join(folder, test.folder)) + `"}`
+			if err := ioutil.WriteFile(filepath.Join(folder, settings.SETTINGS_FILENAME), []byte(settingsJson), 0644); err != nil {
+				t.Fatal(err)
+			}
+			settings.SetSettingsFilePath(filepath.Join(folder, settings.SETTINGS_FILENAME))
+			defer settings.SetSettingsFilePath("")
+			//no keys in the app folder, the home folder or the environment
+			t.Setenv("HOME", folder)
+			t.Setenv("SLM_KEYS", "")
+			defaultTransport := http.DefaultTransport
+			http.DefaultTransport = unreachableTransport{}
+			defer func() { http.DefaultTransport = defaultTransport }()
+
+			var healthy bool
+			output := captureStdout(t, func() { healthy = (&Console{baseFolder: folder}).RunHealthCheck() })
+			if healthy != test.wantHealthy {
+				t.Errorf("got healthy %v, want %v", healthy, test.wantHealthy)
+			}
+			for check, want := range test.wantRows {
+				row := ""
+				for _, line := range strings.Split(output, "\n") {
+					if strings.Contains(line, check) {
+						row = line
+					}
+				}
+				for _, text := range want {
+					if !strings.Contains(row, text) {
+						t.Errorf("got %v row %q, want it to contain %q", check, row, text)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/giwty/switch-library-manager/settings"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunFirstRunSetup creates the settings file of a new installation. on a terminal the user is asked for the
+// folder to scan, otherwise the defaults are written. either way, the next steps are printed.
+func RunFirstRunSetup(baseFolder string) {
+	settingsPath := settings.SettingsFilePath(baseFolder)
+	if _, err := os.Stat(settingsPath); err == nil {
+		return
+	}
+	folder := ""
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		fmt.Printf("Welcome to switch-library-manager! No settings file was found, creating %v\n", settingsPath)
+		folder = promptFolder(os.Stdin)
+	}
+	settingsObj := settings.ReadSettings(baseFolder)
+	if folder != "" {
+		settingsObj.Folder = folder
+		settings.SaveSettings(settingsObj, baseFolder)
+	}
+	if _, err := os.Stat(settingsPath); err != nil {
+		fmt.Printf("failed to create the settings file %v - %v\n", settingsPath, err)
+		return
+	}
+	fmt.Print(firstRunGuidance(settingsPath, settingsObj))
+}
+
+func promptFolder(input io.Reader) string {
+	reader := bufio.NewReader(input)
+	for {
+		fmt.Print("Folder holding your NSP/NSZ/XCI files (leave empty to set it later): ")
+		answer, err := reader.ReadString('\n')
+		folder := strings.TrimSpace(answer)
+		if folder == "" {
+			return ""
+		}
+		if info, statErr := os.Stat(folder); statErr == nil && info.IsDir() {
+			return folder
+		}
+		fmt.Printf("%v is not a folder\n", folder)
+		if err != nil {
+			return ""
+		}
+	}
+}
+
+func firstRunGuidance(settingsPath string, settingsObj *settings.AppSettings) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\nA default settings file was written to %v\n", settingsPath))
+	sb.WriteString("Next steps:\n")
+	if settingsObj.Folder == "" {
+		sb.WriteString("- set \"folder\" in the settings file to the folder holding your games (or pass it with -f in command line mode)\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- the library folder is %v, change \"folder\" in the settings file to scan another one\n", settingsObj.Folder))
+	}
+	sb.WriteString(fmt.Sprintf("- optionally, place your %v (and %v) in the app folder or under ${HOME}/.switch/, to identify the files by their content\n",
+		settings.PROD_KEYS_FILENAME, settings.TITLE_KEYS_FILENAME))
+	if settingsObj.GUI {
+		sb.WriteString("- set \"gui\" to false in the settings file to use the command line mode\n")
+	}
+	sb.WriteString("- run 'switch-library-manager doctor' to check the setup\n\n")
+	return sb.String()
+}
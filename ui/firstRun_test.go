@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/settings"
+)
+
+func TestFirstRunSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+	}{
+		{"new installation", ""},
+		{"existing settings", `{"folder": "/games", "gui": false}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-first-run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			settingsPath := filepath.Join(folder, settings.SETTINGS_FILENAME)
+			if test.existing != "" {
+				if err := ioutil.WriteFile(settingsPath, []byte(test.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			//tests do not run on a terminal, so this is the non interactive setup
+			output := captureStdout(t, func() { RunFirstRunSetup(folder) })
+			content, err := ioutil.ReadFile(settingsPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.existing != "" {
+				if output != "" || string(content) != test.existing {
+					t.Errorf("got output %q and settings %q, want the existing settings left untouched", output, content)
+				}
+				return
+			}
+			if !strings.Contains(output, "A default settings file was written to "+settingsPath) || !strings.Contains(output, "Next steps:") ||
+				!strings.Contains(output, settings.PROD_KEYS_FILENAME) {
+				t.Errorf("got output %q, want the next steps", output)
+			}
+			var written settings.AppSettings
+			if err := json.Unmarshal(content, &written); err != nil {
+				t.Fatalf("the default settings are not valid json - %v", err)
+			}
+			if written.Folder != "" || !written.RequireApply {
+				t.Errorf("got settings %+v, want the defaults", written)
+			}
+
+			//the defaults are only written once
+			if err := ioutil.WriteFile(settingsPath, []byte(`{"folder": "/games"}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if output := captureStdout(t, func() { RunFirstRunSetup(folder) }); output != "" {
+				t.Errorf("got output %q on the second run, want none", output)
+			}
+			if content, _ := ioutil.ReadFile(settingsPath); string(content) != `{"folder": "/games"}` {
+				t.Errorf("got settings %q after the second run, want them untouched", content)
+			}
+		})
+	}
+}
+
+func TestPromptFolder(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-first-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"folder", folder + "\n", folder},
+		{"retry after a missing folder", filepath.Join(folder, "missing") + "\n" + folder + "\n", folder},
+		{"set later", "\n", ""},
+		{"end of input", filepath.Join(folder, "missing"), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			captureStdout(t, func() { got = promptFolder(strings.NewReader(test.input)) })
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -8,24 +8,26 @@ import (
 	"github.com/giwty/switch-library-manager/process"
 	"github.com/giwty/switch-library-manager/settings"
 	"go.uber.org/zap"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/asticode/go-astikit"
 	"github.com/asticode/go-astilectron"
 )
 
 type LibraryTemplateData struct {
-	Id      int    `json:"id"`
-	Name    string `json:"name"`
-	Version int    `json:"version"`
-	Dlc     string `json:"dlc"`
-	TitleId string `json:"titleId"`
-	Path    string `json:"path"`
-	Icon    string `json:"icon"`
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Version      int    `json:"version"`
+	Dlc          string `json:"dlc"`
+	TitleId      string `json:"titleId"`
+	Path         string `json:"path"`
+	Icon         string `json:"icon,omitempty"`
+	Banner       string `json:"banner,omitempty"`
+	LastModified string `json:"lastModified"`
 }
 
 type ProgressUpdate struct {
@@ -132,7 +134,7 @@ func (g *GUI) Start() {
 
 		switch msg.Name {
 		case "organize":
-			g.organizeLibrary()
+			g.organizeLibrary(msg.Payload == "apply", g.progressUpdater())
 		case "isKeysFileAvailable":
 			keys, _ := settings.SwitchKeys()
 			retValue = strconv.FormatBool(keys != nil && keys.GetKey("header_key") != "")
@@ -141,39 +143,18 @@ func (g *GUI) Start() {
 		case "saveSettings":
 			g.saveSettings(msg.Payload)
 		case "updateLocalLibrary":
-			localDB, err := g.buildLocalDB()
+			localDB, err := g.buildLocalDB(g.progressUpdater())
 			if err != nil {
 				g.sugarLogger.Error(err)
 				g.state.window.SendMessage(Message{Name: "error", Payload: err.Error()}, func(m *astilectron.EventMessage) {})
 				return ""
 			}
-			response := []LibraryTemplateData{}
-			for k, v := range localDB.TitlesMap {
-				if v.BaseExist {
-					if title, ok := g.state.switchDB.TitlesMap[k]; ok {
-						response = append(response,
-							LibraryTemplateData{
-								Icon:    title.Attributes.IconUrl,
-								Name:    title.Attributes.Name,
-								TitleId: v.File.Metadata.TitleId,
-								Path:    filepath.Join(v.File.BaseFolder, v.File.Info.Name()),
-							})
-					} else {
-						response = append(response,
-							LibraryTemplateData{
-								Name:    db.ParseTitleNameFromFileName(v.File.Info.Name()),
-								TitleId: v.File.Metadata.TitleId,
-								Path:    v.File.Info.Name(),
-							})
-					}
-
-				}
-			}
+			response := libraryResponse(localDB.Titles(), g.state.switchDB)
 			msg, _ := json.Marshal(response)
 			g.state.window.SendMessage(Message{Name: "libraryLoaded", Payload: string(msg)}, func(m *astilectron.EventMessage) {})
 		case "updateDB":
 			if g.state.switchDB == nil {
-				switchDb, err := g.buildSwitchDb()
+				switchDb, err := g.buildSwitchDb(g.progressUpdater())
 				if err != nil {
 					g.sugarLogger.Error(err)
 					g.state.window.SendMessage(Message{Name: "error", Payload: err.Error()}, func(m *astilectron.EventMessage) {})
@@ -206,27 +187,60 @@ func (g *GUI) Start() {
 	a.Wait()
 }
 
-func (g *GUI) getMissingDLC() string {
-	missingDLC := process.ScanForMissingDLC(g.state.localDB.TitlesMap, g.state.switchDB.TitlesMap)
-	values := make([]process.IncompleteTitle, len(missingDLC))
-	i := 0
-	for _, missingUpdate := range missingDLC {
-		values[i] = missingUpdate
-		i++
+// the library titles sent to the GUI, titles missing from the titles DB are named after their file
+func libraryResponse(titles map[string]*db.SwitchFile, switchDB *db.SwitchTitlesDB) []LibraryTemplateData {
+	response := []LibraryTemplateData{}
+	for k, v := range titles {
+		if v.BaseExist {
+			if title, ok := switchDB.TitlesMap[k]; ok {
+				response = append(response,
+					LibraryTemplateData{
+						Icon:         titleIcon(title.Attributes),
+						Banner:       title.Attributes.BannerUrl,
+						Name:         title.Attributes.Name,
+						TitleId:      v.File.Metadata.TitleId,
+						Path:         filepath.Join(v.File.BaseFolder, v.File.Info.Name()),
+						LastModified: v.LastModified.Format("2006-01-02"),
+					})
+			} else {
+				response = append(response,
+					LibraryTemplateData{
+						Name:         db.ParseTitleNameFromFileName(v.File.Info.Name()),
+						TitleId:      v.File.Metadata.TitleId,
+						Path:         v.File.Info.Name(),
+						LastModified: v.LastModified.Format("2006-01-02"),
+					})
+			}
+
+		}
 	}
+	return response
+}
+
+// the icon of a title, falling back to its banner when the titles DB has no icon for it
+func titleIcon(attributes db.TitleAttributes) string {
+	if attributes.IconUrl != "" {
+		return attributes.IconUrl
+	}
+	return attributes.BannerUrl
+}
+
+func (g *GUI) getMissingDLC() string {
+	settingsObj := settings.ReadSettings(g.baseFolder)
+	localDB, switchDB := process.MergeTitleAliases(g.state.localDB.Titles(), g.state.switchDB.TitlesMap, settingsObj.TitleAliases)
+	missingDLC := process.ScanForMissingDLCWithWorkers(localDB, switchDB, settingsObj.AnalysisWorkers)
+	values := process.SortedIncompleteTitles(missingDLC)
 
 	msg, _ := json.Marshal(values)
 	return string(msg)
 }
 
 func (g *GUI) getMissingUpdates() string {
-	missingUpdates := process.ScanForMissingUpdates(g.state.localDB.TitlesMap, g.state.switchDB.TitlesMap)
-	values := make([]process.IncompleteTitle, len(missingUpdates))
-	i := 0
-	for _, missingUpdate := range missingUpdates {
-		values[i] = missingUpdate
-		i++
-	}
+	settingsObj := settings.ReadSettings(g.baseFolder)
+	localDB, switchDB := process.MergeTitleAliases(process.ExcludeBlacklisted(g.state.localDB.Titles(), process.NewBlacklist(settingsObj.BlacklistedVersions)),
+		g.state.switchDB.TitlesMap, settingsObj.TitleAliases)
+	missingUpdates := process.ScanForMissingUpdatesWithWorkers(localDB, switchDB, settingsObj.AnalysisWorkers)
+	values := process.SortedIncompleteTitles(missingUpdates)
 
 	msg, _ := json.Marshal(values)
 	return string(msg)
@@ -242,51 +256,82 @@ func (g *GUI) saveSettings(settingsJson string) {
 	settings.SaveSettings(&s, g.baseFolder)
 }
 
-func (g *GUI) buildSwitchDb() (*db.SwitchTitlesDB, error) {
+func (g *GUI) buildSwitchDb(progress db.ProgressUpdater) (*db.SwitchTitlesDB, error) {
+	return db.NewJsonTitlesProvider(g.baseFolder, progress).LoadTitlesDB()
+}
+
+func (g *GUI) buildLocalDB(progress db.ProgressUpdater) (*db.LocalSwitchFilesDB, error) {
 	settingsObj := settings.ReadSettings(g.baseFolder)
-	//1. load the titles JSON object
-	g.UpdateProgress(1, 4, "Downloading titles.json")
-	filename := filepath.Join(g.baseFolder, settings.TITLE_JSON_FILENAME)
-	titleFile, titlesEtag, err := db.LoadAndUpdateFile(settings.TITLES_JSON_URL, filename, settingsObj.TitlesEtag)
+	foldersToScan, err := settings.ScanFolders(g.baseFolder, settingsObj)
 	if err != nil {
-		return nil, err
+		g.sugarLogger.Errorf("failed to read the folders file - %v", err)
 	}
-	settingsObj.TitlesEtag = titlesEtag
-
-	g.UpdateProgress(2, 4, "Downloading versions.json")
-	filename = filepath.Join(g.baseFolder, settings.VERSIONS_JSON_FILENAME)
-	versionsFile, versionsEtag, err := db.LoadAndUpdateFile(settings.VERSIONS_JSON_URL, filename, settingsObj.VersionsEtag)
-	if err != nil {
-		return nil, err
+	scanOptions := db.ScanOptions{
+		Recursive:               settingsObj.ScanRecursively,
+		DisableDeepScan:         settingsObj.DisableDeepScan,
+		ArchiveExtractorCommand: settingsObj.ArchiveExtractorCommand,
+		RetryCount:              settingsObj.ScanRetryCount,
+		IncludeHidden:           !settingsObj.SkipHiddenFiles,
+		PreferredFormat:         settingsObj.PreferredFormat,
+		XciBaseOnly:             settingsObj.XciBaseOnly,
+		XciBundledUpdate:        settingsObj.XciBundledUpdate,
+		MaxFileSize:             int64(settingsObj.MaxFileSizeMB) * 1024 * 1024,
+		SkipUpdates:             settingsObj.SkipUpdates,
+		SkipDLC:                 settingsObj.SkipDLC,
+	}
+	db.SetRetryDelay(time.Duration(settingsObj.ScanRetryDelayMs) * time.Millisecond)
+	if settingsObj.TypeByTitlesDB {
+		scanOptions.TitlesDB = g.state.switchDB
 	}
-	settingsObj.VersionsEtag = versionsEtag
 
-	settings.SaveSettings(settingsObj, g.baseFolder)
+	var scanFolders []db.ScanFolder
+	for _, folder := range foldersToScan {
+		files, err := db.ReadFolder(folder, settingsObj.ScanRetryCount)
+		if err != nil {
+			return nil, err
+		}
+		scanFolders = append(scanFolders, db.ScanFolder{Path: folder, Files: files})
+	}
 
-	g.UpdateProgress(3, 4, "Building titles DB ...")
-	switchTitleDB, err := db.CreateSwitchTitleDB(titleFile, versionsFile)
-	g.UpdateProgress(4, 4, "Done")
-	return switchTitleDB, err
+	localDB, err := db.CreateLocalSwitchFilesDBFromFolders(scanFolders, progress, scanOptions)
+	if err == nil {
+		recordTitleDates(g.baseFolder, localDB)
+	}
+	g.state.localDB = localDB
+	return localDB, err
 }
 
-func (g *GUI) buildLocalDB() (*db.LocalSwitchFilesDB, error) {
-	folderToScan := settings.ReadSettings(g.baseFolder).Folder
-	recursiveMode := settings.ReadSettings(g.baseFolder).ScanRecursively
-
-	files, err := ioutil.ReadDir(folderToScan)
+// apply is set when the changes were confirmed in the UI, the GUI counterpart of the -apply flag
+func (g *GUI) organizeLibrary(apply bool, progress db.ProgressUpdater) {
+	settingsObj := settings.ReadSettings(g.baseFolder)
+	settingsObj.Apply = apply
+	defer func() { settingsObj.Apply = false }()
+	foldersToScan, err := settings.ScanFolders(g.baseFolder, settingsObj)
 	if err != nil {
-		return nil, err
+		g.sugarLogger.Errorf("failed to read the folders file - %v", err)
+	}
+	if overlaps := process.FindFolderOverlaps(foldersToScan); len(overlaps) != 0 {
+		g.sugarLogger.Errorf("the scan folders overlap ([%v] is inside [%v]), the library is not organized", overlaps[0].Folder, overlaps[0].Parent)
+		g.state.window.SendMessage(Message{Name: "error", Payload: "The scan folders overlap, the library is not organized"}, func(m *astilectron.EventMessage) {})
+		return
+	}
+	if len(foldersToScan) == 0 {
+		foldersToScan = []string{settingsObj.Folder}
 	}
+	dryRun := settingsObj.OrganizeOptions.DryRun || process.ChangesNotApplied(settingsObj)
+	if settingsObj.OrganizeOptions.KeepOnlyLatestUpdate && dryRun {
+		//the old updates which would be removed are written to slm.log
+		process.ConsolidateUpdates(g.state.localDB, true, process.NewBlacklist(settingsObj.BlacklistedVersions))
+	}
+	process.OrganizeByScanFolders(foldersToScan, g.state.localDB, g.state.switchDB, progress)
 
-	localDB, err := db.CreateLocalSwitchFilesDB(files, folderToScan, g, recursiveMode)
-	g.state.localDB = localDB
-	return localDB, err
 }
 
-func (g *GUI) organizeLibrary() {
-	folderToScan := settings.ReadSettings(g.baseFolder).Folder
-	process.OrganizeByFolders(folderToScan, g.state.localDB, g.state.switchDB, g)
-
+// the UI redraws on each progress message, so they are throttled to the configured interval.
+// each operation (message from the UI) creates a single updater and passes it along
+func (g *GUI) progressUpdater() db.ProgressUpdater {
+	interval := settings.ReadSettings(g.baseFolder).ProgressIntervalMs
+	return db.NewThrottledProgressUpdater(g, time.Duration(interval)*time.Millisecond)
 }
 
 func (g *GUI) UpdateProgress(curr int, total int, message string) {
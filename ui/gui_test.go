@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+func TestLibraryResponseIcons(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-gui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	//a local base game of the given titleId
+	title := func(name string, titleId string) *db.SwitchFile {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &db.SwitchFile{BaseExist: true, File: db.ExtendedFileInfo{Info: info, BaseFolder: folder,
+			Metadata: &switchfs.ContentMetaAttributes{TitleId: titleId}}}
+	}
+	localDB := map[string]*db.SwitchFile{
+		"010000000001": title("Icon and banner [0100000000010000][v0].nsp", "0100000000010000"),
+		"010000000002": title("Banner only [0100000000020000][v0].nsp", "0100000000020000"),
+		"010000000003": title("No images [0100000000030000][v0].nsp", "0100000000030000"),
+		"010000000004": title("Unknown [0100000000040000][v0].nsp", "0100000000040000"),
+	}
+	switchDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"010000000001": {Attributes: db.TitleAttributes{Id: "0100000000010000", Name: "Icon and banner",
+			IconUrl: "https://example.com/icon1.jpg", BannerUrl: "https://example.com/banner1.jpg"}},
+		"010000000002": {Attributes: db.TitleAttributes{Id: "0100000000020000", Name: "Banner only",
+			BannerUrl: "https://example.com/banner2.jpg"}},
+		"010000000003": {Attributes: db.TitleAttributes{Id: "0100000000030000", Name: "No images"}},
+	}}
+
+	content, err := json.Marshal(libraryResponse(localDB, switchDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response []map[string]interface{}
+	if err := json.Unmarshal(content, &response); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(response, func(i, j int) bool { return response[i]["titleId"].(string) < response[j]["titleId"].(string) })
+	tests := []struct {
+		name       string
+		wantIcon   interface{}
+		wantBanner interface{}
+	}{
+		{"icon and banner", "https://example.com/icon1.jpg", "https://example.com/banner1.jpg"},
+		{"the banner stands for a missing icon", "https://example.com/banner2.jpg", "https://example.com/banner2.jpg"},
+		{"no images", nil, nil},
+		{"title missing from the titles DB", nil, nil},
+	}
+	if len(response) != len(tests) {
+		t.Fatalf("got %v titles, want %v", len(response), len(tests))
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if icon := response[i]["icon"]; icon != test.wantIcon {
+				t.Errorf("got icon %v, want %v", icon, test.wantIcon)
+			}
+			if banner := response[i]["banner"]; banner != test.wantBanner {
+				t.Errorf("got banner %v, want %v", banner, test.wantBanner)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package ui
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHandleInterruptCancelsInsteadOfExiting(t *testing.T) {
+	c := &Console{sugarLogger: zap.NewNop().Sugar(), now: time.Now}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.handleInterrupt(cancel)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("failed to send the interrupt: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the interrupt did not cancel the context")
+	}
+}
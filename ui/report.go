@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	REPORTS_FOLDER     = "reports"
+	REPORT_NAME_LAYOUT = "2006-01-02-150405"
+	REPORT_FILE_SUFFIX = ".txt"
+)
+
+// the process stdout, before it is redirected to the report file
+var consoleStdout = os.Stdout
+
+// startReport duplicates everything printed to stdout into a timestamped file in the reports folder,
+// the returned function must be called to flush the report and restore stdout
+func startReport(baseFolder string, reportsToKeep int) (func(), error) {
+	reportsFolder := filepath.Join(baseFolder, REPORTS_FOLDER)
+	err := os.MkdirAll(reportsFolder, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	reportPath := filepath.Join(reportsFolder, time.Now().Format(REPORT_NAME_LAYOUT)+REPORT_FILE_SUFFIX)
+	reportFile, err := os.Create(reportPath)
+	if err != nil {
+		return nil, err
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		reportFile.Close()
+		return nil, err
+	}
+	done := make(chan bool)
+	go func() {
+		_, _ = io.Copy(io.MultiWriter(consoleStdout, reportFile), reader)
+		done <- true
+	}()
+	os.Stdout = writer
+
+	return func() {
+		os.Stdout = consoleStdout
+		writer.Close()
+		<-done
+		reportFile.Close()
+		pruneReports(reportsFolder, reportsToKeep)
+	}, nil
+}
+
+// delete the oldest reports, keeping the last reportsToKeep ones (0 keeps all the reports)
+func pruneReports(reportsFolder string, reportsToKeep int) {
+	if reportsToKeep <= 0 {
+		return
+	}
+	files, err := ioutil.ReadDir(reportsFolder)
+	if err != nil {
+		zap.S().Errorf("failed to list the reports folder - %v", err)
+		return
+	}
+	var reports []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), REPORT_FILE_SUFFIX) {
+			reports = append(reports, file.Name())
+		}
+	}
+	//the timestamped names sort chronologically
+	sort.Strings(reports)
+	for i := 0; i < len(reports)-reportsToKeep; i++ {
+		err = os.Remove(filepath.Join(reportsFolder, reports[i]))
+		if err != nil {
+			zap.S().Errorf("failed to delete old report %v - %v", reports[i], err)
+		}
+	}
+}
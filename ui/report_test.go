@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name          string
+		oldReports    []string
+		reportsToKeep int
+		wantOld       []string
+	}{
+		{"first report", nil, 0, nil},
+		{"all reports kept", []string{"2020-01-01-100000.txt", "2020-01-02-100000.txt"}, 0,
+			[]string{"2020-01-01-100000.txt", "2020-01-02-100000.txt"}},
+		{"oldest reports pruned", []string{"2020-01-01-100000.txt", "2020-01-02-100000.txt", "2020-01-03-100000.txt"}, 2,
+			[]string{"2020-01-03-100000.txt"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-report")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			reportsFolder := filepath.Join(folder, REPORTS_FOLDER)
+			for _, name := range test.oldReports {
+				if err := os.MkdirAll(reportsFolder, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(reportsFolder, name), []byte("old report"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			//the console output goes to a file, to check it still gets everything printed
+			console, err := os.Create(filepath.Join(folder, "console.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer console.Close()
+			defaultConsoleStdout, defaultStdout := consoleStdout, os.Stdout
+			consoleStdout = console
+			defer func() { consoleStdout, os.Stdout = defaultConsoleStdout, defaultStdout }()
+
+			stopReport, err := startReport(folder, test.reportsToKeep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Println("Found available updates")
+			stopReport()
+
+			files, err := ioutil.ReadDir(reportsFolder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var old []string
+			report := ""
+			for _, file := range files {
+				if strings.HasPrefix(file.Name(), "2020-") {
+					old = append(old, file.Name())
+					continue
+				}
+				if report != "" {
+					t.Errorf("got reports %v and %v for a single run", report, file.Name())
+				}
+				report = file.Name()
+			}
+			if !reflect.DeepEqual(old, test.wantOld) {
+				t.Errorf("got old reports %v, want %v", old, test.wantOld)
+			}
+			if !strings.HasSuffix(report, REPORT_FILE_SUFFIX) || len(report) != len(REPORT_NAME_LAYOUT+REPORT_FILE_SUFFIX) {
+				t.Fatalf("got report %q, want a timestamped report", report)
+			}
+			for _, path := range []string{filepath.Join(reportsFolder, report), console.Name()} {
+				content, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != "Found available updates\n" {
+					t.Errorf("got %v content %q, want the printed output", filepath.Base(path), content)
+				}
+			}
+			if os.Stdout != console {
+				t.Error("stdout was not restored after the report")
+			}
+		})
+	}
+}
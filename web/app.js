@@ -186,6 +186,9 @@ $(function () {
                             {field: "Attributes.bannerUrl",formatter:"image", headerSort:false,formatterParams:{height:"60px", width:"60px"}},
                             {title: "Title", field: "Attributes.name", headerFilter:"input",formatter:"textarea",width:350},
                             {title: "# Missing", field: "missing_dlc.length"},
+                            {title: "Owned", headerSort:false, formatter:function(cell, formatterParams, onRendered){
+                                    return cell.getRow().getData().owned_dlc + " of " + cell.getRow().getData().total_dlc
+                                }},
                             {title: "Missing DLC", headerSort:false, field: "missing_dlc",formatter:function(cell, formatterParams, onRendered){
                                     value = ""
                                     for (var i in cell.getValue())
@@ -232,24 +235,28 @@ $(function () {
 
         $("body").on("click", ".library-organize-action", e => {
             e.preventDefault();
+            //with require_apply, the changes are only planned (written to slm.log) unless applied explicitly
+            const requireApply = state.settings.require_apply;
             const options = {
                 type: 'warning',
-                buttons: ['Yes', 'No'],
+                buttons: requireApply ? ['Apply', 'Plan only', 'Cancel'] : ['Yes', 'No'],
                 defaultId: 0,
                 title: 'Confirmation',
                 message: 'Are you sure you want to begin library organization?',
-                detail: 'This action will modify your local library files',
+                detail: requireApply ? 'Apply will modify your local library files, Plan only writes the planned changes to slm.log' :
+                    'This action will modify your local library files',
             };
 
             dialog.showMessageBox(null, options).then( (r) => {
 
-                if (r.response === 0) {
+                const apply = r.response === 0;
+                if (apply || (requireApply && r.response === 1)) {
                     //show progress
                     $('.tabgroup > div').hide();
                     $(".progress-container").show();
                     $(".progress-type").text("Organizing local library...");
 
-                    sendMessage("organize", "", (r => {
+                    sendMessage("organize", apply ? "apply" : "", (r => {
                         $(".progress-container").hide();
                         loadTab("#organize");
                         dialog.showMessageBox(null, {
@@ -257,7 +264,7 @@ $(function () {
                             buttons: ['Ok'],
                             defaultId: 0,
                             title: 'Success',
-                            message: 'Operation completed successfully'
+                            message: apply ? 'Operation completed successfully' : 'The planned changes were written to slm.log'
                         })
                     }))
                 }